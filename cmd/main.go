@@ -3,6 +3,7 @@ package main
 import (
 	"ais-summoner/internal/database"
 	"ais-summoner/internal/game"
+	"ais-summoner/internal/leaderboard"
 	"ais-summoner/internal/pkg/authenticator"
 	"ais-summoner/internal/router"
 	"context"
@@ -22,16 +23,36 @@ import (
 
 func main() {
 	logger := log.New(os.Stdout, "[AIS-Summoners] ", log.LstdFlags)
+	if len(os.Args) > 1 && os.Args[1] == "terrain" {
+		loadEnvVariables(logger)
+		runTerrainCommand(os.Args[2:], logger)
+		return
+	}
+
 	logger.Println("Starting AIS Summoners server...")
 	loadEnvVariables(logger)
 
-	mongodb := database.NewMongoDB()
-	gateway := game.NewGameGateway(mongodb, database.NewRedis())
-	go gateway.Run()
-
 	gob.Register(map[string]interface{}{})
 	store := cookie.NewStore([]byte(os.Getenv("SESSION_SECRET")))
 
+	auth, err := authenticator.NewAuthenticator()
+	if err != nil {
+		log.Fatalf("Failed to initialize the authenticator: %v", err)
+	}
+
+	mongodb := database.NewMongoDB()
+	migrateCtx, cancelMigrate := context.WithTimeout(context.Background(), 30*time.Second)
+	if migrated, err := mongodb.TerrainRepository().MigrateLegacyOutlines(migrateCtx); err != nil {
+		logger.Printf("Error migrating legacy terrains: %v", err)
+	} else if migrated > 0 {
+		logger.Printf("Migrated %d legacy terrains", migrated)
+	}
+	cancelMigrate()
+
+	redis := database.NewRedis()
+	gateway := game.NewGameGateway(mongodb, redis, auth, store)
+	go gateway.Run()
+
 	ginRouter := gin.Default()
 	ginRouter.Use(func(ginCtx *gin.Context) {
 		ginCtx.Header("Access-Control-Allow-Origin", "*")
@@ -45,7 +66,7 @@ func main() {
 
 		ginCtx.Next()
 	})
-	ginRouter.Use(sessions.Sessions("auth-session", store))
+	ginRouter.Use(sessions.Sessions(game.SessionCookieName, store))
 	ginRouter.GET("/health", func(ginCtx *gin.Context) {
 		session := sessions.Default(ginCtx)
 		log.Printf("profile: %v", session.Get("profile"))
@@ -55,15 +76,19 @@ func main() {
 	ginRouter.GET("/ws", func(ginCtx *gin.Context) {
 		gateway.HandleWebSocketConnection(ginCtx.Writer, ginCtx.Request)
 	})
-
-	auth, err := authenticator.NewAuthenticator()
-	if err != nil {
-		log.Fatalf("Failed to initialize the authenticator: %v", err)
-	}
+	ginRouter.GET("/ws/replay/:matchId", func(ginCtx *gin.Context) {
+		gateway.HandleReplaySpectator(ginCtx.Writer, ginCtx.Request, ginCtx.Param("matchId"))
+	})
 
 	router.NewAuthRouterV1(ginRouter, auth)
-	router.NewUserRouterV1(ginRouter, mongodb)
+	board := leaderboard.NewLeaderboard(redis)
+	router.NewUserRouterV1(ginRouter, mongodb, board)
+	router.NewLeaderboardRouterV1(ginRouter, mongodb, board)
 	router.NewTerrainRouterV1(ginRouter, mongodb)
+	router.NewMatchRouterV1(ginRouter, mongodb)
+	router.NewReplayRouterV1(ginRouter, gateway.Replays())
+	router.NewBotRouterV1(ginRouter, mongodb, gateway)
+	router.NewConnectionRouterV1(ginRouter, mongodb, gateway)
 
 	port := os.Getenv("PORT")
 	server := &http.Server{
@@ -79,7 +104,7 @@ func main() {
 		}
 	}()
 
-	handleShutdown(server, mongodb, logger)
+	handleShutdown(server, gateway, mongodb, redis, logger)
 }
 
 func loadEnvVariables(logger *log.Logger) {
@@ -89,7 +114,7 @@ func loadEnvVariables(logger *log.Logger) {
 	}
 }
 
-func handleShutdown(server *http.Server, mongodb *database.MongoDB, logger *log.Logger) {
+func handleShutdown(server *http.Server, gateway *game.GameGateway, mongodb *database.MongoDB, redis *database.Redis, logger *log.Logger) {
 	quit := make(chan os.Signal, 1)
 	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
 	<-quit
@@ -99,13 +124,22 @@ func handleShutdown(server *http.Server, mongodb *database.MongoDB, logger *log.
 	shutdownCtx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
 	defer cancel()
 
-	// Close MongoDB connection
+	// Let running matches finish, leaving time to record them and to
+	// close the sockets before the deadline.
+	drainCtx, cancelDrain := context.WithTimeout(shutdownCtx, 20*time.Second)
+	gateway.Drain(drainCtx)
+	cancelDrain()
+
+	if err := server.Shutdown(shutdownCtx); err != nil {
+		logger.Printf("Server forced to shutdown: %v", err)
+	}
+
+	// Close the database connections once nothing uses them anymore
 	if err := mongodb.Close(); err != nil {
 		logger.Printf("Error closing MongoDB connection: %v", err)
 	}
-
-	if err := server.Shutdown(shutdownCtx); err != nil {
-		logger.Fatalf("Server forced to shutdown: %v", err)
+	if err := redis.Close(); err != nil {
+		logger.Printf("Error closing Redis connection: %v", err)
 	}
 
 	logger.Println("Server shutdown completed")
@@ -0,0 +1,136 @@
+package main
+
+import (
+	"ais-summoner/internal/database"
+	"ais-summoner/internal/pkg/geometry"
+	"ais-summoner/internal/pkg/terrainfile"
+	"context"
+	"flag"
+	"log"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const terrainUsage = `Usage:
+  main terrain import [-format geojson|svg] [-name name] [-rotation degrees] <file>
+  main terrain export [-format geojson|svg] [-o file] <terrain-id>`
+
+// runTerrainCommand imports terrain files into MongoDB or exports stored
+// terrains, using the same conversion as the terrain HTTP endpoints.
+func runTerrainCommand(args []string, logger *log.Logger) {
+	if len(args) == 0 {
+		logger.Fatal(terrainUsage)
+	}
+
+	switch args[0] {
+	case "import":
+		importTerrain(args[1:], logger)
+	case "export":
+		exportTerrain(args[1:], logger)
+	default:
+		logger.Fatal(terrainUsage)
+	}
+}
+
+func importTerrain(args []string, logger *log.Logger) {
+	flags := flag.NewFlagSet("terrain import", flag.ExitOnError)
+	formatName := flags.String("format", "", "file format, guessed from the extension when empty")
+	name := flags.String("name", "", "terrain name, overriding the one in the file")
+	rotation := flags.String("rotation", "", "rotation in degrees, overriding the one in the file")
+	flags.Parse(args)
+	if flags.NArg() != 1 {
+		logger.Fatal(terrainUsage)
+	}
+
+	path := flags.Arg(0)
+	if *formatName == "" {
+		*formatName = strings.TrimPrefix(strings.ToLower(filepath.Ext(path)), ".")
+		if *formatName == "json" {
+			*formatName = string(terrainfile.FormatGeoJSON)
+		}
+	}
+	format, err := terrainfile.ParseFormat(*formatName)
+	if err != nil {
+		logger.Fatal(err)
+	}
+
+	options := terrainfile.ImportOptions{Name: *name}
+	if *rotation != "" {
+		degrees, err := strconv.ParseFloat(*rotation, 64)
+		if err != nil {
+			logger.Fatalf("Invalid rotation %q", *rotation)
+		}
+		options.Rotation = &degrees
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		logger.Fatalf("Error reading %s: %v", path, err)
+	}
+
+	terrain, err := terrainfile.Import(format, data, options)
+	if err != nil {
+		logger.Fatalf("Error importing %s: %v", path, err)
+	}
+	if problems := geometry.ValidateTerrain(terrain); problems != nil {
+		logger.Fatalf("Invalid terrain in %s:\n  %s", path, strings.Join(problems, "\n  "))
+	}
+
+	mongodb := database.NewMongoDB()
+	defer mongodb.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	terrain, err = mongodb.TerrainRepository().Insert(ctx, terrain)
+	if err != nil {
+		logger.Fatalf("Error storing terrain: %v", err)
+	}
+
+	logger.Printf("Imported terrain %s (%s) with %d boundary points", terrain.ID.Hex(), terrain.Name, len(terrain.Boundary))
+}
+
+func exportTerrain(args []string, logger *log.Logger) {
+	flags := flag.NewFlagSet("terrain export", flag.ExitOnError)
+	formatName := flags.String("format", string(terrainfile.FormatGeoJSON), "file format")
+	output := flags.String("o", "", "output file, standard output when empty")
+	flags.Parse(args)
+	if flags.NArg() != 1 {
+		logger.Fatal(terrainUsage)
+	}
+
+	format, err := terrainfile.ParseFormat(*formatName)
+	if err != nil {
+		logger.Fatal(err)
+	}
+
+	mongodb := database.NewMongoDB()
+	defer mongodb.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	terrain, err := mongodb.TerrainRepository().GetByID(ctx, flags.Arg(0))
+	if err != nil {
+		logger.Fatalf("Error loading terrain: %v", err)
+	}
+	if terrain == nil {
+		logger.Fatalf("Terrain %s not found", flags.Arg(0))
+	}
+
+	data, err := terrainfile.Export(format, terrain)
+	if err != nil {
+		logger.Fatalf("Error exporting terrain: %v", err)
+	}
+
+	if *output == "" {
+		os.Stdout.Write(data)
+		return
+	}
+	if err := os.WriteFile(*output, data, 0o644); err != nil {
+		logger.Fatalf("Error writing %s: %v", *output, err)
+	}
+}
@@ -14,6 +14,8 @@ type MongoDB struct {
 	client      *mongo.Client
 	db          *mongo.Database
 	logger      *log.Logger
+	matchRepo   *repositories.MatchRepository
+	replayRepo  *repositories.ReplayRepository
 	terrainRepo *repositories.TerrainRepository
 	userRepo    *repositories.UserRepository
 }
@@ -33,6 +35,8 @@ func NewMongoDB() *MongoDB {
 		client:      client,
 		db:          db,
 		logger:      logger,
+		matchRepo:   repositories.NewMatchRepository(db),
+		replayRepo:  repositories.NewReplayRepository(db),
 		terrainRepo: repositories.NewTerrainRepository(db),
 		userRepo:    repositories.NewUserRepository(db),
 	}
@@ -46,6 +50,14 @@ func (m *MongoDB) TerrainRepository() *repositories.TerrainRepository {
 	return m.terrainRepo
 }
 
+func (m *MongoDB) MatchRepository() *repositories.MatchRepository {
+	return m.matchRepo
+}
+
+func (m *MongoDB) ReplayRepository() *repositories.ReplayRepository {
+	return m.replayRepo
+}
+
 func (m *MongoDB) Close() error {
 	m.logger.Println("Disconnecting from MongoDB")
 	return m.client.Disconnect(context.Background())
@@ -1,15 +1,21 @@
 package database
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"os"
+	"strconv"
 	"time"
 
 	"github.com/redis/go-redis/v9"
-	"golang.org/x/net/context"
 )
 
+type ScoredMember struct {
+	Member string
+	Score  float64
+}
+
 type Redis struct {
 	client *redis.Client
 	ctx    context.Context
@@ -69,3 +75,173 @@ func (r *Redis) DeleteCache(key string) error {
 
 	return nil
 }
+
+func (r *Redis) AddToSortedSet(key string, member string, score float64) error {
+	err := r.client.ZAdd(r.ctx, key, redis.Z{Score: score, Member: member}).Err()
+	if err != nil {
+		return fmt.Errorf("Error adding to sorted set: %v", err)
+	}
+
+	return nil
+}
+
+func (r *Redis) RemoveFromSortedSet(key string, members ...string) error {
+	values := make([]interface{}, len(members))
+	for i, member := range members {
+		values[i] = member
+	}
+
+	err := r.client.ZRem(r.ctx, key, values...).Err()
+	if err != nil {
+		return fmt.Errorf("Error removing from sorted set: %v", err)
+	}
+
+	return nil
+}
+
+// GetSortedSetRange returns the members ranked between start and stop, both
+// inclusive, in ascending score order.
+func (r *Redis) GetSortedSetRange(key string, start int64, stop int64) ([]string, error) {
+	members, err := r.client.ZRange(r.ctx, key, start, stop).Result()
+	if err != nil {
+		return nil, fmt.Errorf("Error getting sorted set range: %v", err)
+	}
+
+	return members, nil
+}
+
+// GetSortedSetRangeByScore returns the members scored between min and max,
+// both inclusive, in ascending score order.
+func (r *Redis) GetSortedSetRangeByScore(key string, min float64, max float64) ([]string, error) {
+	members, err := r.client.ZRangeByScore(r.ctx, key, &redis.ZRangeBy{
+		Min: strconv.FormatFloat(min, 'f', -1, 64),
+		Max: strconv.FormatFloat(max, 'f', -1, 64),
+	}).Result()
+	if err != nil {
+		return nil, fmt.Errorf("Error getting sorted set range: %v", err)
+	}
+
+	return members, nil
+}
+
+// GetSortedSetReverseRange returns the members ranked between start and
+// stop, both inclusive, in descending score order along with their scores.
+func (r *Redis) GetSortedSetReverseRange(key string, start int64, stop int64) ([]ScoredMember, error) {
+	result, err := r.client.ZRevRangeWithScores(r.ctx, key, start, stop).Result()
+	if err != nil {
+		return nil, fmt.Errorf("Error getting sorted set range: %v", err)
+	}
+
+	members := make([]ScoredMember, len(result))
+	for i, z := range result {
+		members[i] = ScoredMember{Member: fmt.Sprint(z.Member), Score: z.Score}
+	}
+
+	return members, nil
+}
+
+// GetSortedSetReverseRank returns the zero-based rank of the member in
+// descending score order, and false when the member is not in the set.
+func (r *Redis) GetSortedSetReverseRank(key string, member string) (int64, bool, error) {
+	rank, err := r.client.ZRevRank(r.ctx, key, member).Result()
+	if err != nil {
+		if err == redis.Nil {
+			return 0, false, nil
+		}
+		return 0, false, fmt.Errorf("Error getting sorted set rank: %v", err)
+	}
+
+	return rank, true, nil
+}
+
+func (r *Redis) GetSortedSetScore(key string, member string) (float64, error) {
+	score, err := r.client.ZScore(r.ctx, key, member).Result()
+	if err != nil && err != redis.Nil {
+		return 0, fmt.Errorf("Error getting sorted set score: %v", err)
+	}
+
+	return score, nil
+}
+
+func (r *Redis) GetSortedSetCount(key string) (int64, error) {
+	count, err := r.client.ZCard(r.ctx, key).Result()
+	if err != nil {
+		return 0, fmt.Errorf("Error counting sorted set: %v", err)
+	}
+
+	return count, nil
+}
+
+func (r *Redis) AddToSet(key string, member string) error {
+	err := r.client.SAdd(r.ctx, key, member).Err()
+	if err != nil {
+		return fmt.Errorf("Error adding to set: %v", err)
+	}
+
+	return nil
+}
+
+func (r *Redis) GetSetMembers(key string) ([]string, error) {
+	members, err := r.client.SMembers(r.ctx, key).Result()
+	if err != nil {
+		return nil, fmt.Errorf("Error getting set members: %v", err)
+	}
+
+	return members, nil
+}
+
+// RunScript runs a Lua script, which Redis executes atomically.
+func (r *Redis) RunScript(script *redis.Script, keys []string, args ...interface{}) (interface{}, error) {
+	result, err := script.Run(r.ctx, r.client, keys, args...).Result()
+	if err != nil && err != redis.Nil {
+		return nil, fmt.Errorf("Error running script: %v", err)
+	}
+
+	return result, nil
+}
+
+// SetCacheIfAbsent sets the key only when it does not exist yet and reports
+// whether it did.
+func (r *Redis) SetCacheIfAbsent(key string, value interface{}, expiration time.Duration) (bool, error) {
+	jsonValue, err := json.Marshal(value)
+	if err != nil {
+		return false, fmt.Errorf("Error marshaling value: %v", err)
+	}
+
+	set, err := r.client.SetNX(r.ctx, key, jsonValue, expiration).Result()
+	if err != nil {
+		return false, fmt.Errorf("Error setting cache: %v", err)
+	}
+
+	return set, nil
+}
+
+func (r *Redis) Publish(channel string, value interface{}) error {
+	jsonValue, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Errorf("Error marshaling value: %v", err)
+	}
+
+	err = r.client.Publish(r.ctx, channel, jsonValue).Err()
+	if err != nil {
+		return fmt.Errorf("Error publishing message: %v", err)
+	}
+
+	return nil
+}
+
+// Subscribe listens to the channels, returning once Redis confirmed the
+// subscription so that no message published afterwards is missed.
+func (r *Redis) Subscribe(channels ...string) (*redis.PubSub, error) {
+	pubsub := r.client.Subscribe(r.ctx, channels...)
+	if _, err := pubsub.Receive(r.ctx); err != nil {
+		pubsub.Close()
+		return nil, fmt.Errorf("Error subscribing: %v", err)
+	}
+
+	return pubsub, nil
+}
+
+func (r *Redis) Close() error {
+	return r.client.Close()
+}
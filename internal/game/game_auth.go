@@ -0,0 +1,110 @@
+package game
+
+import (
+	"ais-summoner/internal/models"
+	"context"
+	"net/http"
+	"strings"
+	"time"
+
+	"golang.org/x/oauth2"
+)
+
+const (
+	SessionCookieName = "auth-session"
+	authTimeout       = 10 * time.Second
+)
+
+// authenticateRequest resolves the user from the session cookie set by the
+// auth callback. It returns nil when the request carries no valid session.
+func (gateway *GameGateway) authenticateRequest(r *http.Request) *models.User {
+	if gateway.sessions == nil {
+		return nil
+	}
+
+	session, err := gateway.sessions.Get(r, SessionCookieName)
+	if err != nil {
+		return nil
+	}
+
+	profile, ok := session.Values["profile"].(map[string]interface{})
+	if !ok {
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
+
+	user, err := gateway.resolveUser(ctx, profile)
+	if err != nil {
+		gateway.logger.Printf("Error resolving session user: %v", err)
+		return nil
+	}
+
+	return user
+}
+
+// authenticate resolves the user from an Authentication payload, which
+// carries either the session cookie value or a bearer ID token.
+func (gateway *GameGateway) authenticate(payload AuthenticationPayload) (*models.User, error) {
+	if payload.Cookie != "" {
+		r, err := http.NewRequest(http.MethodGet, "/", nil)
+		if err != nil {
+			return nil, err
+		}
+		r.AddCookie(&http.Cookie{Name: SessionCookieName, Value: payload.Cookie})
+
+		if user := gateway.authenticateRequest(r); user != nil {
+			return user, nil
+		}
+		return nil, ErrInvalidCredentials
+	}
+
+	rawIDToken := strings.TrimSpace(strings.TrimPrefix(payload.Token, "Bearer "))
+	if rawIDToken == "" || gateway.auth == nil {
+		return nil, ErrInvalidCredentials
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	token := (&oauth2.Token{}).WithExtra(map[string]interface{}{"id_token": rawIDToken})
+	idToken, err := gateway.auth.VerifyIDToken(ctx, token)
+	if err != nil {
+		return nil, ErrInvalidCredentials
+	}
+
+	var profile map[string]interface{}
+	if err := idToken.Claims(&profile); err != nil {
+		return nil, err
+	}
+
+	return gateway.resolveUser(ctx, profile)
+}
+
+// resolveUser finds the user matching the subject of the profile claims,
+// creating it on first login.
+func (gateway *GameGateway) resolveUser(ctx context.Context, profile map[string]interface{}) (*models.User, error) {
+	subject, _ := profile["sub"].(string)
+	if subject == "" {
+		return nil, ErrInvalidCredentials
+	}
+
+	users := gateway.mongodb.UserRepository()
+	user, err := users.GetBySubject(ctx, subject)
+	if err != nil || user != nil {
+		return user, err
+	}
+
+	username, _ := profile["nickname"].(string)
+	if username == "" {
+		username, _ = profile["name"].(string)
+	}
+	email, _ := profile["email"].(string)
+
+	return users.Insert(ctx, &models.User{
+		Subject:  subject,
+		Username: username,
+		Email:    email,
+	})
+}
@@ -0,0 +1,122 @@
+package game
+
+import (
+	"ais-summoner/internal/models"
+	"encoding/binary"
+	"errors"
+	"math"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Little-endian layouts used by BinaryCodec. Coordinates are sent as
+// float32, which is the precision the Unity client simulates with, and
+// player ids as the 12 raw bytes of their ObjectID.
+
+const (
+	vector2Size     = 8
+	vector3Size     = 12
+	inputSize       = vector2Size + 4
+	playerStateSize = 12 + vector3Size + vector2Size + 4
+	gameStateHeader = 4 + 8 + 2 + 4 + 4 + 2
+)
+
+var errBinaryLength = errors.New("binary payload has the wrong length")
+
+func appendVector2(data []byte, vector models.Vector2) []byte {
+	data = binary.LittleEndian.AppendUint32(data, math.Float32bits(float32(vector.X)))
+	return binary.LittleEndian.AppendUint32(data, math.Float32bits(float32(vector.Y)))
+}
+
+func appendVector3(data []byte, vector models.Vector3) []byte {
+	data = binary.LittleEndian.AppendUint32(data, math.Float32bits(float32(vector.X)))
+	data = binary.LittleEndian.AppendUint32(data, math.Float32bits(float32(vector.Y)))
+	return binary.LittleEndian.AppendUint32(data, math.Float32bits(float32(vector.Z)))
+}
+
+func readVector2(data []byte) models.Vector2 {
+	return models.Vector2{
+		X: float64(math.Float32frombits(binary.LittleEndian.Uint32(data[0:]))),
+		Y: float64(math.Float32frombits(binary.LittleEndian.Uint32(data[4:]))),
+	}
+}
+
+func appendObjectID(data []byte, id string) ([]byte, error) {
+	objectID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return nil, err
+	}
+
+	return append(data, objectID[:]...), nil
+}
+
+// MarshalBinary encodes the direction followed by the uint32 sequence number.
+func (payload *PlayerMovePayload) MarshalBinary() ([]byte, error) {
+	data := appendVector2(make([]byte, 0, inputSize), payload.Direction)
+	return binary.LittleEndian.AppendUint32(data, payload.Seq), nil
+}
+
+func (payload *PlayerMovePayload) UnmarshalBinary(data []byte) error {
+	if len(data) != inputSize {
+		return errBinaryLength
+	}
+
+	payload.Direction = readVector2(data)
+	payload.Seq = binary.LittleEndian.Uint32(data[vector2Size:])
+	return nil
+}
+
+// MarshalBinary uses the same layout as PlayerMovePayload.
+func (payload *PlayerDashPayload) MarshalBinary() ([]byte, error) {
+	return (*PlayerMovePayload)(payload).MarshalBinary()
+}
+
+func (payload *PlayerDashPayload) UnmarshalBinary(data []byte) error {
+	return (*PlayerMovePayload)(payload).UnmarshalBinary(data)
+}
+
+// UnmarshalBinary reads the snapshot tick as a uint32, like the tick of
+// GameState.
+func (payload *SnapshotAckPayload) UnmarshalBinary(data []byte) error {
+	if len(data) != 4 {
+		return errBinaryLength
+	}
+
+	payload.Tick = uint64(binary.LittleEndian.Uint32(data))
+	return nil
+}
+
+// MarshalBinary encodes the uint32 tick, the int64 timestamp and a uint16
+// player count, followed by the id, position, direction and float32 health
+// of each player, the uint32 last processed sequence number, the uint32
+// base tick and a uint16 count of removed player ids followed by the ids.
+// Everything after the players is appended so clients reading only the
+// players keep working; such clients never acknowledge snapshots and thus
+// never get deltas.
+func (state *GameState) MarshalBinary() ([]byte, error) {
+	data := make([]byte, 0, gameStateHeader+len(state.Players)*playerStateSize)
+	data = binary.LittleEndian.AppendUint32(data, uint32(state.Tick))
+	data = binary.LittleEndian.AppendUint64(data, uint64(state.Timestamp))
+	data = binary.LittleEndian.AppendUint16(data, uint16(len(state.Players)))
+
+	var err error
+	for _, player := range state.Players {
+		if data, err = appendObjectID(data, player.ID); err != nil {
+			return nil, err
+		}
+		data = appendVector3(data, player.Position)
+		data = appendVector2(data, player.Direction)
+		data = binary.LittleEndian.AppendUint32(data, math.Float32bits(float32(player.Health)))
+	}
+
+	data = binary.LittleEndian.AppendUint32(data, state.LastProcessedSeq)
+	data = binary.LittleEndian.AppendUint32(data, uint32(state.BaseTick))
+	data = binary.LittleEndian.AppendUint16(data, uint16(len(state.Removed)))
+	for _, id := range state.Removed {
+		if data, err = appendObjectID(data, id); err != nil {
+			return nil, err
+		}
+	}
+
+	return data, nil
+}
@@ -0,0 +1,320 @@
+package game
+
+import (
+	"ais-summoner/internal/models"
+	"ais-summoner/internal/pkg/navmesh"
+	"encoding/json"
+	"log"
+	"math"
+	"math/rand"
+	"sync"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// botThinkInterval is how often a bot looks at the latest state and
+// decides on new inputs, roughly the reaction time of a player.
+const botThinkInterval = 100 * time.Millisecond
+
+// GameBot is a server-side player. It talks to its GameClient over an
+// in-memory pipe exactly like a player does over a WebSocket: its inputs
+// are encoded with the client codec and read by the client, and it learns
+// about the room from the messages the client writes back.
+type GameBot struct {
+	client    *GameClient
+	transport *PipeTransport
+	behaviour BotBehaviour
+	mesh      *navmesh.Mesh
+	rng       *rand.Rand
+	logger    *log.Logger
+	state     json.RawMessage
+	seq       uint32
+	direction models.Vector2
+	done      chan struct{}
+	stopOnce  sync.Once
+}
+
+// BotView is what a bot knows when it decides on its next inputs. Players
+// are copies taken from the last GameStateUpdate.
+type BotView struct {
+	Tick   uint64
+	Self   *Player
+	Others []*Player
+	Config RoomConfig
+	// Mesh is nil when the terrain cannot be triangulated.
+	Mesh *navmesh.Mesh
+	Rand *rand.Rand
+}
+
+// BotAction holds the inputs a bot sends after deciding. Move is sent as
+// PlayerMove whenever it changes; Dash sends a PlayerDash towards
+// DashDirection.
+type BotAction struct {
+	Move          models.Vector2
+	Dash          bool
+	DashDirection models.Vector2
+}
+
+func newGameBot(gateway *GameGateway, id string, behaviour BotBehaviour) *GameBot {
+	bot := &GameBot{
+		behaviour: behaviour,
+		rng:       rand.New(rand.NewSource(gateway.clock.Now().UnixNano())),
+		logger:    log.New(log.Writer(), "[GameBot] ", log.LstdFlags),
+		done:      make(chan struct{}),
+	}
+	clientEnd, botEnd := NewPipe()
+	bot.client = newGameClient(gateway, JSONCodec{}, clientEnd)
+	bot.client.setID(id)
+	bot.client.bot = bot
+	bot.transport = botEnd
+
+	return bot
+}
+
+func (bot *GameBot) ID() string {
+	return bot.client.ID()
+}
+
+// Run reads the messages sent to the bot and thinks at a fixed rate until
+// the bot is stopped or its client closes the pipe, then takes it out of
+// its room.
+func (bot *GameBot) Run() {
+	ticker := bot.client.gateway.clock.NewTicker(botThinkInterval)
+	defer func() {
+		ticker.Stop()
+		bot.client.gateway.leaveRoom(bot.client)
+		bot.transport.Close(CloseNormal, "")
+	}()
+
+	messages := make(chan []byte)
+	go bot.listen(messages)
+
+	for {
+		select {
+		case message, ok := <-messages:
+			if !ok {
+				return
+			}
+			bot.receive(message)
+
+		case <-ticker.C():
+			bot.think()
+
+		case <-bot.done:
+			return
+		}
+	}
+}
+
+func (bot *GameBot) Stop() {
+	bot.stopOnce.Do(func() {
+		close(bot.done)
+	})
+}
+
+// listen passes the messages arriving on the pipe on to Run until the pipe
+// is closed. Receiving also answers the pings of the client.
+func (bot *GameBot) listen(messages chan<- []byte) {
+	defer close(messages)
+
+	for {
+		message, err := bot.transport.Receive()
+		if err != nil {
+			return
+		}
+
+		select {
+		case messages <- message:
+		case <-bot.done:
+			return
+		}
+	}
+}
+
+// receive keeps the payload of the latest state update. Older updates are
+// dropped unread, as a lagging player would skip frames.
+func (bot *GameBot) receive(message []byte) {
+	envelope, err := bot.client.codec.Decode(message)
+	if err != nil {
+		bot.logger.Printf("Error decoding message for %s: %v", bot.client.ID(), err)
+		return
+	}
+
+	switch envelope.Event {
+	case GameStateUpdate:
+		bot.state = envelope.Payload
+	case Error:
+		bot.logger.Printf("Bot %s got an error: %s", bot.client.ID(), envelope.Payload)
+	}
+}
+
+// think lets the behaviour decide on the latest state and sends the inputs
+// it asks for.
+func (bot *GameBot) think() {
+	if bot.state == nil {
+		return
+	}
+
+	var state GameState
+	err := json.Unmarshal(bot.state, &state)
+	bot.state = nil
+	if err != nil {
+		bot.logger.Printf("Error decoding state for %s: %v", bot.client.ID(), err)
+		return
+	}
+
+	view := &BotView{
+		Tick:   state.Tick,
+		Config: bot.client.gateway.config,
+		Mesh:   bot.mesh,
+		Rand:   bot.rng,
+	}
+	for _, player := range state.Players {
+		if player.ID == bot.client.ID() {
+			view.Self = player
+		} else {
+			view.Others = append(view.Others, player)
+		}
+	}
+	if view.Self == nil {
+		return
+	}
+
+	action := bot.behaviour.Decide(view)
+	if action.Dash {
+		bot.seq++
+		bot.input(PlayerDash, &PlayerDashPayload{Direction: action.DashDirection, Seq: bot.seq})
+	}
+	if math.Abs(action.Move.X-bot.direction.X) > 1e-3 || math.Abs(action.Move.Y-bot.direction.Y) > 1e-3 {
+		bot.direction = action.Move
+		bot.seq++
+		bot.input(PlayerMove, &PlayerMovePayload{Direction: action.Move, Seq: bot.seq})
+	}
+}
+
+// input encodes the payload and sends it to the client over the pipe.
+func (bot *GameBot) input(event GameEvent, payload Payload) {
+	data, err := bot.client.codec.Encode(event, payload)
+	if err != nil {
+		bot.logger.Printf("Error encoding input for %s: %v", bot.client.ID(), err)
+		return
+	}
+
+	if err := bot.transport.Send([][]byte{data}); err != nil {
+		bot.logger.Printf("Error sending input for %s: %v", bot.client.ID(), err)
+	}
+}
+
+// Position returns where the bot stands on the horizontal plane.
+func (view *BotView) Position() models.Vector2 {
+	return models.Vector2{X: view.Self.Position.X, Y: view.Self.Position.Z}
+}
+
+// Nearest returns the closest other player and its distance, or nil when
+// the bot is alone.
+func (view *BotView) Nearest() (*Player, float64) {
+	var nearest *Player
+	best := math.Inf(1)
+	for _, player := range view.Others {
+		if d := distanceTo(view.Position(), player); d < best {
+			nearest, best = player, d
+		}
+	}
+
+	return nearest, best
+}
+
+// AddBots puts count bots playing the named behaviour in the room
+// identified by roomID. When no room id is given a new room is created on
+// the terrain identified by terrainID.
+func (gateway *GameGateway) AddBots(roomID string, terrainID string, count int, behaviour string) (*GameRoom, []string, error) {
+	var room *GameRoom
+	var botIDs []string
+	for i := 0; i < count; i++ {
+		botBehaviour, err := NewBotBehaviour(behaviour)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		bot := newGameBot(gateway, primitive.NewObjectID().Hex(), botBehaviour)
+		joined, err := gateway.startBot(bot, roomID, terrainID)
+		if err != nil {
+			return room, botIDs, err
+		}
+
+		room, roomID = joined, joined.id
+		botIDs = append(botIDs, bot.ID())
+	}
+
+	return room, botIDs, nil
+}
+
+// RemoveBots stops every bot in the room and returns how many there were.
+// The bots leave the room on their own goroutine.
+func (gateway *GameGateway) RemoveBots(roomID string) (int, error) {
+	room := gateway.FindRoom(roomID)
+	if room == nil {
+		return 0, ErrRoomNotFound
+	}
+
+	removed := 0
+	for _, member := range room.members() {
+		if member.bot != nil {
+			member.bot.Stop()
+			removed++
+		}
+	}
+
+	return removed, nil
+}
+
+// spawnBots fills the seats a ranked match reserved for bots. The seats
+// are marked first so the match record tells them apart from players even
+// when a bot fails to join.
+func (gateway *GameGateway) spawnBots(room *GameRoom, botIDs []string, behaviour string) {
+	room.mutex.Lock()
+	for _, botID := range botIDs {
+		room.bots[botID] = true
+	}
+	room.mutex.Unlock()
+
+	for _, botID := range botIDs {
+		botBehaviour, err := NewBotBehaviour(behaviour)
+		if err != nil {
+			gateway.logger.Printf("Error creating bot behaviour %q, using %q: %v", behaviour, DefaultBotBehaviour, err)
+			botBehaviour, _ = NewBotBehaviour(DefaultBotBehaviour)
+		}
+
+		if _, err := gateway.startBot(newGameBot(gateway, botID, botBehaviour), room.id, ""); err != nil {
+			gateway.logger.Printf("Error adding bot %s to room %s: %v", botID, room.id, err)
+		}
+	}
+}
+
+// startBot joins the bot to a room like a client would and starts it.
+func (gateway *GameGateway) startBot(bot *GameBot, roomID string, terrainID string) (*GameRoom, error) {
+	if err := gateway.joinRoom(bot.client, roomID, terrainID); err != nil {
+		return nil, err
+	}
+
+	room := bot.client.room.Load()
+	if room == nil {
+		return nil, ErrRoomNotFound
+	}
+
+	mesh, err := room.navigationMesh()
+	if err != nil {
+		gateway.logger.Printf("Bots in room %s steer without a navigation mesh: %v", room.id, err)
+	}
+	bot.mesh = mesh
+
+	go bot.client.Read()
+	go bot.client.Write()
+	go bot.Run()
+	return room, nil
+}
+
+func distanceTo(point models.Vector2, player *Player) float64 {
+	return math.Hypot(player.Position.X-point.X, player.Position.Z-point.Y)
+}
@@ -0,0 +1,213 @@
+package game
+
+import (
+	"ais-summoner/internal/models"
+	"fmt"
+	"math"
+	"strings"
+	"time"
+)
+
+const (
+	DefaultBotBehaviour = "chase+dash"
+
+	// botWaypointRadius is how close a bot gets to a waypoint before
+	// heading for the next one.
+	botWaypointRadius = 0.25
+	// botReplanDistance is how far a target may move before the path to it
+	// is searched again.
+	botReplanDistance = 1.0
+	// botReplanInterval forces a new path now and then, in case the bot was
+	// pushed off its path by a dash or a wall.
+	botReplanInterval = 2 * time.Second
+	// botWanderTimeout gives up on a wander target that could not be
+	// reached in time.
+	botWanderTimeout = 15 * time.Second
+	// botWanderRange bounds wander targets when there is no mesh to pick
+	// them from.
+	botWanderRange = 10.0
+	// DefaultFleeRadius is how close another player may come before a
+	// fleeing bot runs.
+	DefaultFleeRadius = 8.0
+)
+
+// BotBehaviour decides on the inputs of one bot. Behaviours may keep state
+// between decisions, so every bot gets its own instance.
+type BotBehaviour interface {
+	Decide(view *BotView) BotAction
+}
+
+var botBehaviours = map[string]func() BotBehaviour{
+	"wander": func() BotBehaviour { return &WanderBehaviour{} },
+	"chase":  func() BotBehaviour { return &ChaseBehaviour{} },
+	"flee":   func() BotBehaviour { return &FleeBehaviour{Radius: DefaultFleeRadius} },
+}
+
+// NewBotBehaviour creates the behaviour with the given name: wander, chase
+// or flee, optionally followed by "+dash" to also dash at players in range.
+func NewBotBehaviour(name string) (BotBehaviour, error) {
+	base, dash := strings.CutSuffix(name, "+dash")
+	create, exists := botBehaviours[base]
+	if !exists {
+		return nil, NewGameError(CodeInvalidPayload, fmt.Sprintf("unknown bot behaviour %q", name))
+	}
+
+	if dash {
+		return &DashBehaviour{Inner: create()}, nil
+	}
+
+	return create(), nil
+}
+
+// WanderBehaviour walks to random points of the walkable area.
+type WanderBehaviour struct {
+	navigator botNavigator
+	chosenAt  uint64
+}
+
+func (behaviour *WanderBehaviour) Decide(view *BotView) BotAction {
+	if !behaviour.navigator.planned || behaviour.navigator.arrived() ||
+		view.Tick-behaviour.chosenAt >= view.Config.Ticks(botWanderTimeout) {
+		behaviour.navigator.plan(view, behaviour.randomTarget(view))
+		behaviour.chosenAt = view.Tick
+	}
+
+	return BotAction{Move: behaviour.navigator.steer(view, behaviour.navigator.target)}
+}
+
+func (behaviour *WanderBehaviour) randomTarget(view *BotView) models.Vector2 {
+	if view.Mesh != nil {
+		return view.Mesh.RandomPoint(view.Rand)
+	}
+
+	position := view.Position()
+	return models.Vector2{
+		X: position.X + (view.Rand.Float64()*2-1)*botWanderRange,
+		Y: position.Y + (view.Rand.Float64()*2-1)*botWanderRange,
+	}
+}
+
+// ChaseBehaviour runs after the nearest player and wanders while alone.
+type ChaseBehaviour struct {
+	navigator botNavigator
+	wander    WanderBehaviour
+}
+
+func (behaviour *ChaseBehaviour) Decide(view *BotView) BotAction {
+	target, _ := view.Nearest()
+	if target == nil {
+		return behaviour.wander.Decide(view)
+	}
+
+	goal := models.Vector2{X: target.Position.X, Y: target.Position.Z}
+	return BotAction{Move: behaviour.navigator.steer(view, goal)}
+}
+
+// FleeBehaviour runs away from the nearest player once it comes within
+// Radius and wanders otherwise.
+type FleeBehaviour struct {
+	Radius    float64
+	navigator botNavigator
+	wander    WanderBehaviour
+}
+
+func (behaviour *FleeBehaviour) Decide(view *BotView) BotAction {
+	threat, d := view.Nearest()
+	if threat == nil || d > behaviour.Radius {
+		return behaviour.wander.Decide(view)
+	}
+
+	// Head for the point one radius further away from the threat; the mesh
+	// moves it back inside the walkable area when it falls off.
+	position := view.Position()
+	away := unitDirection(models.Vector2{X: threat.Position.X, Y: threat.Position.Z}, position)
+	if away == (models.Vector2{}) {
+		away = models.Vector2{X: 1}
+	}
+	goal := models.Vector2{X: position.X + away.X*behaviour.Radius, Y: position.Y + away.Y*behaviour.Radius}
+
+	return BotAction{Move: behaviour.navigator.steer(view, goal)}
+}
+
+// DashBehaviour moves like Inner and dashes at the nearest player whenever
+// it is within dash distance and the dash has cooled down.
+type DashBehaviour struct {
+	Inner    BotBehaviour
+	dashed   bool
+	lastDash uint64
+}
+
+func (behaviour *DashBehaviour) Decide(view *BotView) BotAction {
+	action := behaviour.Inner.Decide(view)
+
+	target, d := view.Nearest()
+	if target == nil || d > view.Config.DashDistance {
+		return action
+	}
+	if behaviour.dashed && view.Tick-behaviour.lastDash < view.Config.Ticks(view.Config.DashCooldown) {
+		return action
+	}
+
+	action.Dash = true
+	action.DashDirection = unitDirection(view.Position(), models.Vector2{X: target.Position.X, Y: target.Position.Z})
+	behaviour.dashed, behaviour.lastDash = true, view.Tick
+	return action
+}
+
+// botNavigator steers a bot along a path over the navigation mesh, or
+// straight at the target when the room has no mesh.
+type botNavigator struct {
+	target    models.Vector2
+	path      []models.Vector2
+	next      int
+	planned   bool
+	plannedAt uint64
+}
+
+// steer returns the unit direction towards the next waypoint on the way to
+// goal, or zero once the bot has arrived.
+func (navigator *botNavigator) steer(view *BotView, goal models.Vector2) models.Vector2 {
+	if !navigator.planned ||
+		math.Hypot(goal.X-navigator.target.X, goal.Y-navigator.target.Y) > botReplanDistance ||
+		view.Tick-navigator.plannedAt >= view.Config.Ticks(botReplanInterval) {
+		navigator.plan(view, goal)
+	}
+
+	position := view.Position()
+	for navigator.next < len(navigator.path) {
+		waypoint := navigator.path[navigator.next]
+		if math.Hypot(waypoint.X-position.X, waypoint.Y-position.Y) > botWaypointRadius {
+			return unitDirection(position, waypoint)
+		}
+		navigator.next++
+	}
+
+	return models.Vector2{}
+}
+
+func (navigator *botNavigator) plan(view *BotView, goal models.Vector2) {
+	navigator.target, navigator.planned, navigator.plannedAt = goal, true, view.Tick
+	navigator.path, navigator.next = []models.Vector2{goal}, 0
+
+	if view.Mesh != nil {
+		if path, found := view.Mesh.FindPath(view.Position(), goal); found {
+			// The path starts where the bot stands.
+			navigator.path = path[1:]
+		}
+	}
+}
+
+func (navigator *botNavigator) arrived() bool {
+	return navigator.next >= len(navigator.path)
+}
+
+// unitDirection returns the unit vector pointing from one point to another.
+func unitDirection(from models.Vector2, to models.Vector2) models.Vector2 {
+	dx, dy := to.X-from.X, to.Y-from.Y
+	length := math.Hypot(dx, dy)
+	if length < 1e-9 {
+		return models.Vector2{}
+	}
+
+	return models.Vector2{X: dx / length, Y: dy / length}
+}
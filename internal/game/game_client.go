@@ -1,108 +1,262 @@
 package game
 
 import (
-	"encoding/json"
+	"ais-summoner/internal/models"
+	"errors"
+	"fmt"
+	"sync/atomic"
 	"time"
-
-	"github.com/gorilla/websocket"
 )
 
+// pingInterval is how often Write pings the client, which also refreshes
+// the round trip estimate used for lag compensation.
+const pingInterval = 5 * time.Second
+
 type GameClient struct {
-	connection *websocket.Conn
-	send       chan []byte
-	gateway    *GameGateway
+	// id is the player id, set once the client is bound to a user or a
+	// bot. Other goroutines, like the admin endpoint, read it while the
+	// read goroutine binds the user, so it is only accessed through ID.
+	id        atomic.Pointer[string]
+	codec     Codec
+	transport Transport
+	// room is the room the client plays in. It is set and cleared under
+	// the room mutex, from whichever goroutine joins, leaves or closes the
+	// room, while the handlers of the client read it without locks.
+	room    atomic.Pointer[GameRoom]
+	user    *models.User
+	queue   *sendQueue
+	gateway *GameGateway
+	bot     *GameBot
+	// inputSeq is the sequence number of the newest input accepted from
+	// the client. It is only touched by the goroutine handling its inputs.
+	inputSeq uint32
+	stats    connectionStats
+}
+
+func newGameClient(gateway *GameGateway, codec Codec, transport Transport) *GameClient {
+	return &GameClient{
+		codec:     codec,
+		transport: transport,
+		queue:     newSendQueue(sendQueueLimit),
+		gateway:   gateway,
+		stats:     connectionStats{connectedAt: time.Now()},
+	}
 }
 
 func (client *GameClient) Read() {
+	// Write closes the transport once the queue is closed, which unregister
+	// does unless disconnect did first, so the last messages still go out.
 	defer func() {
 		client.gateway.unregister <- client
-		client.connection.Close()
 	}()
 
-	client.connection.SetReadLimit(512)
+	client.transport.SetPongHandler(client.handlePong)
+	if !client.authenticated() {
+		client.transport.SetReadDeadline(time.Now().Add(authTimeout))
+	} else {
+		client.transport.SetReadDeadline(time.Now().Add(pongWait))
+	}
 
 	for {
-		_, message, err := client.connection.ReadMessage()
+		message, err := client.transport.Receive()
 		if err != nil {
-			if websocket.IsUnexpectedCloseError(err, websocket.CloseGoingAway, websocket.CloseAbnormalClosure) {
-				client.gateway.logger.Printf("WebSocket error: %v", err)
+			if isUnexpectedClose(err) {
+				client.gateway.logger.Printf("Transport error: %v", err)
+			}
+			if !client.authenticated() {
+				client.sendMessage(Unauthorized, NewGameError(CodeUnauthorized, "authentication timed out").Payload())
+				client.disconnect(ClosePolicyViolation, "authentication timed out")
 			}
 			break
 		}
+		client.stats.received(len(message))
 
-		var wsMessage GameWebSocketMessage
-		if err := json.Unmarshal(message, &wsMessage); err != nil {
-			client.gateway.logger.Printf("Error unmarshaling message: %v", err)
+		envelope, err := client.codec.Decode(message)
+		if err != nil {
+			client.sendMessage(Error, NewGameError(CodeMalformedMessage, err.Error()).Payload())
 			continue
 		}
 
-		client.gateway.logger.Printf("Received message: %v", wsMessage)
+		if !client.authenticated() {
+			if !client.handleAuthentication(envelope) {
+				client.disconnect(ClosePolicyViolation, "authentication failed")
+				break
+			}
+			client.transport.SetReadDeadline(time.Now().Add(pongWait))
+			continue
+		}
 
-		client.handleMessage(wsMessage.Event, wsMessage.Payload)
-		client.sendMessage(GameEvent(2), map[string]string{"message": "Hello from server"})
+		client.handleMessage(envelope)
 	}
 }
 
+// Write sends the queued messages and pings the client until the queue is
+// closed or the transport fails, then closes the transport.
 func (client *GameClient) Write() {
-	ticker := time.NewTicker(54 * time.Second)
+	ticker := time.NewTicker(pingInterval)
 	defer func() {
 		ticker.Stop()
-		client.connection.Close()
+		client.transport.Close(client.queue.closeStatus())
 	}()
 
 	for {
 		select {
-		case message, ok := <-client.send:
-			client.connection.SetWriteDeadline(time.Now().Add(10 * time.Second))
-			if !ok {
-				client.connection.WriteMessage(websocket.CloseMessage, []byte{})
+		case <-client.queue.ready:
+			messages, open := client.queue.take()
+			if err := client.transport.Send(messages); err != nil {
 				return
 			}
-
-			w, err := client.connection.NextWriter(websocket.TextMessage)
-			if err != nil {
-				return
+			for _, message := range messages {
+				client.stats.sent(len(message))
 			}
-			w.Write(message)
 
-			// Add queued messages to the current message
-			n := len(client.send)
-			for i := 0; i < n; i++ {
-				w.Write([]byte{'\n'})
-				w.Write(<-client.send)
-			}
-
-			if err := w.Close(); err != nil {
+			if !open {
 				return
 			}
 
-		case <-ticker.C:
-			client.connection.SetWriteDeadline(time.Now().Add(10 * time.Second))
-			if err := client.connection.WriteMessage(websocket.PingMessage, nil); err != nil {
+		case now := <-ticker.C:
+			client.stats.sampleRates(now)
+			if err := client.transport.Ping(pingPayload(now)); err != nil {
 				return
 			}
 		}
 	}
 }
 
-func (client *GameClient) sendMessage(event GameEvent, payload interface{}) {
-	message := GameWebSocketMessage{
-		Event:   event,
-		Payload: payload,
+// ID returns the player id of the client, or "" before it is bound to a
+// user.
+func (client *GameClient) ID() string {
+	if id := client.id.Load(); id != nil {
+		return *id
 	}
 
-	data, err := json.Marshal(message)
+	return ""
+}
+
+func (client *GameClient) setID(id string) {
+	client.id.Store(&id)
+}
+
+// authenticated reports whether the client is known, as a user or as one
+// of the bots of this instance.
+func (client *GameClient) authenticated() bool {
+	return client.user != nil || client.bot != nil
+}
+
+// disconnect closes the transport with the given close code once the
+// messages already queued are written.
+func (client *GameClient) disconnect(code int, reason string) {
+	client.queue.close(code, reason)
+}
+
+func (client *GameClient) sendMessage(event GameEvent, payload interface{}) {
+	data, err := client.codec.Encode(event, payload)
 	if err != nil {
 		client.gateway.logger.Printf("Error marshaling message: %v", err)
+		return
+	}
+
+	// State updates only matter until the next one, so a client that
+	// falls behind skips them. When the messages it must get pile up
+	// regardless, it is cut off rather than slowing the room down.
+	replaced, err := client.queue.push(data, event == GameStateUpdate)
+	if replaced {
+		client.stats.skippedOut.Add(1)
+	}
+	if err != nil {
+		client.stats.droppedOut.Add(1)
+		client.gateway.logger.Printf("Disconnecting client %s: %v", client.ID(), err)
+		client.queue.abort(CloseSlowConsumer, "client too slow")
+	}
+}
+
+// handleAuthentication processes the first message of an unauthenticated
+// socket. It reports false, after sending Unauthorized, when the socket
+// must be closed.
+func (client *GameClient) handleAuthentication(envelope GameEnvelope) bool {
+	if envelope.Event != Authentication {
+		client.sendMessage(Unauthorized, NewGameError(CodeUnauthorized, "authentication required").Payload())
+		return false
+	}
+
+	var payload AuthenticationPayload
+	if err := decodePayload(envelope, &payload); err != nil {
+		client.sendMessage(Unauthorized, NewGameError(CodeUnauthorized, err.Error()).Payload())
+		return false
 	}
 
-	select {
-	case client.send <- data:
-	default:
-		close(client.send)
+	user, err := client.gateway.authenticate(payload)
+	if err != nil {
+		client.gateway.logger.Printf("Authentication failed: %v", err)
+		client.sendMessage(Unauthorized, ErrInvalidCredentials.Payload())
+		return false
 	}
+
+	client.bindUser(user)
+	return true
+}
+
+// bindUser attaches the authenticated user to the client and acknowledges it.
+func (client *GameClient) bindUser(user *models.User) {
+	client.user = user
+	client.setID(user.ID.Hex())
+	client.sendMessage(Authentication, user)
 }
 
-func (client *GameClient) handleMessage(event GameEvent, payload interface{}) {
+// handleMessage looks up the handler registered for the event, decodes the
+// payload with it and runs it, reporting any failure as an Error event.
+func (client *GameClient) handleMessage(envelope GameEnvelope) {
+	handler, exists := gameEventHandlers[envelope.Event]
+	if !exists {
+		client.sendError(NewGameError(CodeUnknownEvent, fmt.Sprintf("unsupported event %d", envelope.Event)))
+		return
+	}
+
+	payload, err := handler.decode(envelope)
+	if err != nil {
+		client.sendError(err)
+		return
+	}
+
+	if err := handler.handle(client, payload); err != nil {
+		client.sendError(err)
+	}
+}
+
+// acceptInput reports whether an input with the given sequence number is
+// newer than every input accepted so far, and records it if so. Sequence
+// numbers compare with wraparound; inputs numbered 0 are not sequenced and
+// always accepted. Gaps left by lost inputs are fine, but late and
+// duplicate inputs are dropped, as the client has already predicted past
+// them.
+func (client *GameClient) acceptInput(seq uint32) bool {
+	if seq == 0 {
+		return true
+	}
+	if client.inputSeq != 0 && int32(seq-client.inputSeq) <= 0 {
+		client.stats.droppedIn.Add(1)
+		return false
+	}
+
+	client.inputSeq = seq
+	return true
+}
+
+// sendError reports err to the client. A RoomRedirect is sent as a
+// Redirect event; other errors that are not a GameError are logged and
+// hidden behind a generic internal error.
+func (client *GameClient) sendError(err error) {
+	var redirect *RoomRedirect
+	if errors.As(err, &redirect) {
+		client.sendMessage(Redirect, redirect.Payload())
+		return
+	}
+
+	var gameError *GameError
+	if !errors.As(err, &gameError) {
+		client.gateway.logger.Printf("Error handling message: %v", err)
+		gameError = NewGameError(CodeInternal, "internal server error")
+	}
 
+	client.sendMessage(Error, gameError.Payload())
 }
@@ -0,0 +1,63 @@
+package game
+
+import (
+	"math"
+	"testing"
+)
+
+func TestAcceptInput(t *testing.T) {
+	steps := []struct {
+		seq    uint32
+		accept bool
+	}{
+		{1, true},
+		// Gaps left by lost inputs are fine.
+		{4, true},
+		// Late and duplicate inputs are dropped.
+		{3, false},
+		{4, false},
+		{5, true},
+		// Unsequenced inputs are always taken and leave the sequence be.
+		{0, true},
+		{0, true},
+		{5, false},
+		{6, true},
+	}
+
+	client, _ := newTestClient(nil, "player")
+	dropped := uint64(0)
+	for i, step := range steps {
+		if got := client.acceptInput(step.seq); got != step.accept {
+			t.Fatalf("step %d: acceptInput(%d) = %v, want %v", i, step.seq, got, step.accept)
+		}
+		if !step.accept {
+			dropped++
+		}
+	}
+	if got := client.stats.droppedIn.Load(); got != dropped {
+		t.Fatalf("counted %d dropped inputs, want %d", got, dropped)
+	}
+}
+
+func TestAcceptInputWrapsAround(t *testing.T) {
+	client, _ := newTestClient(nil, "player")
+
+	for _, seq := range []uint32{math.MaxUint32 - 1, math.MaxUint32, 1, 2} {
+		if !client.acceptInput(seq) {
+			t.Fatalf("dropped %d while counting past the wraparound", seq)
+		}
+	}
+	// Inputs from before the wraparound are now late.
+	for _, seq := range []uint32{math.MaxUint32, math.MaxUint32 - 5, 2} {
+		if client.acceptInput(seq) {
+			t.Fatalf("accepted %d after 2", seq)
+		}
+	}
+
+	// The first input may carry any sequence number, even one that would
+	// look late against the zero a client starts from.
+	client, _ = newTestClient(nil, "other")
+	if !client.acceptInput(math.MaxUint32) || client.acceptInput(math.MaxUint32) {
+		t.Fatal("did not take the first input once")
+	}
+}
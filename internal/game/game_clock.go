@@ -0,0 +1,52 @@
+package game
+
+import "time"
+
+// Clock abstracts time so the room tick loop can be driven manually.
+type Clock interface {
+	Now() time.Time
+	NewTicker(interval time.Duration) Ticker
+	// AfterFunc calls fn on its own goroutine once the duration has
+	// passed, unless the returned timer is stopped first.
+	AfterFunc(duration time.Duration, fn func()) Timer
+}
+
+type Ticker interface {
+	C() <-chan time.Time
+	Stop()
+}
+
+// Timer is a pending AfterFunc call. Stop reports whether it prevented
+// the call.
+type Timer interface {
+	Stop() bool
+}
+
+type systemClock struct{}
+
+type systemTicker struct {
+	ticker *time.Ticker
+}
+
+// SystemClock is the Clock backed by the time package.
+var SystemClock Clock = systemClock{}
+
+func (systemClock) Now() time.Time {
+	return time.Now()
+}
+
+func (systemClock) NewTicker(interval time.Duration) Ticker {
+	return &systemTicker{ticker: time.NewTicker(interval)}
+}
+
+func (systemClock) AfterFunc(duration time.Duration, fn func()) Timer {
+	return time.AfterFunc(duration, fn)
+}
+
+func (t *systemTicker) C() <-chan time.Time {
+	return t.ticker.C
+}
+
+func (t *systemTicker) Stop() {
+	t.ticker.Stop()
+}
@@ -0,0 +1,173 @@
+package game
+
+import (
+	"sync"
+	"testing"
+	"time"
+)
+
+// manualClock is a Clock that only moves when Advance is called. Ticks are
+// delivered and timers fire synchronously, so once Advance returns every
+// ticker has been read by its owner and every timer function has run.
+type manualClock struct {
+	now     time.Time
+	tickers []*manualTicker
+	timers  []*manualTimer
+	mutex   sync.Mutex
+}
+
+type manualTicker struct {
+	interval time.Duration
+	next     time.Time
+	c        chan time.Time
+	stopped  chan struct{}
+	stopOnce sync.Once
+}
+
+type manualTimer struct {
+	clock   *manualClock
+	at      time.Time
+	fn      func()
+	pending bool
+}
+
+func newManualClock() *manualClock {
+	return &manualClock{now: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)}
+}
+
+func (clock *manualClock) Now() time.Time {
+	clock.mutex.Lock()
+	defer clock.mutex.Unlock()
+
+	return clock.now
+}
+
+func (clock *manualClock) NewTicker(interval time.Duration) Ticker {
+	clock.mutex.Lock()
+	defer clock.mutex.Unlock()
+
+	ticker := &manualTicker{
+		interval: interval,
+		next:     clock.now.Add(interval),
+		c:        make(chan time.Time),
+		stopped:  make(chan struct{}),
+	}
+	clock.tickers = append(clock.tickers, ticker)
+
+	return ticker
+}
+
+func (clock *manualClock) AfterFunc(duration time.Duration, fn func()) Timer {
+	clock.mutex.Lock()
+	defer clock.mutex.Unlock()
+
+	timer := &manualTimer{clock: clock, at: clock.now.Add(duration), fn: fn, pending: true}
+	clock.timers = append(clock.timers, timer)
+
+	return timer
+}
+
+// Advance moves the clock forward, firing every tick and timer due on the
+// way in order.
+func (clock *manualClock) Advance(duration time.Duration) {
+	clock.mutex.Lock()
+	target := clock.now.Add(duration)
+	clock.mutex.Unlock()
+
+	for {
+		clock.mutex.Lock()
+		var timer *manualTimer
+		for _, pending := range clock.timers {
+			if pending.pending && !pending.at.After(target) && (timer == nil || pending.at.Before(timer.at)) {
+				timer = pending
+			}
+		}
+
+		var due *manualTicker
+		for _, ticker := range clock.tickers {
+			if ticker.isStopped() || ticker.next.After(target) {
+				continue
+			}
+			if due == nil || ticker.next.Before(due.next) {
+				due = ticker
+			}
+		}
+
+		if timer != nil && (due == nil || !due.next.Before(timer.at)) {
+			clock.now = timer.at
+			timer.pending = false
+			clock.mutex.Unlock()
+
+			timer.fn()
+			continue
+		}
+		if due == nil {
+			clock.now = target
+			clock.mutex.Unlock()
+			return
+		}
+
+		now := due.next
+		clock.now = now
+		due.next = now.Add(due.interval)
+		clock.mutex.Unlock()
+
+		select {
+		case due.c <- now:
+		case <-due.stopped:
+		}
+	}
+}
+
+// waitForTickers waits until exactly count tickers are running, since their
+// owners create and stop them on their own goroutines.
+func (clock *manualClock) waitForTickers(t *testing.T, count int) {
+	t.Helper()
+
+	deadline := time.Now().Add(2 * time.Second)
+	for time.Now().Before(deadline) {
+		clock.mutex.Lock()
+		running := 0
+		for _, ticker := range clock.tickers {
+			if !ticker.isStopped() {
+				running++
+			}
+		}
+		clock.mutex.Unlock()
+
+		if running == count {
+			return
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	t.Fatalf("%d tickers never ran", count)
+}
+
+func (timer *manualTimer) Stop() bool {
+	timer.clock.mutex.Lock()
+	defer timer.clock.mutex.Unlock()
+
+	stopped := timer.pending
+	timer.pending = false
+	return stopped
+}
+
+func (ticker *manualTicker) C() <-chan time.Time {
+	return ticker.c
+}
+
+func (ticker *manualTicker) Stop() {
+	ticker.stopOnce.Do(func() {
+		close(ticker.stopped)
+	})
+}
+
+func (ticker *manualTicker) isStopped() bool {
+	select {
+	case <-ticker.stopped:
+		return true
+	default:
+		return false
+	}
+}
@@ -0,0 +1,194 @@
+package game
+
+import (
+	"encoding/json"
+	"sync"
+	"time"
+)
+
+// ClusterInstance is a server instance taking part in the cluster. Address
+// is the WebSocket URL clients reconnect to when redirected to it.
+type ClusterInstance struct {
+	ID      string `json:"id"`
+	Address string `json:"address"`
+}
+
+// ClusterMessage carries an event for a player to whichever instance the
+// player is connected to.
+type ClusterMessage struct {
+	From     string          `json:"from"`
+	PlayerID string          `json:"playerId"`
+	Event    GameEvent       `json:"event"`
+	Payload  json.RawMessage `json:"payload"`
+}
+
+// ClusterBackend lets the gateways of several instances share the work.
+// Every room is leased to the instance hosting it, and messages for players
+// connected to another instance are published to all of them.
+type ClusterBackend interface {
+	// ClaimRoom leases the room to the instance, unless another instance
+	// holds the lease, and reports whether it did.
+	ClaimRoom(roomID string, instanceID string, ttl time.Duration) (bool, error)
+	// RenewRoom extends the lease of the instance, reporting false when
+	// the instance no longer holds it.
+	RenewRoom(roomID string, instanceID string, ttl time.Duration) (bool, error)
+	ReleaseRoom(roomID string, instanceID string) error
+	// RoomOwner returns the id of the instance holding the lease of the
+	// room, or an empty string when nobody does.
+	RoomOwner(roomID string) (string, error)
+	RegisterInstance(instance ClusterInstance, ttl time.Duration) error
+	// Instance returns a registered instance, or nil when its registration
+	// expired.
+	Instance(instanceID string) (*ClusterInstance, error)
+	Publish(message *ClusterMessage) error
+	// Subscribe delivers every message published from then on, including
+	// those of the subscribing instance, until stop is called. Delivery is
+	// best effort, as with Redis pub/sub.
+	Subscribe() (messages <-chan *ClusterMessage, stop func(), err error)
+}
+
+// InMemoryCluster is a ClusterBackend for gateways sharing one process,
+// which stands in for Redis when running several gateways side by side.
+type InMemoryCluster struct {
+	clock       Clock
+	leases      map[string]clusterLease
+	instances   map[string]registeredInstance
+	subscribers map[chan *ClusterMessage]bool
+	mutex       sync.Mutex
+}
+
+type clusterLease struct {
+	holder  string
+	expires time.Time
+}
+
+type registeredInstance struct {
+	instance ClusterInstance
+	expires  time.Time
+}
+
+func NewInMemoryCluster(clock Clock) *InMemoryCluster {
+	return &InMemoryCluster{
+		clock:       clock,
+		leases:      make(map[string]clusterLease),
+		instances:   make(map[string]registeredInstance),
+		subscribers: make(map[chan *ClusterMessage]bool),
+	}
+}
+
+func (cluster *InMemoryCluster) ClaimRoom(roomID string, instanceID string, ttl time.Duration) (bool, error) {
+	cluster.mutex.Lock()
+	defer cluster.mutex.Unlock()
+
+	if holder := cluster.holder(roomID); holder != "" && holder != instanceID {
+		return false, nil
+	}
+
+	cluster.leases[roomID] = clusterLease{holder: instanceID, expires: cluster.clock.Now().Add(ttl)}
+	return true, nil
+}
+
+func (cluster *InMemoryCluster) RenewRoom(roomID string, instanceID string, ttl time.Duration) (bool, error) {
+	cluster.mutex.Lock()
+	defer cluster.mutex.Unlock()
+
+	if cluster.holder(roomID) != instanceID {
+		return false, nil
+	}
+
+	cluster.leases[roomID] = clusterLease{holder: instanceID, expires: cluster.clock.Now().Add(ttl)}
+	return true, nil
+}
+
+func (cluster *InMemoryCluster) ReleaseRoom(roomID string, instanceID string) error {
+	cluster.mutex.Lock()
+	defer cluster.mutex.Unlock()
+
+	if cluster.holder(roomID) == instanceID {
+		delete(cluster.leases, roomID)
+	}
+	return nil
+}
+
+func (cluster *InMemoryCluster) RoomOwner(roomID string) (string, error) {
+	cluster.mutex.Lock()
+	defer cluster.mutex.Unlock()
+
+	return cluster.holder(roomID), nil
+}
+
+func (cluster *InMemoryCluster) RegisterInstance(instance ClusterInstance, ttl time.Duration) error {
+	cluster.mutex.Lock()
+	defer cluster.mutex.Unlock()
+
+	cluster.instances[instance.ID] = registeredInstance{instance: instance, expires: cluster.clock.Now().Add(ttl)}
+	return nil
+}
+
+func (cluster *InMemoryCluster) Instance(instanceID string) (*ClusterInstance, error) {
+	cluster.mutex.Lock()
+	defer cluster.mutex.Unlock()
+
+	registered, exists := cluster.instances[instanceID]
+	if !exists {
+		return nil, nil
+	}
+	if !cluster.clock.Now().Before(registered.expires) {
+		delete(cluster.instances, instanceID)
+		return nil, nil
+	}
+
+	instance := registered.instance
+	return &instance, nil
+}
+
+// Publish hands the message to every subscriber, dropping it for those
+// that are not keeping up.
+func (cluster *InMemoryCluster) Publish(message *ClusterMessage) error {
+	cluster.mutex.Lock()
+	defer cluster.mutex.Unlock()
+
+	for subscriber := range cluster.subscribers {
+		copied := *message
+		select {
+		case subscriber <- &copied:
+		default:
+		}
+	}
+	return nil
+}
+
+func (cluster *InMemoryCluster) Subscribe() (<-chan *ClusterMessage, func(), error) {
+	messages := make(chan *ClusterMessage, 256)
+
+	cluster.mutex.Lock()
+	cluster.subscribers[messages] = true
+	cluster.mutex.Unlock()
+
+	var once sync.Once
+	stop := func() {
+		once.Do(func() {
+			cluster.mutex.Lock()
+			delete(cluster.subscribers, messages)
+			cluster.mutex.Unlock()
+			close(messages)
+		})
+	}
+
+	return messages, stop, nil
+}
+
+// holder returns the instance holding the lease of the room, forgetting
+// the lease once it has expired.
+func (cluster *InMemoryCluster) holder(roomID string) string {
+	lease, exists := cluster.leases[roomID]
+	if !exists {
+		return ""
+	}
+	if !cluster.clock.Now().Before(lease.expires) {
+		delete(cluster.leases, roomID)
+		return ""
+	}
+
+	return lease.holder
+}
@@ -0,0 +1,136 @@
+package game
+
+import (
+	"ais-summoner/internal/database"
+	"encoding/json"
+	"log"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+const clusterChannel = "cluster:players"
+
+// renewLeaseScript extends a room lease only while the caller holds it.
+var renewLeaseScript = redis.NewScript(`
+if redis.call("GET", KEYS[1]) == ARGV[1] then
+	return redis.call("PEXPIRE", KEYS[1], ARGV[2])
+end
+return 0
+`)
+
+// releaseLeaseScript deletes a room lease only while the caller holds it.
+var releaseLeaseScript = redis.NewScript(`
+if redis.call("GET", KEYS[1]) == ARGV[1] then
+	return redis.call("DEL", KEYS[1])
+end
+return 0
+`)
+
+// RedisCluster keeps room leases and instance registrations in Redis, with
+// the values stored as JSON, and carries player messages over pub/sub.
+type RedisCluster struct {
+	redis  *database.Redis
+	logger *log.Logger
+}
+
+func NewRedisCluster(cache *database.Redis) *RedisCluster {
+	return &RedisCluster{
+		redis:  cache,
+		logger: log.New(log.Writer(), "[RedisCluster] ", log.LstdFlags),
+	}
+}
+
+func (cluster *RedisCluster) ClaimRoom(roomID string, instanceID string, ttl time.Duration) (bool, error) {
+	claimed, err := cluster.redis.SetCacheIfAbsent(leaseKey(roomID), instanceID, ttl)
+	if err != nil || claimed {
+		return claimed, err
+	}
+
+	// Claiming a room the instance already holds renews the lease.
+	return cluster.RenewRoom(roomID, instanceID, ttl)
+}
+
+func (cluster *RedisCluster) RenewRoom(roomID string, instanceID string, ttl time.Duration) (bool, error) {
+	holder, err := json.Marshal(instanceID)
+	if err != nil {
+		return false, err
+	}
+
+	result, err := cluster.redis.RunScript(renewLeaseScript, []string{leaseKey(roomID)}, string(holder), ttl.Milliseconds())
+	if err != nil {
+		return false, err
+	}
+
+	renewed, _ := result.(int64)
+	return renewed == 1, nil
+}
+
+func (cluster *RedisCluster) ReleaseRoom(roomID string, instanceID string) error {
+	holder, err := json.Marshal(instanceID)
+	if err != nil {
+		return err
+	}
+
+	_, err = cluster.redis.RunScript(releaseLeaseScript, []string{leaseKey(roomID)}, string(holder))
+	return err
+}
+
+func (cluster *RedisCluster) RoomOwner(roomID string) (string, error) {
+	var instanceID string
+	if err := cluster.redis.GetCache(leaseKey(roomID), &instanceID); err != nil {
+		return "", err
+	}
+
+	return instanceID, nil
+}
+
+func (cluster *RedisCluster) RegisterInstance(instance ClusterInstance, ttl time.Duration) error {
+	return cluster.redis.SetCache(instanceKey(instance.ID), instance, ttl)
+}
+
+func (cluster *RedisCluster) Instance(instanceID string) (*ClusterInstance, error) {
+	var instance *ClusterInstance
+	if err := cluster.redis.GetCache(instanceKey(instanceID), &instance); err != nil {
+		return nil, err
+	}
+
+	return instance, nil
+}
+
+func (cluster *RedisCluster) Publish(message *ClusterMessage) error {
+	return cluster.redis.Publish(clusterChannel, message)
+}
+
+func (cluster *RedisCluster) Subscribe() (<-chan *ClusterMessage, func(), error) {
+	pubsub, err := cluster.redis.Subscribe(clusterChannel)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// The channel of the subscription is closed when it is, which ends
+	// the loop below and closes the messages channel in turn.
+	messages := make(chan *ClusterMessage, 256)
+	go func() {
+		defer close(messages)
+
+		for received := range pubsub.Channel() {
+			var message ClusterMessage
+			if err := json.Unmarshal([]byte(received.Payload), &message); err != nil {
+				cluster.logger.Printf("Error decoding cluster message: %v", err)
+				continue
+			}
+			messages <- &message
+		}
+	}()
+
+	return messages, func() { pubsub.Close() }, nil
+}
+
+func leaseKey(roomID string) string {
+	return "cluster:room:" + roomID
+}
+
+func instanceKey(instanceID string) string {
+	return "cluster:instance:" + instanceID
+}
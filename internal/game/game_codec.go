@@ -0,0 +1,110 @@
+package game
+
+import (
+	"encoding"
+	"encoding/json"
+	"errors"
+
+	"github.com/gorilla/websocket"
+)
+
+const (
+	JSONSubprotocol   = "hotelio.json.v1"
+	BinarySubprotocol = "hotelio.binary.v1"
+)
+
+// Codec encodes outgoing messages and performs the first decoding phase of
+// incoming ones. Clients pick a codec through the WebSocket subprotocol.
+type Codec interface {
+	Subprotocol() string
+	MessageType() int
+	Encode(event GameEvent, payload interface{}) ([]byte, error)
+	Decode(data []byte) (GameEnvelope, error)
+}
+
+// codecForSubprotocol returns the codec negotiated during the upgrade,
+// falling back to JSON so plain WebSocket tools keep working.
+func codecForSubprotocol(subprotocol string) Codec {
+	if subprotocol == BinarySubprotocol {
+		return BinaryCodec{}
+	}
+
+	return JSONCodec{}
+}
+
+type JSONCodec struct{}
+
+func (JSONCodec) Subprotocol() string {
+	return JSONSubprotocol
+}
+
+func (JSONCodec) MessageType() int {
+	return websocket.TextMessage
+}
+
+func (JSONCodec) Encode(event GameEvent, payload interface{}) ([]byte, error) {
+	return json.Marshal(GameWebSocketMessage{
+		Event:   event,
+		Payload: payload,
+	})
+}
+
+func (JSONCodec) Decode(data []byte) (GameEnvelope, error) {
+	var envelope GameEnvelope
+	err := json.Unmarshal(data, &envelope)
+	return envelope, err
+}
+
+const (
+	binaryFormatJSON byte = 0
+	binaryFormatRaw  byte = 1
+)
+
+var errShortFrame = errors.New("binary frame is too short")
+
+// BinaryCodec frames every message as one event byte, one format byte and
+// the payload. Payloads sent at tick rate implement encoding.BinaryMarshaler
+// and use a little-endian layout; everything else is carried as JSON.
+type BinaryCodec struct{}
+
+func (BinaryCodec) Subprotocol() string {
+	return BinarySubprotocol
+}
+
+func (BinaryCodec) MessageType() int {
+	return websocket.BinaryMessage
+}
+
+func (BinaryCodec) Encode(event GameEvent, payload interface{}) ([]byte, error) {
+	if marshaler, ok := payload.(encoding.BinaryMarshaler); ok {
+		body, err := marshaler.MarshalBinary()
+		if err != nil {
+			return nil, err
+		}
+		return append([]byte{byte(event), binaryFormatRaw}, body...), nil
+	}
+
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return nil, err
+	}
+	return append([]byte{byte(event), binaryFormatJSON}, body...), nil
+}
+
+func (BinaryCodec) Decode(data []byte) (GameEnvelope, error) {
+	if len(data) < 2 {
+		return GameEnvelope{}, errShortFrame
+	}
+
+	envelope := GameEnvelope{Event: GameEvent(data[0])}
+	switch data[1] {
+	case binaryFormatJSON:
+		envelope.Payload = json.RawMessage(data[2:])
+	case binaryFormatRaw:
+		envelope.binary = data[2:]
+	default:
+		return GameEnvelope{}, errors.New("unknown binary payload format")
+	}
+
+	return envelope, nil
+}
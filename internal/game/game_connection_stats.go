@@ -0,0 +1,205 @@
+package game
+
+import (
+	"encoding/binary"
+	"math"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	// pongWait is how long a connection may stay silent, pongs included,
+	// before Read gives up on it. It spans a few pings so one lost pong
+	// does not drop the client.
+	pongWait = 3 * pingInterval
+	// rttSmoothing weighs each new round trip sample into the estimate, as
+	// TCP does for its smoothed RTT.
+	rttSmoothing = 0.125
+	// jitterSmoothing is the gain RTP uses for its interarrival jitter.
+	jitterSmoothing = 1.0 / 16
+)
+
+// ConnectionStats is a snapshot of the traffic of one client. Rates are
+// measured over the last ping interval. DroppedIn counts stale inputs that
+// were discarded, SkippedOut state updates replaced by a newer one before
+// they were written and DroppedOut messages that did not fit the send
+// queue.
+type ConnectionStats struct {
+	ConnectedAt          time.Time `json:"connectedAt"`
+	RTTMillis            float64   `json:"rttMs"`
+	JitterMillis         float64   `json:"jitterMs"`
+	MessagesIn           uint64    `json:"messagesIn"`
+	MessagesOut          uint64    `json:"messagesOut"`
+	MessagesInPerSecond  float64   `json:"messagesInPerSecond"`
+	MessagesOutPerSecond float64   `json:"messagesOutPerSecond"`
+	BytesIn              uint64    `json:"bytesIn"`
+	BytesOut             uint64    `json:"bytesOut"`
+	DroppedIn            uint64    `json:"droppedIn"`
+	SkippedOut           uint64    `json:"skippedOut"`
+	DroppedOut           uint64    `json:"droppedOut"`
+}
+
+// connectionStats collects the statistics of a client. Counters are updated
+// from the read, write and room goroutines and read from anywhere.
+type connectionStats struct {
+	connectedAt time.Time
+	rtt         atomic.Int64
+	jitter      atomic.Int64
+	messagesIn  atomic.Uint64
+	messagesOut atomic.Uint64
+	bytesIn     atomic.Uint64
+	bytesOut    atomic.Uint64
+	droppedIn   atomic.Uint64
+	skippedOut  atomic.Uint64
+	droppedOut  atomic.Uint64
+	rateIn      atomic.Uint64
+	rateOut     atomic.Uint64
+
+	// The last round trip sample is only touched by the pong handler, and
+	// the counters of the last rate sample only by Write.
+	lastSample time.Duration
+	sampledAt  time.Time
+	sampledIn  uint64
+	sampledOut uint64
+}
+
+func (stats *connectionStats) received(size int) {
+	stats.messagesIn.Add(1)
+	stats.bytesIn.Add(uint64(size))
+}
+
+func (stats *connectionStats) sent(size int) {
+	stats.messagesOut.Add(1)
+	stats.bytesOut.Add(uint64(size))
+}
+
+// roundTrip folds a sample into the smoothed RTT and into the jitter, the
+// smoothed difference between consecutive samples.
+func (stats *connectionStats) roundTrip(sample time.Duration) {
+	rtt := time.Duration(stats.rtt.Load())
+	if rtt == 0 {
+		rtt = sample
+	} else {
+		rtt += time.Duration(rttSmoothing * float64(sample-rtt))
+	}
+	stats.rtt.Store(int64(rtt))
+
+	if stats.lastSample != 0 {
+		difference := sample - stats.lastSample
+		if difference < 0 {
+			difference = -difference
+		}
+		jitter := time.Duration(stats.jitter.Load())
+		jitter += time.Duration(jitterSmoothing * float64(difference-jitter))
+		stats.jitter.Store(int64(jitter))
+	}
+	stats.lastSample = sample
+}
+
+// sampleRates measures the message rates since the previous sample. The
+// rates are stored as float bits so readers get them without locking.
+func (stats *connectionStats) sampleRates(now time.Time) {
+	if stats.sampledAt.IsZero() {
+		stats.sampledAt = stats.connectedAt
+	}
+
+	elapsed := now.Sub(stats.sampledAt).Seconds()
+	if elapsed <= 0 {
+		return
+	}
+
+	in, out := stats.messagesIn.Load(), stats.messagesOut.Load()
+	stats.rateIn.Store(math.Float64bits(float64(in-stats.sampledIn) / elapsed))
+	stats.rateOut.Store(math.Float64bits(float64(out-stats.sampledOut) / elapsed))
+	stats.sampledAt, stats.sampledIn, stats.sampledOut = now, in, out
+}
+
+func (stats *connectionStats) snapshot() ConnectionStats {
+	return ConnectionStats{
+		ConnectedAt:          stats.connectedAt,
+		RTTMillis:            milliseconds(time.Duration(stats.rtt.Load())),
+		JitterMillis:         milliseconds(time.Duration(stats.jitter.Load())),
+		MessagesIn:           stats.messagesIn.Load(),
+		MessagesOut:          stats.messagesOut.Load(),
+		MessagesInPerSecond:  math.Float64frombits(stats.rateIn.Load()),
+		MessagesOutPerSecond: math.Float64frombits(stats.rateOut.Load()),
+		BytesIn:              stats.bytesIn.Load(),
+		BytesOut:             stats.bytesOut.Load(),
+		DroppedIn:            stats.droppedIn.Load(),
+		SkippedOut:           stats.skippedOut.Load(),
+		DroppedOut:           stats.droppedOut.Load(),
+	}
+}
+
+// Stats returns a snapshot of the traffic statistics of the client.
+func (client *GameClient) Stats() ConnectionStats {
+	return client.stats.snapshot()
+}
+
+// RTT returns the smoothed round trip time to the client, or zero before
+// the first pong arrived.
+func (client *GameClient) RTT() time.Duration {
+	return time.Duration(client.stats.rtt.Load())
+}
+
+// Jitter returns how much consecutive round trips to the client vary.
+func (client *GameClient) Jitter() time.Duration {
+	return time.Duration(client.stats.jitter.Load())
+}
+
+// pingPayload stamps a ping with its send time, which the pong echoes.
+func pingPayload(now time.Time) []byte {
+	return binary.BigEndian.AppendUint64(nil, uint64(now.UnixNano()))
+}
+
+// handlePong records the round trip of the echoed ping and, once the
+// client is authenticated, extends the read deadline.
+func (client *GameClient) handlePong(data []byte) {
+	if client.authenticated() {
+		client.transport.SetReadDeadline(time.Now().Add(pongWait))
+	}
+	if len(data) != 8 {
+		return
+	}
+
+	sent := time.Unix(0, int64(binary.BigEndian.Uint64(data)))
+	if sample := time.Since(sent); sample >= 0 {
+		client.stats.roundTrip(sample)
+	}
+}
+
+// ConnectionInfo describes a connected client for the admin endpoint.
+type ConnectionInfo struct {
+	PlayerID    string          `json:"playerId,omitempty"`
+	RoomID      string          `json:"roomId,omitempty"`
+	RemoteAddr  string          `json:"remoteAddr"`
+	Subprotocol string          `json:"subprotocol"`
+	Stats       ConnectionStats `json:"stats"`
+}
+
+// Connections lists the clients connected to this instance with their
+// traffic statistics.
+func (gateway *GameGateway) Connections() []ConnectionInfo {
+	gateway.mutex.RLock()
+	defer gateway.mutex.RUnlock()
+
+	connections := make([]ConnectionInfo, 0, len(gateway.clients))
+	for client := range gateway.clients {
+		info := ConnectionInfo{
+			PlayerID:    client.ID(),
+			RemoteAddr:  client.transport.RemoteAddr(),
+			Subprotocol: client.codec.Subprotocol(),
+			Stats:       client.Stats(),
+		}
+		if room := client.room.Load(); room != nil {
+			info.RoomID = room.id
+		}
+		connections = append(connections, info)
+	}
+
+	return connections
+}
+
+func milliseconds(duration time.Duration) float64 {
+	return float64(duration) / float64(time.Millisecond)
+}
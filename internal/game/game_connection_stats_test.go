@@ -0,0 +1,57 @@
+package game
+
+import (
+	"ais-summoner/internal/models"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// TestConnectionsRaceLogins lists the connections while clients log in,
+// join and leave, which the race detector checks.
+func TestConnectionsRaceLogins(t *testing.T) {
+	clock := newManualClock()
+	gateway := newGameGateway(NewInMemoryCluster(clock), NewInMemoryMatchQueue(clock), clock)
+	go gateway.Run()
+
+	room := gateway.CreateRoom(squareTerrain())
+	defer room.stop()
+
+	listed := make(chan struct{})
+	stop := make(chan struct{})
+	go func() {
+		defer close(listed)
+		for {
+			select {
+			case <-stop:
+				return
+			case <-time.After(time.Millisecond):
+				// Pausing between lists leaves the logins the gateway
+				// mutex, which a busy loop starves under the race detector.
+				gateway.Connections()
+			}
+		}
+	}()
+
+	peers := make([]*testPeer, 10)
+	for i := range peers {
+		peers[i] = servePeer(gateway, &models.User{ID: primitive.NewObjectID()})
+		peers[i].send(t, JoinGame, &JoinGamePayload{RoomID: room.ID()})
+	}
+	for _, peer := range peers {
+		peer.expect(t, JoinGame, nil)
+		peer.send(t, LeaveGame, &LeaveGamePayload{})
+	}
+
+	waitFor(t, "the players to leave", func() bool {
+		for _, connection := range gateway.Connections() {
+			if connection.PlayerID == "" || connection.RoomID != "" {
+				return false
+			}
+		}
+		return true
+	})
+	close(stop)
+	<-listed
+}
@@ -0,0 +1,144 @@
+package game
+
+import (
+	"context"
+	"time"
+)
+
+const (
+	// drainPollInterval is how often Drain checks whether the running
+	// matches have ended.
+	drainPollInterval = 500 * time.Millisecond
+	// disconnectTimeout is how long Drain waits for the sockets to flush
+	// their queues and close.
+	disconnectTimeout = 2 * time.Second
+)
+
+// Draining reports whether the gateway is shutting down and refuses new
+// games.
+func (gateway *GameGateway) Draining() bool {
+	return gateway.draining.Load()
+}
+
+// Drain shuts the gateway down gracefully. It refuses new joins, tells
+// every client the server goes into maintenance and lets running matches
+// play on until ctx is done. Matches still running then are ended and
+// recorded like any other, casual rooms are closed and recorded, and every
+// socket is closed with CloseServiceRestart. Drain returns once the
+// records are written, so the databases can be disconnected afterwards.
+func (gateway *GameGateway) Drain(ctx context.Context) {
+	if gateway.draining.Swap(true) {
+		return
+	}
+	gateway.matchmaker.Stop()
+
+	maintenance := &ServerMaintenancePayload{}
+	if deadline, ok := ctx.Deadline(); ok {
+		maintenance.Deadline = deadline.UnixMilli()
+	}
+	gateway.logger.Printf("Draining, waiting for %d matches", gateway.runningMatches())
+	gateway.broadcast(ServerMaintenance, maintenance)
+
+	ticker := gateway.clock.NewTicker(drainPollInterval)
+	defer ticker.Stop()
+wait:
+	for gateway.runningMatches() > 0 {
+		select {
+		case <-ticker.C():
+		case <-ctx.Done():
+			gateway.logger.Printf("Drain deadline passed with %d matches running", gateway.runningMatches())
+			break wait
+		}
+	}
+
+	gateway.closeRooms()
+	close(gateway.done)
+	gateway.disconnectClients(CloseServiceRestart, "server restarting")
+
+	// Work handed to background from now on could start after Wait
+	// returned, so it is refused.
+	gateway.backgroundMutex.Lock()
+	gateway.backgroundStopped = true
+	gateway.backgroundMutex.Unlock()
+	gateway.pending.Wait()
+	gateway.logger.Printf("Drained")
+}
+
+// runningMatches counts the ranked matches that have not ended.
+func (gateway *GameGateway) runningMatches() int {
+	gateway.mutex.RLock()
+	defer gateway.mutex.RUnlock()
+
+	running := 0
+	for _, room := range gateway.rooms {
+		if room.hasRunningMatch() {
+			running++
+		}
+	}
+
+	return running
+}
+
+// closeRooms ends the running matches, which records them, and closes and
+// records every other room.
+func (gateway *GameGateway) closeRooms() {
+	gateway.mutex.RLock()
+	rooms := make([]*GameRoom, 0, len(gateway.rooms))
+	for _, room := range gateway.rooms {
+		rooms = append(rooms, room)
+	}
+	gateway.mutex.RUnlock()
+
+	for _, room := range rooms {
+		if outcome := room.endMatch(); outcome != nil {
+			gateway.finishMatch(room, outcome)
+			continue
+		}
+
+		if gateway.closeRoom(room) {
+			gateway.recordCasualMatch(room)
+		}
+	}
+}
+
+// broadcast sends a message to every connected client.
+func (gateway *GameGateway) broadcast(event GameEvent, payload interface{}) {
+	gateway.mutex.RLock()
+	defer gateway.mutex.RUnlock()
+
+	for client := range gateway.clients {
+		client.sendMessage(event, payload)
+	}
+}
+
+// disconnectClients closes every socket with the given close code and
+// waits a moment for them to be unregistered.
+func (gateway *GameGateway) disconnectClients(code int, reason string) {
+	gateway.mutex.RLock()
+	for client := range gateway.clients {
+		client.disconnect(code, reason)
+	}
+	gateway.mutex.RUnlock()
+
+	timeout := make(chan struct{})
+	timer := gateway.clock.AfterFunc(disconnectTimeout, func() {
+		close(timeout)
+	})
+	defer timer.Stop()
+
+	for gateway.clientCount() > 0 {
+		select {
+		case <-gateway.emptied:
+		case <-timeout:
+			gateway.logger.Printf("%d clients still connected after %v", gateway.clientCount(), disconnectTimeout)
+			return
+		}
+	}
+}
+
+func (gateway *GameGateway) clientCount() int {
+	gateway.mutex.RLock()
+	defer gateway.mutex.RUnlock()
+
+	return len(gateway.clients)
+}
@@ -0,0 +1,59 @@
+package game
+
+import (
+	"ais-summoner/internal/models"
+	"context"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestDrainClosesPlayersAndSpectators(t *testing.T) {
+	clock := newManualClock()
+	gateway := newGameGateway(NewInMemoryCluster(clock), NewInMemoryMatchQueue(clock), clock)
+	go gateway.Run()
+
+	player := servePeer(gateway, &models.User{ID: primitive.NewObjectID(), Username: "player"})
+
+	recorder := NewReplayRecorder(squareTerrain(), gateway.config)
+	recorder.End(1000)
+	data, _ := recorder.Bytes()
+	replay, err := DecodeReplay(data)
+	if err != nil {
+		t.Fatalf("decoding replay: %v", err)
+	}
+	server, far := NewPipe()
+	gateway.serveReplay(server, JSONCodec{}, replay)
+	spectator := &testPeer{transport: far, codec: JSONCodec{}}
+
+	waitFor(t, "both clients to register", func() bool {
+		return gateway.clientCount() == 2
+	})
+
+	drained := make(chan struct{})
+	go func() {
+		defer close(drained)
+		gateway.Drain(context.Background())
+	}()
+
+	for _, peer := range []*testPeer{player, spectator} {
+		peer.expect(t, ServerMaintenance, nil)
+		peer.expectClose(t, CloseServiceRestart)
+	}
+
+	select {
+	case <-drained:
+	case <-time.After(2 * time.Second):
+		t.Fatal("Drain did not return once the clients were gone")
+	}
+	if count := gateway.clientCount(); count != 0 {
+		t.Fatalf("%d clients still registered", count)
+	}
+
+	// Work handed over after the drain is refused rather than racing the
+	// wait for it.
+	gateway.background(func() {
+		t.Error("background work ran after the drain")
+	})
+}
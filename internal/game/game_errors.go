@@ -0,0 +1,71 @@
+package game
+
+type ErrorCode string
+
+const (
+	CodeMalformedMessage ErrorCode = "malformed_message"
+	CodeInvalidPayload   ErrorCode = "invalid_payload"
+	CodeUnknownEvent     ErrorCode = "unknown_event"
+	CodeUnauthorized     ErrorCode = "unauthorized"
+	CodeNotInRoom        ErrorCode = "not_in_room"
+	CodeAlreadyInRoom    ErrorCode = "already_in_room"
+	CodeRoomNotFound     ErrorCode = "room_not_found"
+	CodeTerrainNotFound  ErrorCode = "terrain_not_found"
+	CodeAlreadySearching ErrorCode = "already_searching"
+	CodeNotSearching     ErrorCode = "not_searching"
+	CodeNotParticipant   ErrorCode = "not_participant"
+	CodeSessionExpired   ErrorCode = "session_expired"
+	CodeServerDraining   ErrorCode = "server_draining"
+	CodeInternal         ErrorCode = "internal_error"
+)
+
+// GameError is an error that is reported back to the client as an Error
+// event carrying its code and message.
+type GameError struct {
+	Code    ErrorCode
+	Message string
+}
+
+func NewGameError(code ErrorCode, message string) *GameError {
+	return &GameError{Code: code, Message: message}
+}
+
+func (err *GameError) Error() string {
+	return err.Message
+}
+
+func (err *GameError) Payload() *ErrorPayload {
+	return &ErrorPayload{Code: err.Code, Message: err.Message}
+}
+
+var (
+	ErrRoomNotFound       = NewGameError(CodeRoomNotFound, "room not found")
+	ErrTerrainNotFound    = NewGameError(CodeTerrainNotFound, "terrain not found")
+	ErrAlreadyInRoom      = NewGameError(CodeAlreadyInRoom, "client is already in a room")
+	ErrNotInRoom          = NewGameError(CodeNotInRoom, "client is not in a room")
+	ErrInvalidCredentials = NewGameError(CodeUnauthorized, "invalid credentials")
+	ErrAlreadySearching   = NewGameError(CodeAlreadySearching, "client is already searching for a match")
+	ErrNotSearching       = NewGameError(CodeNotSearching, "client is not searching for a match")
+	ErrNotParticipant     = NewGameError(CodeNotParticipant, "client is not a participant of this match")
+	ErrSessionExpired     = NewGameError(CodeSessionExpired, "session expired or unknown")
+	ErrServerDraining     = NewGameError(CodeServerDraining, "server is shutting down")
+)
+
+// RoomRedirect is returned when the requested room is hosted by another
+// instance. Clients are sent a Redirect event for it instead of an Error.
+type RoomRedirect struct {
+	RoomID   string
+	Instance ClusterInstance
+}
+
+func (redirect *RoomRedirect) Error() string {
+	return "room " + redirect.RoomID + " is hosted by instance " + redirect.Instance.ID
+}
+
+func (redirect *RoomRedirect) Payload() *RedirectPayload {
+	return &RedirectPayload{
+		RoomID:     redirect.RoomID,
+		InstanceID: redirect.Instance.ID,
+		Address:    redirect.Instance.Address,
+	}
+}
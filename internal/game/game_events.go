@@ -1,24 +1,43 @@
 package game
 
+import "encoding/json"
+
 type GameEvent int
 type GameWebSocketMessage struct {
 	Event   GameEvent   `json:"event"`
 	Payload interface{} `json:"payload"`
 }
 
+// GameEnvelope is the first decoding phase of an incoming message. The
+// payload stays raw until the event, and with it the payload type, is known.
+// Binary codecs leave Payload empty and carry a raw layout in binary instead.
+type GameEnvelope struct {
+	Event   GameEvent       `json:"event"`
+	Payload json.RawMessage `json:"payload"`
+	binary  []byte
+}
+
 const (
-	Authentication  GameEvent = 0
-	JoinGame        GameEvent = 1
-	LeaveGame       GameEvent = 2
-	GameStateUpdate GameEvent = 3
-	PlayerJoin      GameEvent = 4
-	PlayerLeave     GameEvent = 5
-	PlayerMove      GameEvent = 6
-	PlayerDash      GameEvent = 7
-	Error           GameEvent = 252
-	Forbidden       GameEvent = 253
-	Unauthorized    GameEvent = 254
-	ServerError     GameEvent = 255
+	Authentication    GameEvent = 0
+	JoinGame          GameEvent = 1
+	LeaveGame         GameEvent = 2
+	GameStateUpdate   GameEvent = 3
+	PlayerJoin        GameEvent = 4
+	PlayerLeave       GameEvent = 5
+	PlayerMove        GameEvent = 6
+	PlayerDash        GameEvent = 7
+	FindMatch         GameEvent = 8
+	CancelMatch       GameEvent = 9
+	MatchFound        GameEvent = 10
+	MatchEnded        GameEvent = 11
+	SnapshotAck       GameEvent = 12
+	Redirect          GameEvent = 13
+	ResumeSession     GameEvent = 14
+	ServerMaintenance GameEvent = 15
+	Error             GameEvent = 252
+	Forbidden         GameEvent = 253
+	Unauthorized      GameEvent = 254
+	ServerError       GameEvent = 255
 )
 
 // String returns the string representation of GameEvent
@@ -40,6 +59,22 @@ func (g GameEvent) String() string {
 		return "PlayerMove"
 	case PlayerDash:
 		return "PlayerDash"
+	case FindMatch:
+		return "FindMatch"
+	case CancelMatch:
+		return "CancelMatch"
+	case MatchFound:
+		return "MatchFound"
+	case MatchEnded:
+		return "MatchEnded"
+	case SnapshotAck:
+		return "SnapshotAck"
+	case Redirect:
+		return "Redirect"
+	case ResumeSession:
+		return "ResumeSession"
+	case ServerMaintenance:
+		return "ServerMaintenance"
 	case Error:
 		return "Error"
 	case Forbidden:
@@ -2,40 +2,101 @@ package game
 
 import (
 	"ais-summoner/internal/database"
+	"ais-summoner/internal/leaderboard"
+	"ais-summoner/internal/models"
+	"ais-summoner/internal/pkg/authenticator"
+	"context"
+	"time"
 
 	"log"
 	"net/http"
 	"sync"
+	"sync/atomic"
 
+	"github.com/gin-contrib/sessions"
 	"github.com/gorilla/websocket"
+	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
 type GameGateway struct {
-	clients    map[*GameClient]bool
-	mongodb    *database.MongoDB
-	logger     *log.Logger
-	mutex      sync.RWMutex
+	auth        *authenticator.Authenticator
+	clients     map[*GameClient]bool
+	cluster     ClusterBackend
+	instance    ClusterInstance
+	rooms       map[string]*GameRoom
+	config      RoomConfig
+	clock       Clock
+	mongodb     *database.MongoDB
+	leaderboard *leaderboard.Leaderboard
+	logger      *log.Logger
+	matchmaker  *Matchmaker
+	mutex       sync.RWMutex
+	draining    atomic.Bool
+	pending     sync.WaitGroup
+	// backgroundStopped refuses background work once Drain waits for it.
+	backgroundStopped bool
+	backgroundMutex   sync.Mutex
+	done              chan struct{}
+	// emptied is signalled whenever the last client is unregistered.
+	emptied    chan struct{}
 	redis      *database.Redis
 	register   chan *GameClient
+	replays    ReplayStore
+	sessions   sessions.Store
 	unregister chan *GameClient
 	upgrader   websocket.Upgrader
 }
 
-func NewGameGateway(mongodb *database.MongoDB, cache *database.Redis) *GameGateway {
-	return &GameGateway{
-		clients: make(map[*GameClient]bool),
-		mongodb: mongodb,
-		logger:  log.New(log.Writer(), "[GameGateway] ", log.LstdFlags),
-		redis:   cache,
+// NewGameGateway creates the gateway of this instance, which shares its
+// rooms with the other instances through Redis.
+func NewGameGateway(mongodb *database.MongoDB, cache *database.Redis, auth *authenticator.Authenticator, store sessions.Store) *GameGateway {
+	gateway := newGameGateway(NewRedisCluster(cache), NewRedisMatchQueue(cache), SystemClock)
+	gateway.auth = auth
+	gateway.mongodb = mongodb
+	gateway.leaderboard = leaderboard.NewLeaderboard(cache)
+	gateway.redis = cache
+	gateway.replays = NewReplayStore(mongodb)
+	gateway.sessions = store
+
+	if gateway.instance.Address == "" {
+		gateway.logger.Printf("GAME_PUBLIC_ADDRESS is not set: players of other instances asking for rooms hosted here get ErrRoomNotFound instead of a redirect")
+	}
+
+	return gateway
+}
+
+// newGameGateway creates a gateway sharing its rooms through the cluster
+// backend. It has no databases until NewGameGateway adds them; gateways
+// without them, like those of tests, keep no records.
+func newGameGateway(cluster ClusterBackend, queue MatchQueue, clock Clock) *GameGateway {
+	gateway := &GameGateway{
+		clients:    make(map[*GameClient]bool),
+		cluster:    cluster,
+		instance:   newClusterInstance(),
+		rooms:      make(map[string]*GameRoom),
+		config:     DefaultRoomConfig(),
+		clock:      clock,
+		done:       make(chan struct{}),
+		emptied:    make(chan struct{}, 1),
+		logger:     log.New(log.Writer(), "[GameGateway] ", log.LstdFlags),
+		register:   make(chan *GameClient),
+		unregister: make(chan *GameClient),
 		upgrader: websocket.Upgrader{
+			Subprotocols: []string{BinarySubprotocol, JSONSubprotocol},
 			CheckOrigin: func(r *http.Request) bool {
 				return true
 			},
 		},
 	}
+	gateway.matchmaker = NewMatchmaker(gateway, queue, DefaultMatchmakerConfig(), clock)
+
+	return gateway
 }
 
 func (gateway *GameGateway) Run() {
+	go gateway.matchmaker.Run()
+	go gateway.runCluster()
+
 	for {
 		select {
 		case client := <-gateway.register:
@@ -45,11 +106,17 @@ func (gateway *GameGateway) Run() {
 			gateway.logger.Printf("Client registered")
 
 		case client := <-gateway.unregister:
+			gateway.matchmaker.Cancel(client)
+			gateway.dropClient(client)
+
 			gateway.mutex.Lock()
-			_, exists := gateway.clients[client]
-			if exists {
-				delete(gateway.clients, client)
-				close(client.send)
+			delete(gateway.clients, client)
+			client.queue.close(CloseNormal, "")
+			if len(gateway.clients) == 0 {
+				select {
+				case gateway.emptied <- struct{}{}:
+				default:
+				}
 			}
 			gateway.mutex.Unlock()
 			gateway.logger.Printf("Client unregistered")
@@ -57,20 +124,255 @@ func (gateway *GameGateway) Run() {
 	}
 }
 
+// HandleWebSocketConnection upgrades the request to a game socket. Requests
+// carrying a valid session cookie are authenticated right away; any other
+// socket must send an Authentication message first. Clients that offer the
+// binary subprotocol get BinaryCodec frames, every other client gets JSON.
 func (gateway *GameGateway) HandleWebSocketConnection(w http.ResponseWriter, r *http.Request) {
+	select {
+	case <-gateway.done:
+		http.Error(w, ErrServerDraining.Error(), http.StatusServiceUnavailable)
+		return
+	default:
+	}
+
+	user := gateway.authenticateRequest(r)
+
 	conn, err := gateway.upgrader.Upgrade(w, r, nil)
 	if err != nil {
 		gateway.logger.Printf("Error upgrading connection: %v", err)
+		return
 	}
 
-	client := &GameClient{
-		connection: conn,
-		send:       make(chan []byte, 256),
-		gateway:    gateway,
-	}
+	codec := codecForSubprotocol(conn.Subprotocol())
+	gateway.Serve(NewWebSocketTransport(conn, codec.MessageType()), codec, user)
+}
+
+// Serve runs a client over the transport. A nil user must authenticate
+// with its first message, like a socket without a session cookie.
+func (gateway *GameGateway) Serve(transport Transport, codec Codec, user *models.User) {
+	client := newGameClient(gateway, codec, transport)
 
-	gateway.clients[client] = true
+	gateway.register <- client
+
+	if user != nil {
+		client.bindUser(user)
+	}
 
 	go client.Read()
 	go client.Write()
 }
+
+// CreateRoom opens a new room on the given terrain.
+func (gateway *GameGateway) CreateRoom(terrain *models.Terrain) *GameRoom {
+	room := NewGameRoom(primitive.NewObjectID().Hex(), terrain, gateway.config, gateway.clock)
+
+	gateway.mutex.Lock()
+	gateway.rooms[room.id] = room
+	gateway.mutex.Unlock()
+
+	go room.Run()
+	gateway.leaseRoom(room.id)
+
+	gateway.logger.Printf("Room %s created on terrain %s", room.id, terrain.ID.Hex())
+	return room
+}
+
+// CreateMatchRoom opens a ranked room that only the given players may join.
+// When the match ends its outcome is applied to their ratings.
+func (gateway *GameGateway) CreateMatchRoom(terrain *models.Terrain, playerIDs []string) *GameRoom {
+	room := NewGameRoom(primitive.NewObjectID().Hex(), terrain, gateway.config, gateway.clock)
+	room.match = newRoomMatch(playerIDs, gateway.config.Ticks(gateway.config.MatchDuration))
+	room.onMatchEnd = gateway.matchEnded
+
+	gateway.mutex.Lock()
+	gateway.rooms[room.id] = room
+	gateway.mutex.Unlock()
+
+	go room.Run()
+	gateway.leaseRoom(room.id)
+
+	gateway.logger.Printf("Match room %s created on terrain %s", room.id, terrain.ID.Hex())
+	return room
+}
+
+// matchEnded is called by a ranked room, with its mutex held, when its
+// match ends.
+func (gateway *GameGateway) matchEnded(room *GameRoom, outcome *MatchOutcome) {
+	gateway.background(func() {
+		gateway.finishMatch(room, outcome)
+	})
+}
+
+// finishMatch closes the room of an ended match and records its outcome.
+func (gateway *GameGateway) finishMatch(room *GameRoom, outcome *MatchOutcome) {
+	gateway.closeRoom(room)
+	gateway.recordMatch(room.record(outcome), gateway.replayOf(room))
+	gateway.recordRatings(outcome)
+}
+
+// closeRoom removes every member from the room and tears it down. It
+// reports false when the room had already been closed.
+func (gateway *GameGateway) closeRoom(room *GameRoom) bool {
+	gateway.mutex.Lock()
+	defer gateway.mutex.Unlock()
+
+	for _, member := range room.members() {
+		room.leave(member)
+		if member.bot != nil {
+			member.bot.Stop()
+		}
+	}
+
+	if gateway.rooms[room.id] != room {
+		return false
+	}
+
+	delete(gateway.rooms, room.id)
+	room.stop()
+	gateway.background(func() {
+		gateway.releaseRoom(room.id)
+	})
+	gateway.logger.Printf("Room %s closed", room.id)
+	return true
+}
+
+// Replays returns the store the replays of finished matches are kept in.
+func (gateway *GameGateway) Replays() ReplayStore {
+	return gateway.replays
+}
+
+func (gateway *GameGateway) replayOf(room *GameRoom) []byte {
+	replay, err := room.replay()
+	if err != nil {
+		gateway.logger.Printf("Error encoding replay of room %s: %v", room.id, err)
+		return nil
+	}
+
+	return replay
+}
+
+// FindRoom returns the room with the given id, or nil if it does not exist.
+func (gateway *GameGateway) FindRoom(id string) *GameRoom {
+	gateway.mutex.RLock()
+	defer gateway.mutex.RUnlock()
+
+	return gateway.rooms[id]
+}
+
+// joinRoom puts the client in the room identified by roomID. When no room id
+// is given a new room is created on the terrain identified by terrainID.
+// Rooms hosted by another instance yield a RoomRedirect.
+func (gateway *GameGateway) joinRoom(client *GameClient, roomID string, terrainID string) error {
+	if client.room.Load() != nil {
+		return ErrAlreadyInRoom
+	}
+	if gateway.Draining() {
+		return ErrServerDraining
+	}
+
+	var room *GameRoom
+	if roomID != "" {
+		room = gateway.FindRoom(roomID)
+		if room == nil {
+			return gateway.locateRoom(roomID)
+		}
+	} else {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+
+		terrain, err := gateway.mongodb.TerrainRepository().GetByID(ctx, terrainID)
+		if err != nil {
+			return err
+		}
+		if terrain == nil {
+			return ErrTerrainNotFound
+		}
+
+		room = gateway.CreateRoom(terrain)
+	}
+
+	// Hold the gateway lock so the room cannot be torn down between the
+	// lookup above and the join below.
+	gateway.mutex.Lock()
+	defer gateway.mutex.Unlock()
+
+	if gateway.rooms[room.id] != room {
+		return ErrRoomNotFound
+	}
+	if client.room.Load() != nil {
+		return ErrAlreadyInRoom
+	}
+
+	return room.join(client)
+}
+
+// leaveRoom removes the client from its current room and tears the room
+// down once the last player is gone. Bots do not keep a room open: they
+// are stopped when the last player that is not a bot leaves.
+func (gateway *GameGateway) leaveRoom(client *GameClient) {
+	gateway.mutex.Lock()
+	defer gateway.mutex.Unlock()
+
+	room := client.room.Load()
+	if room == nil {
+		return
+	}
+
+	room.leave(client)
+	gateway.tidyRoom(room, client.bot == nil)
+}
+
+// tidyRoom runs after a player is gone from the room. When a human left
+// and no other human remains, the bots are stopped; the room is closed
+// once it is empty. It must be called with the gateway mutex held.
+func (gateway *GameGateway) tidyRoom(room *GameRoom, humanLeft bool) {
+	if humanLeft && !room.hasHumans() {
+		for _, member := range room.members() {
+			room.leave(member)
+			member.bot.Stop()
+		}
+	}
+
+	if room.isEmpty() {
+		delete(gateway.rooms, room.id)
+		room.stop()
+		gateway.logger.Printf("Room %s closed", room.id)
+
+		gateway.background(func() {
+			gateway.releaseRoom(room.id)
+			gateway.recordCasualMatch(room)
+		})
+	}
+}
+
+// recordCasualMatch records a closed room that did not play a ranked
+// match, which are recorded when their match ends, if a human played in
+// it against someone.
+func (gateway *GameGateway) recordCasualMatch(room *GameRoom) {
+	if room.match != nil {
+		return
+	}
+
+	if match := room.record(nil); len(match.Participants) > 1 && hasHumanParticipant(match) {
+		gateway.recordMatch(match, gateway.replayOf(room))
+	}
+}
+
+// background runs fn in its own goroutine. Drain waits for it before the
+// databases are disconnected, and refuses any work handed over after.
+func (gateway *GameGateway) background(fn func()) {
+	gateway.backgroundMutex.Lock()
+	defer gateway.backgroundMutex.Unlock()
+
+	if gateway.backgroundStopped {
+		gateway.logger.Printf("Dropping background work handed over after the drain")
+		return
+	}
+
+	gateway.pending.Add(1)
+	go func() {
+		defer gateway.pending.Done()
+		fn()
+	}()
+}
@@ -0,0 +1,167 @@
+package game
+
+import (
+	"encoding/json"
+	"os"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const (
+	// roomLeaseTTL is how long a room stays assigned to an instance that
+	// stopped renewing its leases, for example because it crashed.
+	roomLeaseTTL       = 15 * time.Second
+	leaseRenewInterval = roomLeaseTTL / 3
+)
+
+// newClusterInstance identifies this instance, reading the WebSocket URL
+// clients are redirected to from GAME_PUBLIC_ADDRESS.
+func newClusterInstance() ClusterInstance {
+	return ClusterInstance{
+		ID:      primitive.NewObjectID().Hex(),
+		Address: os.Getenv("GAME_PUBLIC_ADDRESS"),
+	}
+}
+
+// runCluster keeps the instance registered and the leases of its rooms
+// alive, and hands the messages other instances publish to the players
+// connected here.
+func (gateway *GameGateway) runCluster() {
+	ticker := gateway.clock.NewTicker(leaseRenewInterval)
+	defer ticker.Stop()
+
+	var messages <-chan *ClusterMessage
+	var stop func()
+	defer func() {
+		if stop != nil {
+			stop()
+		}
+	}()
+
+	gateway.renewLeases()
+	for {
+		if messages == nil {
+			var err error
+			if messages, stop, err = gateway.cluster.Subscribe(); err != nil {
+				gateway.logger.Printf("Error subscribing to the cluster: %v", err)
+			}
+		}
+
+		select {
+		case <-gateway.done:
+			return
+		case <-ticker.C():
+			gateway.renewLeases()
+		case message, ok := <-messages:
+			if !ok {
+				messages, stop = nil, nil
+				continue
+			}
+			gateway.receive(message)
+		}
+	}
+}
+
+// renewLeases extends the registration of the instance and the leases of
+// its rooms. A lease that expired, most likely while Redis was unreachable,
+// is claimed again unless another instance took the room over.
+func (gateway *GameGateway) renewLeases() {
+	if err := gateway.cluster.RegisterInstance(gateway.instance, roomLeaseTTL); err != nil {
+		gateway.logger.Printf("Error registering instance %s: %v", gateway.instance.ID, err)
+	}
+
+	gateway.mutex.RLock()
+	roomIDs := make([]string, 0, len(gateway.rooms))
+	for roomID := range gateway.rooms {
+		roomIDs = append(roomIDs, roomID)
+	}
+	gateway.mutex.RUnlock()
+
+	for _, roomID := range roomIDs {
+		renewed, err := gateway.cluster.RenewRoom(roomID, gateway.instance.ID, roomLeaseTTL)
+		if err != nil {
+			gateway.logger.Printf("Error renewing lease of room %s: %v", roomID, err)
+			continue
+		}
+		if !renewed && gateway.FindRoom(roomID) != nil {
+			gateway.leaseRoom(roomID)
+		}
+	}
+}
+
+// leaseRoom records this instance as the host of the room.
+func (gateway *GameGateway) leaseRoom(roomID string) {
+	claimed, err := gateway.cluster.ClaimRoom(roomID, gateway.instance.ID, roomLeaseTTL)
+	if err != nil {
+		gateway.logger.Printf("Error leasing room %s: %v", roomID, err)
+		return
+	}
+	if !claimed {
+		gateway.logger.Printf("Room %s is leased to another instance", roomID)
+	}
+}
+
+func (gateway *GameGateway) releaseRoom(roomID string) {
+	if err := gateway.cluster.ReleaseRoom(roomID, gateway.instance.ID); err != nil {
+		gateway.logger.Printf("Error releasing lease of room %s: %v", roomID, err)
+	}
+}
+
+// locateRoom explains why a room is not hosted here: it returns a
+// RoomRedirect when another live instance holds its lease and
+// ErrRoomNotFound otherwise.
+func (gateway *GameGateway) locateRoom(roomID string) error {
+	owner, err := gateway.cluster.RoomOwner(roomID)
+	if err != nil {
+		return err
+	}
+	if owner == "" || owner == gateway.instance.ID {
+		return ErrRoomNotFound
+	}
+
+	instance, err := gateway.cluster.Instance(owner)
+	if err != nil {
+		return err
+	}
+	if instance == nil || instance.Address == "" {
+		gateway.logger.Printf("Room %s is hosted by instance %s, which has no address", roomID, owner)
+		return ErrRoomNotFound
+	}
+
+	return &RoomRedirect{RoomID: roomID, Instance: *instance}
+}
+
+// publish sends an event to the instances the player may be connected to.
+func (gateway *GameGateway) publish(playerID string, event GameEvent, payload interface{}) error {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+
+	return gateway.cluster.Publish(&ClusterMessage{
+		From:     gateway.instance.ID,
+		PlayerID: playerID,
+		Event:    event,
+		Payload:  data,
+	})
+}
+
+// receive handles a message published by another instance. Matches go to
+// the matchmaker, which moves the player into the room.
+func (gateway *GameGateway) receive(message *ClusterMessage) {
+	if message.From == gateway.instance.ID {
+		return
+	}
+	if message.Event != MatchFound {
+		gateway.logger.Printf("Ignoring cluster message %s for %s", message.Event, message.PlayerID)
+		return
+	}
+
+	var match MatchFoundPayload
+	if err := json.Unmarshal(message.Payload, &match); err != nil {
+		gateway.logger.Printf("Error decoding match for %s: %v", message.PlayerID, err)
+		return
+	}
+	gateway.matchmaker.receiveMatch(message.PlayerID, &match)
+}
@@ -0,0 +1,248 @@
+package game
+
+import (
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// newClusterGateway creates a gateway of the cluster reachable at address.
+func newClusterGateway(cluster ClusterBackend, clock *manualClock, address string) *GameGateway {
+	gateway := newGameGateway(cluster, NewInMemoryMatchQueue(clock), clock)
+	gateway.instance.Address = address
+
+	return gateway
+}
+
+// waitFor polls condition until it holds.
+func waitFor(t *testing.T, what string, condition func() bool) {
+	t.Helper()
+
+	deadline := time.Now().Add(2 * time.Second)
+	for !condition() {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %s", what)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+// clusterBackend is a cluster backend under test along with the way to
+// let time pass for it.
+type clusterBackend struct {
+	name    string
+	cluster ClusterBackend
+	clock   *manualClock
+	advance func(duration time.Duration)
+}
+
+// clusterBackends returns the in-memory cluster and a Redis cluster backed
+// by a local stand-in, both on a fresh manual clock.
+func clusterBackends(t *testing.T) []clusterBackend {
+	inMemoryClock := newManualClock()
+
+	redisClock := newManualClock()
+	cache, server := newTestRedis(t)
+
+	return []clusterBackend{
+		{
+			name:    "in-memory",
+			cluster: NewInMemoryCluster(inMemoryClock),
+			clock:   inMemoryClock,
+			advance: inMemoryClock.Advance,
+		},
+		{
+			name:    "redis",
+			cluster: NewRedisCluster(cache),
+			clock:   redisClock,
+			advance: func(duration time.Duration) {
+				redisClock.Advance(duration)
+				server.FastForward(duration)
+			},
+		},
+	}
+}
+
+func TestClusterLeases(t *testing.T) {
+	for _, backend := range clusterBackends(t) {
+		t.Run(backend.name, func(t *testing.T) {
+			cluster := backend.cluster
+
+			if claimed, err := cluster.ClaimRoom("room", "a", roomLeaseTTL); err != nil || !claimed {
+				t.Fatalf("claiming a free room returned %v, %v", claimed, err)
+			}
+			if claimed, _ := cluster.ClaimRoom("room", "b", roomLeaseTTL); claimed {
+				t.Fatal("claimed a room leased to another instance")
+			}
+			if owner, _ := cluster.RoomOwner("room"); owner != "a" {
+				t.Fatalf("room is leased to %q, want a", owner)
+			}
+
+			// Renewing keeps the lease past its first expiry, but only for
+			// its holder.
+			if renewed, _ := cluster.RenewRoom("room", "b", roomLeaseTTL); renewed {
+				t.Fatal("renewed the lease of another instance")
+			}
+			backend.advance(roomLeaseTTL - time.Second)
+			if renewed, err := cluster.RenewRoom("room", "a", roomLeaseTTL); err != nil || !renewed {
+				t.Fatalf("renewing the held lease returned %v, %v", renewed, err)
+			}
+			backend.advance(2 * time.Second)
+			if owner, _ := cluster.RoomOwner("room"); owner != "a" {
+				t.Fatalf("renewed lease is held by %q, want a", owner)
+			}
+
+			// Releasing only lets go of a held lease.
+			cluster.ReleaseRoom("room", "b")
+			if owner, _ := cluster.RoomOwner("room"); owner != "a" {
+				t.Fatal("another instance released the lease")
+			}
+			cluster.ReleaseRoom("room", "a")
+			if owner, _ := cluster.RoomOwner("room"); owner != "" {
+				t.Fatalf("released room is leased to %q", owner)
+			}
+
+			// An expired lease is free for anybody.
+			cluster.ClaimRoom("room", "a", roomLeaseTTL)
+			backend.advance(roomLeaseTTL)
+			if renewed, _ := cluster.RenewRoom("room", "a", roomLeaseTTL); renewed {
+				t.Fatal("renewed an expired lease")
+			}
+			if claimed, _ := cluster.ClaimRoom("room", "b", roomLeaseTTL); !claimed {
+				t.Fatal("could not claim an expired lease")
+			}
+
+			// Registrations expire the same way.
+			instance := ClusterInstance{ID: "a", Address: "ws://a"}
+			if err := cluster.RegisterInstance(instance, roomLeaseTTL); err != nil {
+				t.Fatalf("register: %v", err)
+			}
+			if registered, _ := cluster.Instance("a"); registered == nil || *registered != instance {
+				t.Fatalf("got instance %+v, want %+v", registered, instance)
+			}
+			backend.advance(roomLeaseTTL)
+			if registered, _ := cluster.Instance("a"); registered != nil {
+				t.Fatalf("expired registration still returns %+v", registered)
+			}
+		})
+	}
+}
+
+func TestClusterMessages(t *testing.T) {
+	for _, backend := range clusterBackends(t) {
+		t.Run(backend.name, func(t *testing.T) {
+			messages, stop, err := backend.cluster.Subscribe()
+			if err != nil {
+				t.Fatalf("subscribe: %v", err)
+			}
+
+			sent := &ClusterMessage{From: "a", PlayerID: "player", Event: MatchFound, Payload: []byte(`{"roomId":"room"}`)}
+			// A Redis subscription may not be active yet when Subscribe
+			// returns, so the message is published until it arrives.
+			var received *ClusterMessage
+			for received == nil {
+				if err := backend.cluster.Publish(sent); err != nil {
+					t.Fatalf("publish: %v", err)
+				}
+				select {
+				case received = <-messages:
+				case <-time.After(10 * time.Millisecond):
+				}
+			}
+			if received.From != sent.From || received.PlayerID != sent.PlayerID || received.Event != sent.Event || string(received.Payload) != string(sent.Payload) {
+				t.Fatalf("received %+v, want %+v", received, sent)
+			}
+
+			stop()
+			for range messages {
+			}
+		})
+	}
+}
+
+func TestGatewaysShareRooms(t *testing.T) {
+	for _, backend := range clusterBackends(t) {
+		t.Run(backend.name, func(t *testing.T) {
+			a := newClusterGateway(backend.cluster, backend.clock, "ws://a")
+			b := newClusterGateway(backend.cluster, backend.clock, "ws://b")
+			a.renewLeases()
+			b.renewLeases()
+
+			room := a.CreateRoom(squareTerrain())
+			defer room.stop()
+
+			// The room is joined where it is hosted...
+			host, _ := newTestClient(a, primitive.NewObjectID().Hex())
+			if err := a.joinRoom(host, room.ID(), ""); err != nil {
+				t.Fatalf("joining on the host: %v", err)
+			}
+
+			// ...and redirects there from any other instance.
+			guest, inbox := newTestClient(b, primitive.NewObjectID().Hex())
+			err := b.joinRoom(guest, room.ID(), "")
+			redirect, ok := err.(*RoomRedirect)
+			if !ok || redirect.Instance != a.instance {
+				t.Fatalf("got %v, want a redirect to instance a", err)
+			}
+			guest.sendError(err)
+			var payload RedirectPayload
+			inbox.expect(t, Redirect, &payload)
+			if payload.RoomID != room.ID() || payload.InstanceID != a.instance.ID || payload.Address != "ws://a" {
+				t.Fatalf("got redirect %+v", payload)
+			}
+
+			// A room nobody hosts is not found anywhere.
+			if err := b.joinRoom(guest, primitive.NewObjectID().Hex(), ""); err != ErrRoomNotFound {
+				t.Fatalf("got %v for an unknown room, want ErrRoomNotFound", err)
+			}
+		})
+	}
+}
+
+func TestRoomLeaseFailover(t *testing.T) {
+	for _, backend := range clusterBackends(t) {
+		t.Run(backend.name, func(t *testing.T) {
+			cluster := backend.cluster
+			a := newClusterGateway(cluster, backend.clock, "ws://a")
+			b := newClusterGateway(cluster, backend.clock, "ws://b")
+			a.renewLeases()
+			b.renewLeases()
+
+			room := a.CreateRoom(squareTerrain())
+			defer room.stop()
+			guest, _ := newTestClient(b, primitive.NewObjectID().Hex())
+			if _, ok := b.joinRoom(guest, room.ID(), "").(*RoomRedirect); !ok {
+				t.Fatal("no redirect to the host")
+			}
+
+			// Once the host stops renewing, as when it crashed, nobody is
+			// sent to it any longer.
+			backend.advance(roomLeaseTTL)
+			b.renewLeases()
+			if err := b.joinRoom(guest, room.ID(), ""); err != ErrRoomNotFound {
+				t.Fatalf("got %v for a room whose host is gone, want ErrRoomNotFound", err)
+			}
+
+			// A host that comes back claims its room again while it is free.
+			a.renewLeases()
+			if owner, _ := cluster.RoomOwner(room.ID()); owner != a.instance.ID {
+				t.Fatalf("room is leased to %q, want instance a", owner)
+			}
+			if _, ok := b.joinRoom(guest, room.ID(), "").(*RoomRedirect); !ok {
+				t.Fatal("no redirect to the host that came back")
+			}
+
+			// It does not take the room back from an instance that took it
+			// over.
+			backend.advance(roomLeaseTTL)
+			if claimed, _ := cluster.ClaimRoom(room.ID(), b.instance.ID, roomLeaseTTL); !claimed {
+				t.Fatal("could not take over the expired lease")
+			}
+			a.renewLeases()
+			if owner, _ := cluster.RoomOwner(room.ID()); owner != b.instance.ID {
+				t.Fatalf("room is leased to %q, want instance b", owner)
+			}
+		})
+	}
+}
@@ -0,0 +1,93 @@
+package game
+
+import (
+	"ais-summoner/internal/models"
+	"testing"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// runningTickers is how many tickers a running gateway keeps: the lease
+// renewal of the cluster and the two of the matchmaker.
+const runningTickers = 3
+
+func TestServeRequiresAuthentication(t *testing.T) {
+	clock := newManualClock()
+	gateway := newGameGateway(NewInMemoryCluster(clock), NewInMemoryMatchQueue(clock), clock)
+	go gateway.Run()
+
+	// Anything but an Authentication message first is refused...
+	peer := servePeer(gateway, nil)
+	peer.send(t, JoinGame, &JoinGamePayload{RoomID: primitive.NewObjectID().Hex()})
+	peer.expect(t, Unauthorized, nil)
+	peer.expectClose(t, ClosePolicyViolation)
+
+	// ...and so are credentials that do not check out.
+	peer = servePeer(gateway, nil)
+	peer.send(t, Authentication, &AuthenticationPayload{Token: "Bearer forged"})
+	var payload ErrorPayload
+	peer.expect(t, Unauthorized, &payload)
+	if payload.Code != ErrInvalidCredentials.Code {
+		t.Fatalf("got error code %v, want %v", payload.Code, ErrInvalidCredentials.Code)
+	}
+	peer.expectClose(t, ClosePolicyViolation)
+
+	waitFor(t, "the refused clients to unregister", func() bool {
+		return gateway.clientCount() == 0
+	})
+}
+
+func TestServePlaysOverTransport(t *testing.T) {
+	clock := newManualClock()
+	gateway := newGameGateway(NewInMemoryCluster(clock), NewInMemoryMatchQueue(clock), clock)
+	go gateway.Run()
+	clock.waitForTickers(t, runningTickers)
+
+	user := &models.User{ID: primitive.NewObjectID(), Username: "player"}
+	peer := servePeer(gateway, user)
+	var authenticated models.User
+	peer.expect(t, Authentication, &authenticated)
+	if authenticated.ID != user.ID {
+		t.Fatalf("authenticated as %s, want %s", authenticated.ID.Hex(), user.ID.Hex())
+	}
+
+	room := gateway.CreateRoom(squareTerrain())
+	clock.waitForTickers(t, runningTickers+1)
+
+	peer.send(t, JoinGame, &JoinGamePayload{RoomID: room.ID()})
+	var snapshot RoomSnapshot
+	peer.expect(t, JoinGame, &snapshot)
+	if snapshot.RoomID != room.ID() || len(snapshot.Players) != 1 || snapshot.Players[0].ID != user.ID.Hex() {
+		t.Fatalf("joined %+v, want room %s with the player alone", snapshot, room.ID())
+	}
+	spawn := snapshot.Players[0].Position
+
+	// The input reaches the room through the read goroutine, so ticks are
+	// stepped until a state shows it processed.
+	peer.send(t, PlayerMove, &PlayerMovePayload{Direction: models.Vector2{X: 1}, Seq: 1})
+	var state GameState
+	for state.LastProcessedSeq != 1 {
+		if state.Tick > 100 {
+			t.Fatal("the input was never processed")
+		}
+		clock.Advance(gateway.config.TickInterval())
+		peer.expect(t, GameStateUpdate, &state)
+	}
+	if len(state.Players) != 1 || state.Players[0].Position.X <= spawn.X {
+		t.Fatalf("got players %+v, want the player moved right of %v", state.Players, spawn)
+	}
+
+	// A peer going away leaves its slot suspended until the grace period
+	// runs out, which closes the room.
+	peer.transport.Close(CloseGoingAway, "")
+	waitFor(t, "the client to unregister", func() bool {
+		return gateway.clientCount() == 0
+	})
+	if gateway.FindRoom(room.ID()) == nil {
+		t.Fatal("room closed before the grace period ran out")
+	}
+	clock.Advance(gateway.config.ResumeGrace)
+	if gateway.FindRoom(room.ID()) != nil {
+		t.Fatal("room outlived its last player")
+	}
+}
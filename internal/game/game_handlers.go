@@ -0,0 +1,149 @@
+package game
+
+import (
+	"bytes"
+	"encoding"
+	"encoding/json"
+)
+
+// eventHandler decodes the payload of one GameEvent and handles it.
+type eventHandler struct {
+	decode func(envelope GameEnvelope) (Payload, error)
+	handle func(client *GameClient, payload Payload) error
+}
+
+// handlerFor builds an eventHandler that decodes the payload into T before
+// passing it to handle.
+func handlerFor[T any, P interface {
+	*T
+	Payload
+}](handle func(client *GameClient, payload P) error) eventHandler {
+	return eventHandler{
+		decode: func(envelope GameEnvelope) (Payload, error) {
+			payload := P(new(T))
+			if err := decodePayload(envelope, payload); err != nil {
+				return nil, err
+			}
+			return payload, nil
+		},
+		handle: func(client *GameClient, payload Payload) error {
+			return handle(client, payload.(P))
+		},
+	}
+}
+
+// gameEventHandlers maps the events a client may send once authenticated
+// to their handlers. Authentication is handled before the registry is used.
+var gameEventHandlers = map[GameEvent]eventHandler{
+	JoinGame:      handlerFor(handleJoinGame),
+	LeaveGame:     handlerFor(handleLeaveGame),
+	PlayerMove:    handlerFor(handlePlayerMove),
+	PlayerDash:    handlerFor(handlePlayerDash),
+	FindMatch:     handlerFor(handleFindMatch),
+	CancelMatch:   handlerFor(handleCancelMatch),
+	SnapshotAck:   handlerFor(handleSnapshotAck),
+	ResumeSession: handlerFor(handleResumeSession),
+}
+
+// handleJoinGame moves the client into a room. A client that joins a room
+// gives up its search for a match.
+func handleJoinGame(client *GameClient, payload *JoinGamePayload) error {
+	if err := client.gateway.joinRoom(client, payload.RoomID, payload.TerrainID); err != nil {
+		return err
+	}
+
+	client.gateway.matchmaker.Cancel(client)
+	return nil
+}
+
+func handleLeaveGame(client *GameClient, payload *LeaveGamePayload) error {
+	if client.room.Load() == nil {
+		return ErrNotInRoom
+	}
+
+	client.gateway.leaveRoom(client)
+	return nil
+}
+
+func handlePlayerMove(client *GameClient, payload *PlayerMovePayload) error {
+	// The room may close under the client at any time, so it is read once.
+	room := client.room.Load()
+	if room == nil {
+		return ErrNotInRoom
+	}
+
+	if client.acceptInput(payload.Seq) {
+		room.enqueue(client, PlayerMove, payload.Direction, payload.Seq)
+	}
+	return nil
+}
+
+func handlePlayerDash(client *GameClient, payload *PlayerDashPayload) error {
+	// The room may close under the client at any time, so it is read once.
+	room := client.room.Load()
+	if room == nil {
+		return ErrNotInRoom
+	}
+
+	if client.acceptInput(payload.Seq) {
+		room.enqueue(client, PlayerDash, payload.Direction, payload.Seq)
+	}
+	return nil
+}
+
+func handleResumeSession(client *GameClient, payload *ResumeSessionPayload) error {
+	if err := client.gateway.resumeSession(client, payload.Token); err != nil {
+		return err
+	}
+
+	client.gateway.matchmaker.Cancel(client)
+	return nil
+}
+
+func handleSnapshotAck(client *GameClient, payload *SnapshotAckPayload) error {
+	room := client.room.Load()
+	if room == nil {
+		return ErrNotInRoom
+	}
+
+	room.acknowledge(client, payload.Tick)
+	return nil
+}
+
+func handleFindMatch(client *GameClient, payload *FindMatchPayload) error {
+	if client.room.Load() != nil {
+		return ErrAlreadyInRoom
+	}
+
+	return client.gateway.matchmaker.Find(client, payload)
+}
+
+func handleCancelMatch(client *GameClient, payload *CancelMatchPayload) error {
+	return client.gateway.matchmaker.Cancel(client)
+}
+
+// decodePayload is the second decoding phase: it strictly decodes the raw
+// payload of an envelope and validates the result.
+func decodePayload(envelope GameEnvelope, payload Payload) error {
+	if envelope.binary != nil {
+		unmarshaler, ok := payload.(encoding.BinaryUnmarshaler)
+		if !ok {
+			return NewGameError(CodeInvalidPayload, "payload has no binary layout")
+		}
+		if err := unmarshaler.UnmarshalBinary(envelope.binary); err != nil {
+			return NewGameError(CodeInvalidPayload, err.Error())
+		}
+	} else if raw := envelope.Payload; len(raw) != 0 && !bytes.Equal(raw, []byte("null")) {
+		decoder := json.NewDecoder(bytes.NewReader(raw))
+		decoder.DisallowUnknownFields()
+		if err := decoder.Decode(payload); err != nil {
+			return NewGameError(CodeInvalidPayload, err.Error())
+		}
+	}
+
+	if err := payload.Validate(); err != nil {
+		return NewGameError(CodeInvalidPayload, err.Error())
+	}
+
+	return nil
+}
@@ -0,0 +1,50 @@
+package game
+
+import (
+	"ais-summoner/internal/models"
+	"sync"
+	"testing"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// TestInputsRaceRoomClosing handles inputs while the room closes under the
+// client, which the race detector checks and which must not find the room
+// gone between the check and the use.
+func TestInputsRaceRoomClosing(t *testing.T) {
+	clock := newManualClock()
+	gateway := newGameGateway(NewInMemoryCluster(clock), NewInMemoryMatchQueue(clock), clock)
+
+	for round := 0; round < 20; round++ {
+		room := gateway.CreateRoom(squareTerrain())
+		client, _ := newTestClient(gateway, primitive.NewObjectID().Hex())
+		if err := gateway.joinRoom(client, room.ID(), ""); err != nil {
+			t.Fatalf("join: %v", err)
+		}
+
+		var handling sync.WaitGroup
+		handling.Add(1)
+		go func() {
+			defer handling.Done()
+			for seq := uint32(1); seq <= 200; seq++ {
+				direction := models.Vector2{X: 1}
+				if err := handlePlayerMove(client, &PlayerMovePayload{Direction: direction, Seq: seq}); err != nil && err != ErrNotInRoom {
+					t.Errorf("move: %v", err)
+				}
+				if err := handlePlayerDash(client, &PlayerDashPayload{Direction: direction, Seq: seq}); err != nil && err != ErrNotInRoom {
+					t.Errorf("dash: %v", err)
+				}
+				if err := handleSnapshotAck(client, &SnapshotAckPayload{Tick: 1}); err != nil && err != ErrNotInRoom {
+					t.Errorf("ack: %v", err)
+				}
+			}
+		}()
+
+		gateway.closeRoom(room)
+		handling.Wait()
+
+		if client.room.Load() != nil {
+			t.Fatal("client still in a closed room")
+		}
+	}
+}
@@ -0,0 +1,201 @@
+package game
+
+import (
+	"ais-summoner/internal/database"
+	"ais-summoner/internal/models"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// squareTerrain is a 20 by 20 terrain centered on the origin.
+func squareTerrain() *models.Terrain {
+	return &models.Terrain{
+		ID:   primitive.NewObjectID(),
+		Name: "square",
+		Boundary: []models.Vector2{
+			{X: -10, Y: -10}, {X: 10, Y: -10}, {X: 10, Y: 10}, {X: -10, Y: 10},
+		},
+	}
+}
+
+// testInbox reads the JSON messages queued for a client that has no
+// transport.
+type testInbox struct {
+	client  *GameClient
+	pending []GameEnvelope
+}
+
+// newTestClient creates a client without a transport, whose messages are
+// read through the returned inbox.
+func newTestClient(gateway *GameGateway, id string) (*GameClient, *testInbox) {
+	client := newGameClient(gateway, JSONCodec{}, nil)
+	client.setID(id)
+
+	return client, &testInbox{client: client}
+}
+
+// expect waits for the next message of the event, skipping any other, and
+// decodes its payload into payload unless it is nil.
+func (inbox *testInbox) expect(t *testing.T, event GameEvent, payload interface{}) {
+	t.Helper()
+
+	timeout := time.After(2 * time.Second)
+	for {
+		for len(inbox.pending) > 0 {
+			envelope := inbox.pending[0]
+			inbox.pending = inbox.pending[1:]
+			if envelope.Event != event {
+				continue
+			}
+
+			if payload != nil {
+				if err := json.Unmarshal(envelope.Payload, payload); err != nil {
+					t.Fatalf("decoding %s: %v", event, err)
+				}
+			}
+			return
+		}
+
+		select {
+		case <-inbox.client.queue.ready:
+			inbox.receive(t)
+		case <-timeout:
+			t.Fatalf("no %s message", event)
+		}
+	}
+}
+
+// expectNone checks that no message arrives for a moment.
+func (inbox *testInbox) expectNone(t *testing.T) {
+	t.Helper()
+
+	select {
+	case <-inbox.client.queue.ready:
+		inbox.receive(t)
+	case <-time.After(50 * time.Millisecond):
+	}
+	if len(inbox.pending) > 0 {
+		t.Fatalf("unexpected %s message", inbox.pending[0].Event)
+	}
+}
+
+func (inbox *testInbox) receive(t *testing.T) {
+	t.Helper()
+
+	messages, _ := inbox.client.queue.take()
+	for _, message := range messages {
+		envelope, err := inbox.client.codec.Decode(message)
+		if err != nil {
+			t.Fatalf("decoding message: %v", err)
+		}
+		inbox.pending = append(inbox.pending, envelope)
+	}
+}
+
+// testPeer is the far end of the pipe a client is served over, which
+// talks to the gateway like a game client would.
+type testPeer struct {
+	transport *PipeTransport
+	codec     Codec
+}
+
+// servePeer serves a client over a pipe and returns its far end. A nil
+// user must authenticate first.
+func servePeer(gateway *GameGateway, user *models.User) *testPeer {
+	server, peer := NewPipe()
+	gateway.Serve(server, JSONCodec{}, user)
+
+	return &testPeer{transport: peer, codec: JSONCodec{}}
+}
+
+func (peer *testPeer) send(t *testing.T, event GameEvent, payload interface{}) {
+	t.Helper()
+
+	data, err := peer.codec.Encode(event, payload)
+	if err != nil {
+		t.Fatalf("encoding %s: %v", event, err)
+	}
+	if err := peer.transport.Send([][]byte{data}); err != nil {
+		t.Fatalf("sending %s: %v", event, err)
+	}
+}
+
+// receive waits for the next message, failing with the error that ended
+// the transport instead.
+func (peer *testPeer) receive(t *testing.T) (GameEnvelope, error) {
+	t.Helper()
+
+	peer.transport.SetReadDeadline(time.Now().Add(2 * time.Second))
+	data, err := peer.transport.Receive()
+	if err != nil {
+		return GameEnvelope{}, err
+	}
+
+	envelope, err := peer.codec.Decode(data)
+	if err != nil {
+		t.Fatalf("decoding message: %v", err)
+	}
+	return envelope, nil
+}
+
+// expect waits for the next message of the event, skipping any other, and
+// decodes its payload into payload unless it is nil.
+func (peer *testPeer) expect(t *testing.T, event GameEvent, payload interface{}) {
+	t.Helper()
+
+	for {
+		envelope, err := peer.receive(t)
+		if err != nil {
+			t.Fatalf("no %s message: %v", event, err)
+		}
+		if envelope.Event != event {
+			continue
+		}
+
+		if payload != nil {
+			if err := json.Unmarshal(envelope.Payload, payload); err != nil {
+				t.Fatalf("decoding %s: %v", event, err)
+			}
+		}
+		return
+	}
+}
+
+// expectClose skips the remaining messages and checks the close code the
+// transport ended with.
+func (peer *testPeer) expectClose(t *testing.T, code int) {
+	t.Helper()
+
+	for {
+		_, err := peer.receive(t)
+		if err == nil {
+			continue
+		}
+
+		var closeErr *CloseError
+		if !errors.As(err, &closeErr) || closeErr.Code != code {
+			t.Fatalf("transport ended with %v, want close code %d", err, code)
+		}
+		return
+	}
+}
+
+// newTestRedis connects a Redis client to a local stand-in server, which
+// lets time pass through FastForward.
+func newTestRedis(t *testing.T) (*database.Redis, *miniredis.Miniredis) {
+	t.Helper()
+
+	server := miniredis.RunT(t)
+	t.Setenv("REDIS_CONNECTION_STRING", server.Addr())
+	cache := database.NewRedis()
+	t.Cleanup(func() {
+		cache.Close()
+	})
+
+	return cache, server
+}
@@ -0,0 +1,92 @@
+package game
+
+import (
+	"ais-summoner/internal/models"
+	"math"
+)
+
+// positionHistory is a ring buffer of the player positions at the end of
+// the most recent ticks, used to judge hits against what a lagging client
+// saw rather than the current state.
+type positionHistory struct {
+	entries []positionEntry
+}
+
+type positionEntry struct {
+	tick      uint64
+	ids       []string
+	positions []models.Vector3
+}
+
+// newPositionHistory keeps the positions of the given number of ticks.
+func newPositionHistory(size uint64) *positionHistory {
+	if size == 0 {
+		size = 1
+	}
+
+	return &positionHistory{entries: make([]positionEntry, size)}
+}
+
+// record stores the positions of the players at the end of the tick,
+// reusing the slices of the entry it replaces.
+func (history *positionHistory) record(tick uint64, players []*Player) {
+	entry := &history.entries[tick%uint64(len(history.entries))]
+	entry.tick = tick
+	entry.ids = entry.ids[:0]
+	entry.positions = entry.positions[:0]
+	for _, player := range players {
+		entry.ids = append(entry.ids, player.ID)
+		entry.positions = append(entry.positions, player.Position)
+	}
+}
+
+// position returns where the player stood at the end of the tick, or false
+// when the tick is no longer kept or the player was not there.
+func (history *positionHistory) position(tick uint64, playerID string) (models.Vector3, bool) {
+	entry := &history.entries[tick%uint64(len(history.entries))]
+	if entry.tick != tick {
+		return models.Vector3{}, false
+	}
+
+	for i, id := range entry.ids {
+		if id == playerID {
+			return entry.positions[i], true
+		}
+	}
+
+	return models.Vector3{}, false
+}
+
+// rewound returns the position of the victim as seen rewind ticks ago. It
+// falls back to the current position when there is nothing to rewind to,
+// including ticks from before the victim last spawned.
+func (sim *Simulation) rewound(victim *Player, rewind uint64) models.Vector3 {
+	if rewind == 0 || rewind > sim.tick {
+		return victim.Position
+	}
+
+	tick := sim.tick - rewind
+	if tick < victim.spawnedAt {
+		return victim.Position
+	}
+	if position, ok := sim.history.position(tick, victim.ID); ok {
+		return position
+	}
+
+	return victim.Position
+}
+
+// rewindTicks estimates how many ticks old the state was when the client
+// acted on it. The state took half a round trip to reach the client and
+// the input another half to come back, so that is one round trip, capped
+// at MaxRewind. Clients whose round trips vary by more than MaxRewind get
+// no rewind at all, as their estimate says little about the state they saw.
+func (room *GameRoom) rewindTicks(client *GameClient) uint64 {
+	rtt := client.RTT()
+	if rtt <= 0 || client.Jitter() > room.config.MaxRewind {
+		return 0
+	}
+
+	ticks := uint64(math.Round(float64(rtt) / float64(room.config.TickInterval())))
+	return min(ticks, room.config.Ticks(room.config.MaxRewind))
+}
@@ -0,0 +1,118 @@
+package game
+
+// MatchOutcome describes how a ranked match ended. Placements maps every
+// participant to their place, where 1 is best and ties share a place.
+type MatchOutcome struct {
+	RoomID     string         `json:"roomId"`
+	WinnerID   string         `json:"winnerId,omitempty"`
+	Placements map[string]int `json:"placements"`
+}
+
+// roomMatch tracks the participants of a ranked match formed by the
+// matchmaker. A match ends when its time runs out or when, after at least
+// two players have joined, at most one of them is still in the room.
+type roomMatch struct {
+	participants []string
+	joined       map[string]bool
+	left         []string
+	endTick      uint64
+	ended        bool
+}
+
+func newRoomMatch(participants []string, endTick uint64) *roomMatch {
+	return &roomMatch{
+		participants: participants,
+		joined:       make(map[string]bool),
+		endTick:      endTick,
+	}
+}
+
+func (match *roomMatch) isParticipant(playerID string) bool {
+	for _, participant := range match.participants {
+		if participant == playerID {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (match *roomMatch) playerJoined(playerID string) {
+	match.joined[playerID] = true
+	match.removeLeft(playerID)
+}
+
+func (match *roomMatch) playerLeft(playerID string) {
+	match.removeLeft(playerID)
+	match.left = append(match.left, playerID)
+}
+
+func (match *roomMatch) removeLeft(playerID string) {
+	for i, left := range match.left {
+		if left == playerID {
+			match.left = append(match.left[:i], match.left[i+1:]...)
+			return
+		}
+	}
+}
+
+// remaining lists the participants that joined and have not left.
+func (match *roomMatch) remaining() []string {
+	var remaining []string
+	for _, participant := range match.participants {
+		if match.joined[participant] && !match.hasLeft(participant) {
+			remaining = append(remaining, participant)
+		}
+	}
+
+	return remaining
+}
+
+func (match *roomMatch) hasLeft(playerID string) bool {
+	for _, left := range match.left {
+		if left == playerID {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (match *roomMatch) shouldEnd(tick uint64) bool {
+	if match.ended {
+		return false
+	}
+	if tick >= match.endTick {
+		return true
+	}
+
+	return len(match.joined) >= 2 && len(match.remaining()) <= 1
+}
+
+// outcome ranks the players still in the room first, ordered by kills, then
+// the players that left with the last to leave ranked highest, then those
+// that never joined.
+func (match *roomMatch) outcome(roomID string, kills map[string]int) *MatchOutcome {
+	remaining := match.remaining()
+	placements, winnerID := placeByKills(remaining, kills)
+
+	place := len(remaining) + 1
+	for i := len(match.left) - 1; i >= 0; i-- {
+		placements[match.left[i]] = place
+		place++
+	}
+
+	for _, participant := range match.participants {
+		if _, placed := placements[participant]; !placed {
+			placements[participant] = place
+		}
+	}
+
+	if len(match.participants) < 2 {
+		winnerID = ""
+	} else if len(remaining) == 1 {
+		winnerID = remaining[0]
+	}
+
+	return &MatchOutcome{RoomID: roomID, WinnerID: winnerID, Placements: placements}
+}
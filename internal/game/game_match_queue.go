@@ -0,0 +1,198 @@
+package game
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+type MatchTicket struct {
+	PlayerID   string    `json:"playerId"`
+	Rating     float64   `json:"rating"`
+	Region     string    `json:"region"`
+	TerrainID  string    `json:"terrainId,omitempty"`
+	EnqueuedAt time.Time `json:"enqueuedAt"`
+}
+
+// MatchQueue stores the tickets of the players waiting for a match. Claim
+// must be atomic so that several matchmakers can share one queue.
+//
+// Tickets expire unless the instance that queued them keeps refreshing
+// them, so the players of an instance that crashed are not matched.
+type MatchQueue interface {
+	Enqueue(ticket *MatchTicket, ttl time.Duration) error
+	// Refresh extends the ticket by ttl, reporting false when it is no
+	// longer queued because it was claimed, removed or expired.
+	Refresh(ticket *MatchTicket, ttl time.Duration) (bool, error)
+	Remove(ticket *MatchTicket) error
+	Regions() ([]string, error)
+	// Tickets returns the tickets of a region, longest waiting first.
+	Tickets(region string) ([]*MatchTicket, error)
+	// Candidates returns the tickets of a region rated between min and max.
+	Candidates(region string, min float64, max float64) ([]*MatchTicket, error)
+	// Claim removes all of the given players from the region, or none of
+	// them if any is no longer queued, and reports which happened.
+	Claim(region string, playerIDs []string) (bool, error)
+	PublishMatch(playerID string, match *MatchFoundPayload) error
+	// TakeMatch returns and forgets the match published for a player, or
+	// nil when there is none.
+	TakeMatch(playerID string) (*MatchFoundPayload, error)
+}
+
+// InMemoryMatchQueue is a process-local MatchQueue. Tickets are ordered by
+// enqueue time and then player id and expire on the given clock, so
+// matching over it is deterministic.
+type InMemoryMatchQueue struct {
+	clock   Clock
+	tickets map[string]*queuedTicket
+	matches map[string]*MatchFoundPayload
+	mutex   sync.Mutex
+}
+
+type queuedTicket struct {
+	ticket  MatchTicket
+	expires time.Time
+}
+
+func NewInMemoryMatchQueue(clock Clock) *InMemoryMatchQueue {
+	return &InMemoryMatchQueue{
+		clock:   clock,
+		tickets: make(map[string]*queuedTicket),
+		matches: make(map[string]*MatchFoundPayload),
+	}
+}
+
+func (queue *InMemoryMatchQueue) Enqueue(ticket *MatchTicket, ttl time.Duration) error {
+	queue.mutex.Lock()
+	defer queue.mutex.Unlock()
+
+	queue.tickets[ticket.PlayerID] = &queuedTicket{ticket: *ticket, expires: queue.clock.Now().Add(ttl)}
+	return nil
+}
+
+func (queue *InMemoryMatchQueue) Refresh(ticket *MatchTicket, ttl time.Duration) (bool, error) {
+	queue.mutex.Lock()
+	defer queue.mutex.Unlock()
+
+	queued := queue.live(ticket.PlayerID)
+	if queued == nil || queued.ticket.Region != ticket.Region {
+		return false, nil
+	}
+
+	queued.expires = queue.clock.Now().Add(ttl)
+	return true, nil
+}
+
+func (queue *InMemoryMatchQueue) Remove(ticket *MatchTicket) error {
+	queue.mutex.Lock()
+	defer queue.mutex.Unlock()
+
+	delete(queue.tickets, ticket.PlayerID)
+	return nil
+}
+
+func (queue *InMemoryMatchQueue) Regions() ([]string, error) {
+	queue.mutex.Lock()
+	defer queue.mutex.Unlock()
+
+	seen := make(map[string]bool)
+	var regions []string
+	for playerID := range queue.tickets {
+		queued := queue.live(playerID)
+		if queued != nil && !seen[queued.ticket.Region] {
+			seen[queued.ticket.Region] = true
+			regions = append(regions, queued.ticket.Region)
+		}
+	}
+
+	sort.Strings(regions)
+	return regions, nil
+}
+
+func (queue *InMemoryMatchQueue) Tickets(region string) ([]*MatchTicket, error) {
+	return queue.filter(func(ticket *MatchTicket) bool {
+		return ticket.Region == region
+	}), nil
+}
+
+func (queue *InMemoryMatchQueue) Candidates(region string, min float64, max float64) ([]*MatchTicket, error) {
+	return queue.filter(func(ticket *MatchTicket) bool {
+		return ticket.Region == region && ticket.Rating >= min && ticket.Rating <= max
+	}), nil
+}
+
+func (queue *InMemoryMatchQueue) Claim(region string, playerIDs []string) (bool, error) {
+	queue.mutex.Lock()
+	defer queue.mutex.Unlock()
+
+	for _, playerID := range playerIDs {
+		queued := queue.live(playerID)
+		if queued == nil || queued.ticket.Region != region {
+			return false, nil
+		}
+	}
+
+	for _, playerID := range playerIDs {
+		delete(queue.tickets, playerID)
+	}
+	return true, nil
+}
+
+func (queue *InMemoryMatchQueue) PublishMatch(playerID string, match *MatchFoundPayload) error {
+	queue.mutex.Lock()
+	defer queue.mutex.Unlock()
+
+	queue.matches[playerID] = match
+	return nil
+}
+
+func (queue *InMemoryMatchQueue) TakeMatch(playerID string) (*MatchFoundPayload, error) {
+	queue.mutex.Lock()
+	defer queue.mutex.Unlock()
+
+	match := queue.matches[playerID]
+	delete(queue.matches, playerID)
+	return match, nil
+}
+
+func (queue *InMemoryMatchQueue) filter(keep func(ticket *MatchTicket) bool) []*MatchTicket {
+	queue.mutex.Lock()
+	defer queue.mutex.Unlock()
+
+	var tickets []*MatchTicket
+	for playerID := range queue.tickets {
+		queued := queue.live(playerID)
+		if queued != nil && keep(&queued.ticket) {
+			copied := queued.ticket
+			tickets = append(tickets, &copied)
+		}
+	}
+
+	sortTickets(tickets)
+	return tickets
+}
+
+// live returns the ticket of the player unless it expired, in which case it
+// is dropped. It must be called with the queue mutex held.
+func (queue *InMemoryMatchQueue) live(playerID string) *queuedTicket {
+	queued, exists := queue.tickets[playerID]
+	if !exists {
+		return nil
+	}
+	if !queue.clock.Now().Before(queued.expires) {
+		delete(queue.tickets, playerID)
+		return nil
+	}
+
+	return queued
+}
+
+// sortTickets orders tickets longest waiting first, breaking ties by id.
+func sortTickets(tickets []*MatchTicket) {
+	sort.Slice(tickets, func(i, j int) bool {
+		if !tickets[i].EnqueuedAt.Equal(tickets[j].EnqueuedAt) {
+			return tickets[i].EnqueuedAt.Before(tickets[j].EnqueuedAt)
+		}
+		return tickets[i].PlayerID < tickets[j].PlayerID
+	})
+}
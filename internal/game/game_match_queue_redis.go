@@ -0,0 +1,224 @@
+package game
+
+import (
+	"ais-summoner/internal/database"
+	"encoding/json"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+const matchPublishTTL = time.Minute
+
+// enqueueScript stores a ticket body with its TTL and adds the player to
+// both sets of the region in one step, so a ticket is never half queued.
+var enqueueScript = redis.NewScript(`
+redis.call("SET", KEYS[1], ARGV[2], "PX", ARGV[3])
+redis.call("ZADD", KEYS[2], ARGV[4], ARGV[1])
+redis.call("ZADD", KEYS[3], ARGV[5], ARGV[1])
+return 1
+`)
+
+// refreshTicketScript extends the TTL of a ticket that is still queued.
+var refreshTicketScript = redis.NewScript(`
+if redis.call("ZSCORE", KEYS[2], ARGV[1]) == false then
+	return 0
+end
+return redis.call("PEXPIRE", KEYS[1], ARGV[2])
+`)
+
+// claimScript removes every given player from the queue of a region, or
+// none of them when one has already been claimed by another matchmaker or
+// its ticket expired. KEYS[3] onwards are the tickets of the players.
+var claimScript = redis.NewScript(`
+for i, id in ipairs(ARGV) do
+	if redis.call("ZSCORE", KEYS[1], id) == false or redis.call("EXISTS", KEYS[i + 2]) == 0 then
+		return 0
+	end
+end
+for i, id in ipairs(ARGV) do
+	redis.call("ZREM", KEYS[1], id)
+	redis.call("ZREM", KEYS[2], id)
+	redis.call("DEL", KEYS[i + 2])
+end
+return 1
+`)
+
+// pruneScript removes the players whose ticket expired from the sets of a
+// region. A player queued again in the meantime has a ticket and is kept.
+var pruneScript = redis.NewScript(`
+for i, id in ipairs(ARGV) do
+	if redis.call("EXISTS", KEYS[i + 2]) == 0 then
+		redis.call("ZREM", KEYS[1], id)
+		redis.call("ZREM", KEYS[2], id)
+	end
+end
+return 1
+`)
+
+// RedisMatchQueue keeps the queue in Redis so that every server instance
+// matches from the same pool. Each region has a sorted set of players by
+// enqueue time and one by rating; ticket bodies are stored as JSON and
+// expire unless refreshed. Sets still naming an expired ticket are pruned
+// when they are read.
+type RedisMatchQueue struct {
+	redis *database.Redis
+}
+
+func NewRedisMatchQueue(cache *database.Redis) *RedisMatchQueue {
+	return &RedisMatchQueue{redis: cache}
+}
+
+func (queue *RedisMatchQueue) Enqueue(ticket *MatchTicket, ttl time.Duration) error {
+	body, err := json.Marshal(ticket)
+	if err != nil {
+		return err
+	}
+
+	// Regions are only ever added, so the set of regions can be updated on
+	// its own.
+	if err := queue.redis.AddToSet("matchmaking:regions", ticket.Region); err != nil {
+		return err
+	}
+
+	keys := []string{ticketKey(ticket.Region, ticket.PlayerID), waitingKey(ticket.Region), ratingKey(ticket.Region)}
+	_, err = queue.redis.RunScript(enqueueScript, keys, ticket.PlayerID, string(body), ttl.Milliseconds(), ticket.EnqueuedAt.UnixMilli(), ticket.Rating)
+	return err
+}
+
+func (queue *RedisMatchQueue) Refresh(ticket *MatchTicket, ttl time.Duration) (bool, error) {
+	keys := []string{ticketKey(ticket.Region, ticket.PlayerID), waitingKey(ticket.Region)}
+	result, err := queue.redis.RunScript(refreshTicketScript, keys, ticket.PlayerID, ttl.Milliseconds())
+	if err != nil {
+		return false, err
+	}
+
+	refreshed, _ := result.(int64)
+	return refreshed == 1, nil
+}
+
+func (queue *RedisMatchQueue) Remove(ticket *MatchTicket) error {
+	if err := queue.redis.RemoveFromSortedSet(waitingKey(ticket.Region), ticket.PlayerID); err != nil {
+		return err
+	}
+	if err := queue.redis.RemoveFromSortedSet(ratingKey(ticket.Region), ticket.PlayerID); err != nil {
+		return err
+	}
+
+	return queue.redis.DeleteCache(ticketKey(ticket.Region, ticket.PlayerID))
+}
+
+func (queue *RedisMatchQueue) Regions() ([]string, error) {
+	return queue.redis.GetSetMembers("matchmaking:regions")
+}
+
+func (queue *RedisMatchQueue) Tickets(region string) ([]*MatchTicket, error) {
+	playerIDs, err := queue.redis.GetSortedSetRange(waitingKey(region), 0, -1)
+	if err != nil {
+		return nil, err
+	}
+
+	return queue.load(region, playerIDs)
+}
+
+func (queue *RedisMatchQueue) Candidates(region string, min float64, max float64) ([]*MatchTicket, error) {
+	playerIDs, err := queue.redis.GetSortedSetRangeByScore(ratingKey(region), min, max)
+	if err != nil {
+		return nil, err
+	}
+
+	tickets, err := queue.load(region, playerIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	sortTickets(tickets)
+	return tickets, nil
+}
+
+func (queue *RedisMatchQueue) Claim(region string, playerIDs []string) (bool, error) {
+	keys, args := ticketScriptArgs(region, playerIDs)
+	result, err := queue.redis.RunScript(claimScript, keys, args...)
+	if err != nil {
+		return false, err
+	}
+
+	claimed, _ := result.(int64)
+	return claimed == 1, nil
+}
+
+func (queue *RedisMatchQueue) PublishMatch(playerID string, match *MatchFoundPayload) error {
+	return queue.redis.SetCache(matchKey(playerID), match, matchPublishTTL)
+}
+
+func (queue *RedisMatchQueue) TakeMatch(playerID string) (*MatchFoundPayload, error) {
+	var match *MatchFoundPayload
+	if err := queue.redis.GetCache(matchKey(playerID), &match); err != nil {
+		return nil, err
+	}
+	if match == nil {
+		return nil, nil
+	}
+
+	return match, queue.redis.DeleteCache(matchKey(playerID))
+}
+
+// load reads the tickets of the given players of a region, skipping any
+// that were claimed between the range query and the read or expired. The
+// players of expired tickets are pruned from the sets.
+func (queue *RedisMatchQueue) load(region string, playerIDs []string) ([]*MatchTicket, error) {
+	tickets := make([]*MatchTicket, 0, len(playerIDs))
+	var missing []string
+	for _, playerID := range playerIDs {
+		var ticket *MatchTicket
+		if err := queue.redis.GetCache(ticketKey(region, playerID), &ticket); err != nil {
+			return nil, err
+		}
+		if ticket == nil {
+			missing = append(missing, playerID)
+			continue
+		}
+		tickets = append(tickets, ticket)
+	}
+
+	if len(missing) > 0 {
+		keys, args := ticketScriptArgs(region, missing)
+		if _, err := queue.redis.RunScript(pruneScript, keys, args...); err != nil {
+			return nil, err
+		}
+	}
+
+	return tickets, nil
+}
+
+// ticketScriptArgs builds the keys and arguments of the scripts that work
+// on several players of a region: both sets followed by the tickets as
+// keys, and the player ids as arguments.
+func ticketScriptArgs(region string, playerIDs []string) ([]string, []interface{}) {
+	keys := []string{waitingKey(region), ratingKey(region)}
+	args := make([]interface{}, len(playerIDs))
+	for i, playerID := range playerIDs {
+		keys = append(keys, ticketKey(region, playerID))
+		args[i] = playerID
+	}
+
+	return keys, args
+}
+
+// The region is used as a hash tag so the sets and tickets of a region
+// share a slot, which the scripts require.
+func waitingKey(region string) string {
+	return "matchmaking:{" + region + "}:waiting"
+}
+
+func ratingKey(region string) string {
+	return "matchmaking:{" + region + "}:rating"
+}
+
+func ticketKey(region string, playerID string) string {
+	return "matchmaking:{" + region + "}:ticket:" + playerID
+}
+
+func matchKey(playerID string) string {
+	return "matchmaking:match:" + playerID
+}
@@ -0,0 +1,147 @@
+package game
+
+import (
+	"testing"
+	"time"
+)
+
+func TestRedisMatchQueueOrder(t *testing.T) {
+	cache, _ := newTestRedis(t)
+	queue := NewRedisMatchQueue(cache)
+	start := time.UnixMilli(1_700_000_000_000)
+
+	tickets := []*MatchTicket{
+		{PlayerID: "c", Rating: 1500, Region: "eu", EnqueuedAt: start.Add(time.Second)},
+		{PlayerID: "b", Rating: 1600, Region: "eu", EnqueuedAt: start},
+		{PlayerID: "a", Rating: 1400, Region: "eu", EnqueuedAt: start},
+		{PlayerID: "d", Rating: 1500, Region: "us", EnqueuedAt: start},
+	}
+	for _, ticket := range tickets {
+		if err := queue.Enqueue(ticket, ticketTTL); err != nil {
+			t.Fatalf("enqueue: %v", err)
+		}
+	}
+
+	regions, _ := queue.Regions()
+	if len(regions) != 2 {
+		t.Fatalf("got regions %v, want eu and us", regions)
+	}
+
+	// Longest waiting first, ties broken by id.
+	queued, err := queue.Tickets("eu")
+	if err != nil {
+		t.Fatalf("tickets: %v", err)
+	}
+	if ids := ticketIDs(queued); !equalIDs(ids, "a", "b", "c") {
+		t.Fatalf("got tickets %v, want [a b c]", ids)
+	}
+	if queued[0].Rating != 1400 || !queued[0].EnqueuedAt.Equal(start) {
+		t.Fatalf("got ticket %+v back", queued[0])
+	}
+
+	candidates, _ := queue.Candidates("eu", 1450, 1600)
+	if ids := ticketIDs(candidates); !equalIDs(ids, "b", "c") {
+		t.Fatalf("got candidates %v, want [b c]", ids)
+	}
+}
+
+func TestRedisMatchQueueClaim(t *testing.T) {
+	cache, _ := newTestRedis(t)
+	queue := NewRedisMatchQueue(cache)
+	for _, id := range []string{"a", "b", "c"} {
+		queue.Enqueue(&MatchTicket{PlayerID: id, Region: "eu", EnqueuedAt: time.Now()}, ticketTTL)
+	}
+
+	// A claim naming a player that is not queued in the region takes no one.
+	if claimed, err := queue.Claim("eu", []string{"a", "x"}); err != nil || claimed {
+		t.Fatalf("claiming a group with a player that is not queued returned %v, %v", claimed, err)
+	}
+	if claimed, _ := queue.Claim("us", []string{"a"}); claimed {
+		t.Fatal("claimed a player in the wrong region")
+	}
+
+	if claimed, err := queue.Claim("eu", []string{"a", "b"}); err != nil || !claimed {
+		t.Fatalf("claiming queued players returned %v, %v", claimed, err)
+	}
+	if claimed, _ := queue.Claim("eu", []string{"b", "c"}); claimed {
+		t.Fatal("claimed a player twice")
+	}
+
+	queued, _ := queue.Tickets("eu")
+	if ids := ticketIDs(queued); !equalIDs(ids, "c") {
+		t.Fatalf("got tickets %v, want [c]", ids)
+	}
+
+	// Removing takes a ticket out of both sets.
+	queue.Remove(queued[0])
+	if candidates, _ := queue.Candidates("eu", 0, 3000); len(candidates) != 0 {
+		t.Fatalf("got candidates %v after removing the last ticket", ticketIDs(candidates))
+	}
+}
+
+func TestRedisMatchQueueTicketsExpire(t *testing.T) {
+	cache, server := newTestRedis(t)
+	queue := NewRedisMatchQueue(cache)
+	kept := &MatchTicket{PlayerID: "kept", Rating: 1500, Region: "eu", EnqueuedAt: time.Now()}
+	ghost := &MatchTicket{PlayerID: "ghost", Rating: 1500, Region: "eu", EnqueuedAt: time.Now()}
+	queue.Enqueue(kept, ticketTTL)
+	queue.Enqueue(ghost, ticketTTL)
+
+	for i := 0; i < 3; i++ {
+		server.FastForward(ticketRefreshInterval)
+		if refreshed, err := queue.Refresh(kept, ticketTTL); err != nil || !refreshed {
+			t.Fatalf("refresh %d returned %v, %v", i, refreshed, err)
+		}
+	}
+	server.FastForward(ticketRefreshInterval)
+
+	if claimed, _ := queue.Claim("eu", []string{"kept", "ghost"}); claimed {
+		t.Fatal("claimed an expired ticket")
+	}
+	// The sets still name the ghost until they are pruned, but its ticket
+	// is gone and stays gone.
+	if refreshed, _ := queue.Refresh(ghost, ticketTTL); refreshed || server.Exists(ticketKey("eu", "ghost")) {
+		t.Fatal("refreshed an expired ticket")
+	}
+
+	// Reading the queue prunes the players of expired tickets from both
+	// sets.
+	queued, _ := queue.Tickets("eu")
+	if ids := ticketIDs(queued); !equalIDs(ids, "kept") {
+		t.Fatalf("got tickets %v, want [kept]", ids)
+	}
+	for _, key := range []string{waitingKey("eu"), ratingKey("eu")} {
+		members, _ := server.ZMembers(key)
+		if !equalIDs(members, "kept") {
+			t.Fatalf("%s holds %v after pruning, want [kept]", key, members)
+		}
+	}
+
+	// A player queued again after its ticket expired is not pruned.
+	queue.Enqueue(ghost, ticketTTL)
+	server.FastForward(ticketRefreshInterval)
+	if candidates, _ := queue.Candidates("eu", 0, 3000); len(candidates) != 2 {
+		t.Fatalf("got candidates %v, want kept and the requeued ghost", ticketIDs(candidates))
+	}
+}
+
+func TestRedisMatchQueuePublishedMatches(t *testing.T) {
+	cache, _ := newTestRedis(t)
+	queue := NewRedisMatchQueue(cache)
+
+	if match, err := queue.TakeMatch("player"); err != nil || match != nil {
+		t.Fatalf("took %+v, %v before any match was published", match, err)
+	}
+
+	published := &MatchFoundPayload{RoomID: "room", TerrainID: "terrain", Players: []string{"player", "rival"}}
+	if err := queue.PublishMatch("player", published); err != nil {
+		t.Fatalf("publish: %v", err)
+	}
+	match, err := queue.TakeMatch("player")
+	if err != nil || match == nil || match.RoomID != "room" || !equalIDs(match.Players, "player", "rival") {
+		t.Fatalf("took %+v, %v, want the published match", match, err)
+	}
+	if match, _ := queue.TakeMatch("player"); match != nil {
+		t.Fatal("took the same match twice")
+	}
+}
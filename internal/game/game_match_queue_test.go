@@ -0,0 +1,163 @@
+package game
+
+import (
+	"testing"
+	"time"
+)
+
+func ticketIDs(tickets []*MatchTicket) []string {
+	ids := make([]string, len(tickets))
+	for i, ticket := range tickets {
+		ids[i] = ticket.PlayerID
+	}
+
+	return ids
+}
+
+func equalIDs(got []string, want ...string) bool {
+	if len(got) != len(want) {
+		return false
+	}
+	for i := range got {
+		if got[i] != want[i] {
+			return false
+		}
+	}
+
+	return true
+}
+
+func TestInMemoryMatchQueueOrder(t *testing.T) {
+	clock := newManualClock()
+	queue := NewInMemoryMatchQueue(clock)
+	start := clock.Now()
+
+	tickets := []*MatchTicket{
+		{PlayerID: "c", Rating: 1500, Region: "eu", EnqueuedAt: start.Add(time.Second)},
+		{PlayerID: "b", Rating: 1600, Region: "eu", EnqueuedAt: start},
+		{PlayerID: "a", Rating: 1400, Region: "eu", EnqueuedAt: start},
+		{PlayerID: "d", Rating: 1500, Region: "us", EnqueuedAt: start},
+	}
+	for _, ticket := range tickets {
+		if err := queue.Enqueue(ticket, ticketTTL); err != nil {
+			t.Fatalf("enqueue: %v", err)
+		}
+	}
+
+	regions, _ := queue.Regions()
+	if !equalIDs(regions, "eu", "us") {
+		t.Fatalf("got regions %v", regions)
+	}
+
+	// Longest waiting first, ties broken by id.
+	queued, _ := queue.Tickets("eu")
+	if ids := ticketIDs(queued); !equalIDs(ids, "a", "b", "c") {
+		t.Fatalf("got tickets %v, want [a b c]", ids)
+	}
+
+	candidates, _ := queue.Candidates("eu", 1450, 1600)
+	if ids := ticketIDs(candidates); !equalIDs(ids, "b", "c") {
+		t.Fatalf("got candidates %v, want [b c]", ids)
+	}
+}
+
+func TestInMemoryMatchQueueClaim(t *testing.T) {
+	clock := newManualClock()
+	queue := NewInMemoryMatchQueue(clock)
+	for _, id := range []string{"a", "b", "c"} {
+		queue.Enqueue(&MatchTicket{PlayerID: id, Region: "eu", EnqueuedAt: clock.Now()}, ticketTTL)
+	}
+
+	// A claim naming a player that is not queued in the region takes no one.
+	if claimed, _ := queue.Claim("eu", []string{"a", "x"}); claimed {
+		t.Fatal("claimed a group with a player that is not queued")
+	}
+	if claimed, _ := queue.Claim("us", []string{"a"}); claimed {
+		t.Fatal("claimed a player in the wrong region")
+	}
+
+	if claimed, _ := queue.Claim("eu", []string{"a", "b"}); !claimed {
+		t.Fatal("could not claim queued players")
+	}
+	if claimed, _ := queue.Claim("eu", []string{"b", "c"}); claimed {
+		t.Fatal("claimed a player twice")
+	}
+
+	queued, _ := queue.Tickets("eu")
+	if ids := ticketIDs(queued); !equalIDs(ids, "c") {
+		t.Fatalf("got tickets %v, want [c]", ids)
+	}
+}
+
+func TestInMemoryMatchQueueTicketsExpire(t *testing.T) {
+	clock := newManualClock()
+	queue := NewInMemoryMatchQueue(clock)
+	kept := &MatchTicket{PlayerID: "kept", Region: "eu", EnqueuedAt: clock.Now()}
+	ghost := &MatchTicket{PlayerID: "ghost", Region: "eu", EnqueuedAt: clock.Now()}
+	queue.Enqueue(kept, ticketTTL)
+	queue.Enqueue(ghost, ticketTTL)
+
+	for i := 0; i < 3; i++ {
+		clock.Advance(ticketRefreshInterval)
+		if refreshed, _ := queue.Refresh(kept, ticketTTL); !refreshed {
+			t.Fatalf("refresh %d failed", i)
+		}
+	}
+	clock.Advance(ticketRefreshInterval)
+
+	queued, _ := queue.Tickets("eu")
+	if ids := ticketIDs(queued); !equalIDs(ids, "kept") {
+		t.Fatalf("got tickets %v, want [kept]", ids)
+	}
+	if claimed, _ := queue.Claim("eu", []string{"kept", "ghost"}); claimed {
+		t.Fatal("claimed an expired ticket")
+	}
+	if refreshed, _ := queue.Refresh(ghost, ticketTTL); refreshed {
+		t.Fatal("refreshed an expired ticket")
+	}
+
+	clock.Advance(ticketTTL)
+	if regions, _ := queue.Regions(); len(regions) != 0 {
+		t.Fatalf("got regions %v after every ticket expired", regions)
+	}
+}
+
+func TestMatchmakerRequeuesExpiredTickets(t *testing.T) {
+	clock := newManualClock()
+	queue := NewInMemoryMatchQueue(clock)
+	matchmaker := NewMatchmaker(nil, queue, DefaultMatchmakerConfig(), clock)
+
+	searching := &searchingClient{ticket: &MatchTicket{PlayerID: "player", Region: "eu", EnqueuedAt: clock.Now()}}
+	matchmaker.searching["player"] = searching
+	queue.Enqueue(searching.ticket, ticketTTL)
+
+	// Refreshing keeps the ticket of a searching player queued.
+	for i := 0; i < 6; i++ {
+		clock.Advance(ticketRefreshInterval)
+		matchmaker.refreshTickets()
+	}
+	if queued, _ := queue.Tickets("eu"); len(queued) != 1 {
+		t.Fatalf("got %d tickets, want the refreshed one", len(queued))
+	}
+
+	// A ticket that is gone once may have been claimed, so it is only
+	// queued again when it is still gone on the next refresh.
+	clock.Advance(ticketTTL)
+	matchmaker.refreshTickets()
+	if queued, _ := queue.Tickets("eu"); len(queued) != 0 {
+		t.Fatal("requeued a ticket missing once")
+	}
+	matchmaker.refreshTickets()
+	if queued, _ := queue.Tickets("eu"); len(queued) != 1 {
+		t.Fatal("did not requeue a ticket missing twice")
+	}
+
+	// A player that got its match is no longer searching and stays out.
+	queue.Claim("eu", []string{"player"})
+	matchmaker.refreshTickets()
+	matchmaker.takeSearching("player")
+	matchmaker.refreshTickets()
+	if queued, _ := queue.Tickets("eu"); len(queued) != 0 {
+		t.Fatal("requeued the ticket of a matched player")
+	}
+}
@@ -0,0 +1,160 @@
+package game
+
+import (
+	"ais-summoner/internal/models"
+	"context"
+	"sort"
+	"time"
+)
+
+// record builds the match document of the room. Casual rooms have no
+// outcome, so their players are placed by kills.
+func (room *GameRoom) record(outcome *MatchOutcome) *models.Match {
+	room.mutex.RLock()
+	defer room.mutex.RUnlock()
+
+	endedAt := room.clock.Now()
+	match := &models.Match{
+		RoomID:     room.id,
+		TerrainID:  room.terrain.ID,
+		Ranked:     room.match != nil,
+		StartedAt:  room.startedAt,
+		EndedAt:    endedAt,
+		DurationMs: endedAt.Sub(room.startedAt).Milliseconds(),
+	}
+
+	playerIDs := make([]string, 0, len(room.stats))
+	for playerID := range room.stats {
+		playerIDs = append(playerIDs, playerID)
+	}
+	if outcome != nil {
+		for playerID := range outcome.Placements {
+			if _, exists := room.stats[playerID]; !exists {
+				playerIDs = append(playerIDs, playerID)
+			}
+		}
+	}
+
+	var placements map[string]int
+	if outcome != nil {
+		placements = outcome.Placements
+		match.WinnerID = outcome.WinnerID
+	} else {
+		placements, match.WinnerID = placeByKills(playerIDs, room.kills())
+	}
+
+	sort.Slice(playerIDs, func(i, j int) bool {
+		return placements[playerIDs[i]] < placements[playerIDs[j]]
+	})
+	for _, playerID := range playerIDs {
+		participant := models.MatchParticipant{
+			UserID:    playerID,
+			Placement: placements[playerID],
+			Bot:       room.bots[playerID],
+		}
+		if stats, exists := room.stats[playerID]; exists {
+			participant.Stats = *stats
+		}
+		match.Participants = append(match.Participants, participant)
+	}
+
+	return match
+}
+
+// replay closes the replay log of the room and returns it.
+func (room *GameRoom) replay() ([]byte, error) {
+	room.mutex.Lock()
+	defer room.mutex.Unlock()
+
+	room.recorder.End(room.simulation.Tick())
+	return room.recorder.Bytes()
+}
+
+// kills must be called with the room mutex held.
+func (room *GameRoom) kills() map[string]int {
+	kills := make(map[string]int, len(room.stats))
+	for playerID, stats := range room.stats {
+		kills[playerID] = stats.Kills
+	}
+
+	return kills
+}
+
+// placeByKills ranks players by kills, sharing places on ties, and returns
+// the player with the most kills when nobody else has as many.
+func placeByKills(playerIDs []string, kills map[string]int) (map[string]int, string) {
+	sorted := append([]string(nil), playerIDs...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return kills[sorted[i]] > kills[sorted[j]]
+	})
+
+	placements := make(map[string]int, len(sorted))
+	for i, playerID := range sorted {
+		if i > 0 && kills[playerID] == kills[sorted[i-1]] {
+			placements[playerID] = placements[sorted[i-1]]
+		} else {
+			placements[playerID] = i + 1
+		}
+	}
+
+	winnerID := ""
+	if len(sorted) == 1 || len(sorted) > 1 && kills[sorted[0]] > kills[sorted[1]] {
+		winnerID = sorted[0]
+	}
+
+	return placements, winnerID
+}
+
+// hasHumanParticipant reports whether anyone but bots played the match.
+func hasHumanParticipant(match *models.Match) bool {
+	for _, participant := range match.Participants {
+		if !participant.Bot {
+			return true
+		}
+	}
+
+	return false
+}
+
+// recordMatch stores the match and its replay, and adds the match to the
+// lifetime stats of every participant that is not a bot.
+func (gateway *GameGateway) recordMatch(match *models.Match, replay []byte) {
+	if gateway.mongodb == nil {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	if _, err := gateway.mongodb.MatchRepository().Insert(ctx, match); err != nil {
+		gateway.logger.Printf("Error saving match of room %s: %v", match.RoomID, err)
+		return
+	}
+
+	if replay != nil {
+		if err := gateway.replays.Save(ctx, match.ID.Hex(), replay); err != nil {
+			gateway.logger.Printf("Error saving replay of match %s: %v", match.ID.Hex(), err)
+		}
+	}
+
+	for _, participant := range match.Participants {
+		if participant.Bot {
+			continue
+		}
+
+		stats := models.UserStats{
+			Matches: 1,
+			Kills:   participant.Stats.Kills,
+			Deaths:  participant.Stats.Deaths,
+			Damage:  participant.Stats.Damage,
+			Dashes:  participant.Stats.Dashes,
+		}
+		if participant.UserID == match.WinnerID {
+			stats.Wins = 1
+		}
+
+		if err := gateway.mongodb.UserRepository().IncrementStats(ctx, participant.UserID, stats); err != nil {
+			gateway.logger.Printf("Error updating stats of %s: %v", participant.UserID, err)
+		}
+	}
+}
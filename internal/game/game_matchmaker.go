@@ -0,0 +1,518 @@
+package game
+
+import (
+	"ais-summoner/internal/models"
+	"ais-summoner/internal/pkg/glicko"
+	"context"
+	"log"
+	"math"
+	"math/rand"
+	"os"
+	"sort"
+	"sync"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const DefaultRating = glicko.DefaultRating
+
+const (
+	// ticketTTL is how long the ticket of a player stays queued after the
+	// instance it searches on stopped refreshing it, for example because
+	// it crashed.
+	ticketTTL             = 30 * time.Second
+	ticketRefreshInterval = ticketTTL / 3
+)
+
+type MatchmakerConfig struct {
+	MatchSize int
+	// BaseWindow is the rating difference accepted as soon as a player
+	// joins the queue. It grows by WindowGrowth every second they wait, up
+	// to MaxWindow.
+	BaseWindow   float64
+	WindowGrowth float64
+	MaxWindow    float64
+	Interval     time.Duration
+	// BackfillAfter is how long a player waits before the missing seats of
+	// their match are filled with bots playing BotBehaviour. Zero disables
+	// backfill.
+	BackfillAfter time.Duration
+	BotBehaviour  string
+}
+
+// DefaultMatchmakerConfig returns the matchmaker configuration, reading the
+// bot backfill delay from GAME_BOT_BACKFILL and the behaviour of backfilled
+// bots from GAME_BOT_BEHAVIOUR when they are set.
+func DefaultMatchmakerConfig() MatchmakerConfig {
+	config := MatchmakerConfig{
+		MatchSize:    2,
+		BaseWindow:   50,
+		WindowGrowth: 10,
+		MaxWindow:    500,
+		Interval:     time.Second,
+		BotBehaviour: DefaultBotBehaviour,
+	}
+
+	if backfill, err := time.ParseDuration(os.Getenv("GAME_BOT_BACKFILL")); err == nil && backfill > 0 {
+		config.BackfillAfter = backfill
+	}
+	if behaviour := os.Getenv("GAME_BOT_BEHAVIOUR"); behaviour != "" {
+		config.BotBehaviour = behaviour
+	}
+
+	return config
+}
+
+// Matchmaker pairs queued players of similar rating in the same region and
+// opens a room for them once a match fills.
+type Matchmaker struct {
+	gateway   *GameGateway
+	queue     MatchQueue
+	config    MatchmakerConfig
+	clock     Clock
+	logger    *log.Logger
+	searching map[string]*searchingClient
+	mutex     sync.Mutex
+	done      chan struct{}
+}
+
+type searchingClient struct {
+	client *GameClient
+	ticket *MatchTicket
+	// missing is set when the last refresh found the ticket gone. It is
+	// only touched by refreshTickets.
+	missing bool
+}
+
+func NewMatchmaker(gateway *GameGateway, queue MatchQueue, config MatchmakerConfig, clock Clock) *Matchmaker {
+	return &Matchmaker{
+		gateway:   gateway,
+		queue:     queue,
+		config:    config,
+		clock:     clock,
+		logger:    log.New(log.Writer(), "[Matchmaker] ", log.LstdFlags),
+		searching: make(map[string]*searchingClient),
+		done:      make(chan struct{}),
+	}
+}
+
+// Run attempts to form matches at the configured interval, and keeps the
+// tickets of the players searching here alive, until stopped.
+func (matchmaker *Matchmaker) Run() {
+	ticker := matchmaker.clock.NewTicker(matchmaker.config.Interval)
+	defer ticker.Stop()
+	refresh := matchmaker.clock.NewTicker(ticketRefreshInterval)
+	defer refresh.Stop()
+
+	for {
+		select {
+		case <-ticker.C():
+			matchmaker.Match()
+		case <-refresh.C():
+			matchmaker.refreshTickets()
+		case <-matchmaker.done:
+			return
+		}
+	}
+}
+
+func (matchmaker *Matchmaker) Stop() {
+	close(matchmaker.done)
+}
+
+// Find puts the client in the queue for the requested region.
+func (matchmaker *Matchmaker) Find(client *GameClient, payload *FindMatchPayload) error {
+	if matchmaker.gateway.Draining() {
+		return ErrServerDraining
+	}
+
+	rating := matchmaker.ratingOf(client)
+
+	matchmaker.mutex.Lock()
+	defer matchmaker.mutex.Unlock()
+
+	if _, exists := matchmaker.searching[client.ID()]; exists {
+		return ErrAlreadySearching
+	}
+
+	ticket := &MatchTicket{
+		PlayerID:   client.ID(),
+		Rating:     rating,
+		Region:     payload.Region,
+		TerrainID:  payload.TerrainID,
+		EnqueuedAt: matchmaker.clock.Now(),
+	}
+	if err := matchmaker.queue.Enqueue(ticket, ticketTTL); err != nil {
+		return err
+	}
+
+	matchmaker.searching[client.ID()] = &searchingClient{client: client, ticket: ticket}
+	return nil
+}
+
+// Cancel takes the client out of the queue.
+func (matchmaker *Matchmaker) Cancel(client *GameClient) error {
+	matchmaker.mutex.Lock()
+	defer matchmaker.mutex.Unlock()
+
+	searching, exists := matchmaker.searching[client.ID()]
+	if !exists || searching.client != client {
+		return ErrNotSearching
+	}
+
+	delete(matchmaker.searching, client.ID())
+	return matchmaker.queue.Remove(searching.ticket)
+}
+
+// refreshTickets extends the tickets of the players searching here. A
+// ticket missing once may have been claimed for a match that is still on
+// its way; missing twice in a row it expired, most likely while Redis was
+// unreachable, and is queued again.
+func (matchmaker *Matchmaker) refreshTickets() {
+	matchmaker.mutex.Lock()
+	searching := make([]*searchingClient, 0, len(matchmaker.searching))
+	for _, entry := range matchmaker.searching {
+		searching = append(searching, entry)
+	}
+	matchmaker.mutex.Unlock()
+
+	for _, entry := range searching {
+		refreshed, err := matchmaker.queue.Refresh(entry.ticket, ticketTTL)
+		if err != nil {
+			matchmaker.logger.Printf("Error refreshing ticket of %s: %v", entry.ticket.PlayerID, err)
+			continue
+		}
+		if refreshed || !entry.missing {
+			entry.missing = !refreshed
+			continue
+		}
+
+		matchmaker.requeue(entry)
+	}
+}
+
+// requeue queues the expired ticket of a player still searching here.
+func (matchmaker *Matchmaker) requeue(entry *searchingClient) {
+	matchmaker.mutex.Lock()
+	defer matchmaker.mutex.Unlock()
+
+	if matchmaker.searching[entry.ticket.PlayerID] != entry {
+		return
+	}
+
+	matchmaker.logger.Printf("Ticket of %s expired, queueing it again", entry.ticket.PlayerID)
+	if err := matchmaker.queue.Enqueue(entry.ticket, ticketTTL); err != nil {
+		matchmaker.logger.Printf("Error queueing ticket of %s: %v", entry.ticket.PlayerID, err)
+		return
+	}
+	entry.missing = false
+}
+
+// Match runs one matchmaking pass over every region and delivers matches
+// that other instances formed for players connected here.
+func (matchmaker *Matchmaker) Match() {
+	regions, err := matchmaker.queue.Regions()
+	if err != nil {
+		matchmaker.logger.Printf("Error listing regions: %v", err)
+		return
+	}
+
+	for _, region := range regions {
+		if err := matchmaker.matchRegion(region); err != nil {
+			matchmaker.logger.Printf("Error matching region %s: %v", region, err)
+		}
+	}
+
+	matchmaker.deliverPublishedMatches()
+}
+
+func (matchmaker *Matchmaker) matchRegion(region string) error {
+	tickets, err := matchmaker.queue.Tickets(region)
+	if err != nil {
+		return err
+	}
+
+	now := matchmaker.clock.Now()
+	matched := make(map[string]bool)
+
+	for _, anchor := range tickets {
+		if matched[anchor.PlayerID] {
+			continue
+		}
+
+		window := matchmaker.window(now.Sub(anchor.EnqueuedAt))
+		candidates, err := matchmaker.queue.Candidates(region, anchor.Rating-window, anchor.Rating+window)
+		if err != nil {
+			return err
+		}
+
+		group := matchmaker.pickGroup(anchor, candidates, matched, now)
+		bots := 0
+		if group == nil {
+			group, bots = matchmaker.backfillGroup(anchor, candidates, matched, now)
+			if group == nil {
+				continue
+			}
+		}
+
+		terrain, err := matchmaker.chooseTerrain(group)
+		if err != nil {
+			return err
+		}
+
+		playerIDs := make([]string, len(group))
+		for i, ticket := range group {
+			playerIDs[i] = ticket.PlayerID
+		}
+
+		claimed, err := matchmaker.queue.Claim(region, playerIDs)
+		if err != nil {
+			return err
+		}
+		if !claimed {
+			// Another instance matched some of these players first.
+			continue
+		}
+
+		for _, playerID := range playerIDs {
+			matched[playerID] = true
+		}
+		matchmaker.startMatch(terrain, playerIDs, bots)
+	}
+
+	return nil
+}
+
+// window is the rating difference accepted after waiting for the given time.
+func (matchmaker *Matchmaker) window(waited time.Duration) float64 {
+	window := matchmaker.config.BaseWindow + matchmaker.config.WindowGrowth*waited.Seconds()
+	return math.Min(window, matchmaker.config.MaxWindow)
+}
+
+// pickGroup completes a match around the anchor with the closest rated
+// candidates that every member of the group accepts, or returns nil when
+// there are not enough of them.
+func (matchmaker *Matchmaker) pickGroup(anchor *MatchTicket, candidates []*MatchTicket, matched map[string]bool, now time.Time) []*MatchTicket {
+	var others []*MatchTicket
+	for _, candidate := range candidates {
+		if candidate.PlayerID != anchor.PlayerID && !matched[candidate.PlayerID] {
+			others = append(others, candidate)
+		}
+	}
+
+	sort.SliceStable(others, func(i, j int) bool {
+		return math.Abs(others[i].Rating-anchor.Rating) < math.Abs(others[j].Rating-anchor.Rating)
+	})
+
+	group := []*MatchTicket{anchor}
+	for _, candidate := range others {
+		if len(group) == matchmaker.config.MatchSize {
+			break
+		}
+		if matchmaker.fits(group, candidate, now) {
+			group = append(group, candidate)
+		}
+	}
+
+	if len(group) < matchmaker.config.MatchSize {
+		return nil
+	}
+	return group
+}
+
+// fits reports whether the candidate and every member of the group are
+// within each other's rating window.
+func (matchmaker *Matchmaker) fits(group []*MatchTicket, candidate *MatchTicket, now time.Time) bool {
+	window := matchmaker.window(now.Sub(candidate.EnqueuedAt))
+	for _, member := range group {
+		difference := math.Abs(member.Rating - candidate.Rating)
+		if difference > window || difference > matchmaker.window(now.Sub(member.EnqueuedAt)) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// backfillGroup gathers every available candidate around an anchor that
+// has waited longer than BackfillAfter and returns how many bots complete
+// the match. It returns nil while the anchor should keep waiting.
+func (matchmaker *Matchmaker) backfillGroup(anchor *MatchTicket, candidates []*MatchTicket, matched map[string]bool, now time.Time) ([]*MatchTicket, int) {
+	if matchmaker.config.BackfillAfter <= 0 || now.Sub(anchor.EnqueuedAt) < matchmaker.config.BackfillAfter {
+		return nil, 0
+	}
+
+	group := []*MatchTicket{anchor}
+	for _, candidate := range candidates {
+		if candidate.PlayerID != anchor.PlayerID && !matched[candidate.PlayerID] && len(group) < matchmaker.config.MatchSize && matchmaker.fits(group, candidate, now) {
+			group = append(group, candidate)
+		}
+	}
+
+	return group, matchmaker.config.MatchSize - len(group)
+}
+
+// chooseTerrain picks the terrain the longest waiting player asked for,
+// or a random one when nobody in the group has a preference.
+func (matchmaker *Matchmaker) chooseTerrain(group []*MatchTicket) (*models.Terrain, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	terrains := matchmaker.gateway.mongodb.TerrainRepository()
+	for _, ticket := range group {
+		if ticket.TerrainID == "" {
+			continue
+		}
+
+		terrain, err := terrains.GetByID(ctx, ticket.TerrainID)
+		if err != nil {
+			return nil, err
+		}
+		if terrain != nil {
+			return terrain, nil
+		}
+	}
+
+	list, err := terrains.Find(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if len(list) == 0 {
+		return nil, ErrTerrainNotFound
+	}
+
+	return list[rand.Intn(len(list))], nil
+}
+
+// startMatch opens the room, fills the given number of seats with bots and
+// moves the players connected to this instance into it. Players connected
+// elsewhere get the match over the cluster, which redirects them to this
+// instance. It is also kept in the queue for instances that miss the
+// message.
+func (matchmaker *Matchmaker) startMatch(terrain *models.Terrain, playerIDs []string, bots int) {
+	botIDs := make([]string, bots)
+	for i := range botIDs {
+		botIDs[i] = primitive.NewObjectID().Hex()
+	}
+
+	participants := append(append([]string(nil), playerIDs...), botIDs...)
+	room := matchmaker.gateway.CreateMatchRoom(terrain, participants)
+	matchmaker.gateway.spawnBots(room, botIDs, matchmaker.config.BotBehaviour)
+	match := &MatchFoundPayload{
+		RoomID:    room.id,
+		TerrainID: terrain.ID.Hex(),
+		Players:   participants,
+	}
+
+	if bots > 0 {
+		matchmaker.logger.Printf("Match formed in room %s for %v with %d bots", room.id, playerIDs, bots)
+	} else {
+		matchmaker.logger.Printf("Match formed in room %s for %v", room.id, playerIDs)
+	}
+
+	for _, playerID := range playerIDs {
+		if client := matchmaker.takeSearching(playerID); client != nil {
+			matchmaker.deliver(client, match)
+			continue
+		}
+
+		if err := matchmaker.queue.PublishMatch(playerID, match); err != nil {
+			matchmaker.logger.Printf("Error publishing match for %s: %v", playerID, err)
+		}
+		if err := matchmaker.gateway.publish(playerID, MatchFound, match); err != nil {
+			matchmaker.logger.Printf("Error sending match to %s: %v", playerID, err)
+		}
+	}
+}
+
+// receiveMatch delivers a match another instance formed for a player
+// searching here, dropping the copy it kept in the queue.
+func (matchmaker *Matchmaker) receiveMatch(playerID string, match *MatchFoundPayload) {
+	client := matchmaker.takeSearching(playerID)
+	if client == nil {
+		return
+	}
+
+	if _, err := matchmaker.queue.TakeMatch(playerID); err != nil {
+		matchmaker.logger.Printf("Error reading match for %s: %v", playerID, err)
+	}
+	matchmaker.deliver(client, match)
+}
+
+func (matchmaker *Matchmaker) deliverPublishedMatches() {
+	matchmaker.mutex.Lock()
+	playerIDs := make([]string, 0, len(matchmaker.searching))
+	for playerID := range matchmaker.searching {
+		playerIDs = append(playerIDs, playerID)
+	}
+	matchmaker.mutex.Unlock()
+
+	for _, playerID := range playerIDs {
+		match, err := matchmaker.queue.TakeMatch(playerID)
+		if err != nil {
+			matchmaker.logger.Printf("Error reading match for %s: %v", playerID, err)
+			continue
+		}
+		if match == nil {
+			continue
+		}
+
+		if client := matchmaker.takeSearching(playerID); client != nil {
+			matchmaker.deliver(client, match)
+		}
+	}
+}
+
+// ratingOf reads the current rating of the client's user, so ratings
+// changed by a previous match are taken into account. Without a database
+// the rating the user logged in with is used.
+func (matchmaker *Matchmaker) ratingOf(client *GameClient) float64 {
+	user := client.user
+	if matchmaker.gateway.mongodb != nil {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+
+		if current, err := matchmaker.gateway.mongodb.UserRepository().GetByID(ctx, client.ID()); err == nil && current != nil {
+			user = current
+		}
+	}
+
+	if user == nil || user.Rating.Deviation == 0 {
+		return DefaultRating
+	}
+
+	return user.Rating.Rating
+}
+
+func (matchmaker *Matchmaker) takeSearching(playerID string) *GameClient {
+	matchmaker.mutex.Lock()
+	defer matchmaker.mutex.Unlock()
+
+	searching, exists := matchmaker.searching[playerID]
+	if !exists {
+		return nil
+	}
+
+	delete(matchmaker.searching, playerID)
+	return searching.client
+}
+
+// deliver tells the client about its match and moves it into the room. A
+// client that cannot join a room hosted here, for example because it
+// joined another room meanwhile, forfeits its seat so the other players
+// do not wait for it until the match times out.
+func (matchmaker *Matchmaker) deliver(client *GameClient, match *MatchFoundPayload) {
+	client.sendMessage(MatchFound, match)
+
+	err := matchmaker.gateway.joinRoom(client, match.RoomID, "")
+	if err == nil {
+		return
+	}
+	client.sendError(err)
+
+	if room := matchmaker.gateway.FindRoom(match.RoomID); room != nil {
+		matchmaker.logger.Printf("Player %s forfeits the match in room %s: %v", client.ID(), room.id, err)
+		room.forfeit(client.ID())
+	}
+}
@@ -0,0 +1,118 @@
+package game
+
+import (
+	"ais-summoner/internal/models"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestFindWithoutDatabase(t *testing.T) {
+	clock := newManualClock()
+	queue := NewInMemoryMatchQueue(clock)
+	gateway := newGameGateway(NewInMemoryCluster(clock), queue, clock)
+
+	// Without a database the rating comes from the user that logged in.
+	client, _ := newTestClient(gateway, primitive.NewObjectID().Hex())
+	client.user = &models.User{Rating: models.Rating{Rating: 1720, Deviation: 80}}
+	if err := gateway.matchmaker.Find(client, &FindMatchPayload{Region: "eu"}); err != nil {
+		t.Fatalf("find: %v", err)
+	}
+
+	queued, _ := queue.Tickets("eu")
+	if len(queued) != 1 || queued[0].Rating != 1720 {
+		t.Fatalf("got tickets %+v, want one rated 1720", queued)
+	}
+}
+
+func TestPairingsFitBothWindows(t *testing.T) {
+	clock := newManualClock()
+	config := DefaultMatchmakerConfig()
+	matchmaker := NewMatchmaker(nil, NewInMemoryMatchQueue(clock), config, clock)
+	now := clock.Now()
+
+	// The veteran has waited long enough to accept the newcomer, but the
+	// newcomer does not accept a rating that far off yet.
+	difference := 200.0
+	veteran := &MatchTicket{PlayerID: "veteran", Rating: 1500, EnqueuedAt: now.Add(-time.Minute)}
+	newcomer := &MatchTicket{PlayerID: "newcomer", Rating: 1500 + difference, EnqueuedAt: now}
+	candidates := []*MatchTicket{veteran, newcomer}
+
+	if group := matchmaker.pickGroup(veteran, candidates, map[string]bool{}, now); group != nil {
+		t.Fatalf("paired %v outside the newcomer's window", ticketIDs(group))
+	}
+
+	// Once the newcomer's window has grown to the difference they pair.
+	waited := time.Duration((difference - config.BaseWindow) / config.WindowGrowth * float64(time.Second))
+	later := now.Add(waited)
+	group := matchmaker.pickGroup(veteran, candidates, map[string]bool{}, later)
+	if ids := ticketIDs(group); !equalIDs(ids, "veteran", "newcomer") {
+		t.Fatalf("got group %v once both windows fit, want [veteran newcomer]", ids)
+	}
+
+	// A group of three only takes players every member accepts.
+	config.MatchSize = 3
+	matchmaker = NewMatchmaker(nil, NewInMemoryMatchQueue(clock), config, clock)
+	low := &MatchTicket{PlayerID: "low", Rating: 1400, EnqueuedAt: now.Add(-time.Minute)}
+	high := &MatchTicket{PlayerID: "high", Rating: 1600, EnqueuedAt: now.Add(-10 * time.Second)}
+	middle := &MatchTicket{PlayerID: "middle", Rating: 1450, EnqueuedAt: now.Add(-time.Minute)}
+	if group := matchmaker.pickGroup(low, []*MatchTicket{low, high, middle}, map[string]bool{}, now); group != nil {
+		t.Fatalf("grouped %v although high does not accept low", ticketIDs(group))
+	}
+}
+
+func TestJoiningAnotherRoomCancelsTheSearch(t *testing.T) {
+	clock := newManualClock()
+	queue := NewInMemoryMatchQueue(clock)
+	gateway := newGameGateway(NewInMemoryCluster(clock), queue, clock)
+	room := gateway.CreateRoom(squareTerrain())
+	defer room.stop()
+
+	client, _ := newTestClient(gateway, primitive.NewObjectID().Hex())
+	if err := handleFindMatch(client, &FindMatchPayload{Region: "eu"}); err != nil {
+		t.Fatalf("find: %v", err)
+	}
+	if err := handleJoinGame(client, &JoinGamePayload{RoomID: room.ID()}); err != nil {
+		t.Fatalf("join: %v", err)
+	}
+
+	if queued, _ := queue.Tickets("eu"); len(queued) != 0 {
+		t.Fatalf("still queued as %v after joining a room", ticketIDs(queued))
+	}
+	if err := gateway.matchmaker.Cancel(client); err != ErrNotSearching {
+		t.Fatalf("got %v cancelling again, want ErrNotSearching", err)
+	}
+}
+
+func TestUndeliverableMatchIsForfeited(t *testing.T) {
+	clock := newManualClock()
+	gateway := newGameGateway(NewInMemoryCluster(clock), NewInMemoryMatchQueue(clock), clock)
+
+	casual := gateway.CreateRoom(squareTerrain())
+	defer casual.stop()
+	absentID, presentID := primitive.NewObjectID().Hex(), primitive.NewObjectID().Hex()
+	ranked := gateway.CreateMatchRoom(squareTerrain(), []string{absentID, presentID})
+	defer ranked.stop()
+	clock.waitForTickers(t, 2)
+
+	// The match reaches a player that went into another room meanwhile.
+	absent, absentInbox := newTestClient(gateway, absentID)
+	if err := gateway.joinRoom(absent, casual.ID(), ""); err != nil {
+		t.Fatalf("joining the casual room: %v", err)
+	}
+	match := &MatchFoundPayload{RoomID: ranked.ID(), Players: []string{absentID, presentID}}
+	gateway.matchmaker.deliver(absent, match)
+	absentInbox.expect(t, Error, nil)
+
+	present, presentInbox := newTestClient(gateway, presentID)
+	gateway.matchmaker.deliver(present, match)
+
+	// The match ends on the next tick rather than at its time limit.
+	clock.Advance(gateway.config.TickInterval())
+	var outcome MatchOutcome
+	presentInbox.expect(t, MatchEnded, &outcome)
+	if outcome.WinnerID != presentID || outcome.Placements[absentID] != 2 {
+		t.Fatalf("got outcome %+v, want a win for the player that came", outcome)
+	}
+}
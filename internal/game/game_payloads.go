@@ -0,0 +1,160 @@
+package game
+
+import (
+	"ais-summoner/internal/models"
+	"errors"
+	"math"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Payload is implemented by every incoming event payload so it can be
+// checked after decoding and before it reaches its handler.
+type Payload interface {
+	Validate() error
+}
+
+type AuthenticationPayload struct {
+	Cookie string `json:"cookie,omitempty"`
+	Token  string `json:"token,omitempty"`
+}
+
+func (payload *AuthenticationPayload) Validate() error {
+	if (payload.Cookie == "") == (payload.Token == "") {
+		return errors.New("exactly one of cookie or token is required")
+	}
+
+	return nil
+}
+
+type JoinGamePayload struct {
+	RoomID    string `json:"roomId,omitempty"`
+	TerrainID string `json:"terrainId,omitempty"`
+}
+
+func (payload *JoinGamePayload) Validate() error {
+	if (payload.RoomID == "") == (payload.TerrainID == "") {
+		return errors.New("exactly one of roomId or terrainId is required")
+	}
+	if payload.TerrainID != "" && !primitive.IsValidObjectID(payload.TerrainID) {
+		return errors.New("terrainId is not a valid id")
+	}
+
+	return nil
+}
+
+type LeaveGamePayload struct{}
+
+func (payload *LeaveGamePayload) Validate() error {
+	return nil
+}
+
+type PlayerMovePayload struct {
+	Direction models.Vector2 `json:"direction"`
+	Seq       uint32         `json:"seq"`
+}
+
+func (payload *PlayerMovePayload) Validate() error {
+	return validateDirection(payload.Direction)
+}
+
+type PlayerDashPayload struct {
+	Direction models.Vector2 `json:"direction"`
+	Seq       uint32         `json:"seq"`
+}
+
+func (payload *PlayerDashPayload) Validate() error {
+	return validateDirection(payload.Direction)
+}
+
+// SnapshotAckPayload tells the server which state update the client last
+// received, so later updates can be sent as deltas against it.
+type SnapshotAckPayload struct {
+	Tick uint64 `json:"tick"`
+}
+
+func (payload *SnapshotAckPayload) Validate() error {
+	if payload.Tick == 0 {
+		return errors.New("tick must be positive")
+	}
+
+	return nil
+}
+
+type FindMatchPayload struct {
+	Region    string `json:"region"`
+	TerrainID string `json:"terrainId,omitempty"`
+}
+
+func (payload *FindMatchPayload) Validate() error {
+	if payload.Region == "" || len(payload.Region) > 32 {
+		return errors.New("region must be between 1 and 32 characters")
+	}
+	for _, r := range payload.Region {
+		if !(r >= 'a' && r <= 'z' || r >= '0' && r <= '9' || r == '-') {
+			return errors.New("region may only contain lowercase letters, digits and dashes")
+		}
+	}
+	if payload.TerrainID != "" && !primitive.IsValidObjectID(payload.TerrainID) {
+		return errors.New("terrainId is not a valid id")
+	}
+
+	return nil
+}
+
+type CancelMatchPayload struct{}
+
+func (payload *CancelMatchPayload) Validate() error {
+	return nil
+}
+
+// ResumeSessionPayload carries the resume token the client got when it
+// joined the room it wants back into.
+type ResumeSessionPayload struct {
+	Token string `json:"token"`
+}
+
+func (payload *ResumeSessionPayload) Validate() error {
+	if _, valid := resumeTokenRoom(payload.Token); !valid {
+		return errors.New("token is not a valid resume token")
+	}
+
+	return nil
+}
+
+type MatchFoundPayload struct {
+	RoomID    string   `json:"roomId"`
+	TerrainID string   `json:"terrainId"`
+	Players   []string `json:"players"`
+}
+
+// RedirectPayload tells a client that the room it asked for is hosted by
+// another instance, which it reaches by reconnecting to Address.
+type RedirectPayload struct {
+	RoomID     string `json:"roomId"`
+	InstanceID string `json:"instanceId"`
+	Address    string `json:"address"`
+}
+
+// ServerMaintenancePayload warns that the server is shutting down. Matches
+// in progress may play on until Deadline, in Unix milliseconds, when every
+// socket is closed. Players should find their next game elsewhere.
+type ServerMaintenancePayload struct {
+	Deadline int64 `json:"deadline,omitempty"`
+}
+
+type ErrorPayload struct {
+	Code    ErrorCode `json:"code"`
+	Message string    `json:"message"`
+}
+
+func validateDirection(direction models.Vector2) error {
+	if math.IsNaN(direction.X) || math.IsNaN(direction.Y) || math.IsInf(direction.X, 0) || math.IsInf(direction.Y, 0) {
+		return errors.New("direction must be finite")
+	}
+	if math.Hypot(direction.X, direction.Y) > 1.0001 {
+		return errors.New("direction must not be longer than 1")
+	}
+
+	return nil
+}
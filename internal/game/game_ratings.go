@@ -0,0 +1,150 @@
+package game
+
+import (
+	"ais-summoner/internal/leaderboard"
+	"ais-summoner/internal/models"
+	"ais-summoner/internal/pkg/glicko"
+	"context"
+	"errors"
+	"time"
+)
+
+// ratingUpdateAttempts is how many times a rating is computed again when
+// another match updated it first.
+const ratingUpdateAttempts = 3
+
+var (
+	errRatingConflict = errors.New("rating kept changing")
+	errUserGone       = errors.New("user no longer exists")
+)
+
+// recordRatings treats a finished ranked match as one Glicko-2 rating
+// period in which every participant played every other one, then stores
+// the new ratings and submits them to the leaderboards. The lifetime
+// rating and the rating of the current season are updated apart, each
+// against the opponents' rating of the same kind. Bots have no user and
+// thus no rating, so a match against bots alone leaves ratings as they
+// are.
+func (gateway *GameGateway) recordRatings(outcome *MatchOutcome) {
+	if gateway.mongodb == nil {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	playerIDs := make([]string, 0, len(outcome.Placements))
+	for playerID := range outcome.Placements {
+		playerIDs = append(playerIDs, playerID)
+	}
+
+	users, err := gateway.mongodb.UserRepository().FindByIDs(ctx, playerIDs)
+	if err != nil {
+		gateway.logger.Printf("Error loading ratings for room %s: %v", outcome.RoomID, err)
+		return
+	}
+	if len(users) < 2 {
+		return
+	}
+
+	season := leaderboard.CurrentSeason(gateway.clock.Now())
+	ratings := make(map[string]glicko.Rating, len(users))
+	seasonRatings := make(map[string]glicko.Rating, len(users))
+	for _, user := range users {
+		ratings[user.ID.Hex()] = toGlicko(user.Rating)
+		seasonRatings[user.ID.Hex()] = toGlicko(user.SeasonRatings[season])
+	}
+
+	for _, user := range users {
+		playerID := user.ID.Hex()
+
+		rating, err := gateway.updateRating(ctx, user, "", ratings, outcome.Placements)
+		if err != nil {
+			gateway.logger.Printf("Error saving rating of %s: %v", playerID, err)
+			continue
+		}
+		seasonRating, err := gateway.updateRating(ctx, user, season, seasonRatings, outcome.Placements)
+		if err != nil {
+			gateway.logger.Printf("Error saving %s rating of %s: %v", season, playerID, err)
+			continue
+		}
+
+		if err := gateway.leaderboard.Submit(playerID, rating.Rating, season, seasonRating.Rating); err != nil {
+			gateway.logger.Printf("Error submitting %s to the leaderboard: %v", playerID, err)
+		}
+	}
+}
+
+// updateRating rates the user against the opponents' ratings from before
+// the match and stores the result. When another match stored a rating of
+// the user in the meantime, the user is loaded again and rated from there.
+// An empty season selects the lifetime rating.
+func (gateway *GameGateway) updateRating(ctx context.Context, user *models.User, season string, opponents map[string]glicko.Rating, placements map[string]int) (models.Rating, error) {
+	playerID := user.ID.Hex()
+
+	var results []glicko.Result
+	for opponentID, opponent := range opponents {
+		if opponentID == playerID {
+			continue
+		}
+		results = append(results, glicko.Result{
+			Opponent: opponent,
+			Score:    score(placements[playerID], placements[opponentID]),
+		})
+	}
+
+	for attempt := 0; attempt < ratingUpdateAttempts; attempt++ {
+		previous := user.Rating
+		if season != "" {
+			previous = user.SeasonRatings[season]
+		}
+
+		updated := glicko.Update(toGlicko(previous), results)
+		rating := models.Rating{
+			Rating:      updated.Rating,
+			Deviation:   updated.Deviation,
+			Volatility:  updated.Volatility,
+			GamesPlayed: previous.GamesPlayed + 1,
+		}
+
+		saved, err := gateway.mongodb.UserRepository().UpdateRating(ctx, playerID, season, previous.GamesPlayed, rating)
+		if err != nil {
+			return models.Rating{}, err
+		}
+		if saved {
+			return rating, nil
+		}
+
+		if user, err = gateway.mongodb.UserRepository().GetByID(ctx, playerID); err != nil {
+			return models.Rating{}, err
+		}
+		if user == nil {
+			return models.Rating{}, errUserGone
+		}
+	}
+
+	return models.Rating{}, errRatingConflict
+}
+
+func toGlicko(rating models.Rating) glicko.Rating {
+	if rating.Deviation == 0 {
+		return glicko.NewRating()
+	}
+
+	return glicko.Rating{
+		Rating:     rating.Rating,
+		Deviation:  rating.Deviation,
+		Volatility: rating.Volatility,
+	}
+}
+
+func score(place int, opponentPlace int) float64 {
+	switch {
+	case place < opponentPlace:
+		return 1
+	case place > opponentPlace:
+		return 0
+	default:
+		return 0.5
+	}
+}
@@ -0,0 +1,361 @@
+package game
+
+import (
+	"ais-summoner/internal/models"
+	"ais-summoner/internal/pkg/geometry"
+	"bytes"
+	"compress/gzip"
+	"encoding/binary"
+	"errors"
+	"io"
+	"math"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// A replay log is a gzip stream holding a header followed by records.
+//
+// The header is the magic "HREP", a version byte, the terrain the room was
+// created with as a uvarint length prefixed BSON document, and the room
+// configuration the simulation ran with. Keeping the terrain in the log
+// lets a match be replayed as it was played after its terrain is edited
+// or deleted. The version goes up whenever the format changes, and logs of
+// other versions are not played.
+//
+// Each record starts with a kind byte and the uvarint number of ticks
+// since the previous record. Joins carry the 12 byte player id and
+// implicitly assign the next player slot; leaves and inputs refer to
+// players by uvarint slot. Inputs then carry the event byte, the direction
+// as two float64 and the uvarint rewind, so a replay runs the simulation
+// with exactly the values the room used.
+
+const (
+	replayMagic   = "HREP"
+	replayVersion = 1
+)
+
+type ReplayRecordKind byte
+
+const (
+	ReplayJoin  ReplayRecordKind = 1
+	ReplayLeave ReplayRecordKind = 2
+	ReplayInput ReplayRecordKind = 3
+	ReplayEnd   ReplayRecordKind = 4
+)
+
+var errInvalidReplay = errors.New("invalid replay log")
+
+type ReplayRecord struct {
+	Tick     uint64
+	Kind     ReplayRecordKind
+	PlayerID string
+	Input    PlayerInput
+}
+
+type Replay struct {
+	Terrain *models.Terrain
+	Config  RoomConfig
+	Records []ReplayRecord
+}
+
+// ReplayRecorder appends the accepted inputs and the joins and leaves of a
+// room, stamped with the tick at which the simulation saw them.
+type ReplayRecorder struct {
+	terrain  *models.Terrain
+	config   RoomConfig
+	buffer   bytes.Buffer
+	slots    map[string]uint64
+	lastTick uint64
+}
+
+func NewReplayRecorder(terrain *models.Terrain, config RoomConfig) *ReplayRecorder {
+	return &ReplayRecorder{
+		terrain: terrain,
+		config:  config,
+		slots:   make(map[string]uint64),
+	}
+}
+
+func (recorder *ReplayRecorder) Join(tick uint64, playerID string) {
+	objectID, err := primitive.ObjectIDFromHex(playerID)
+	if err != nil {
+		return
+	}
+
+	recorder.writeHeader(ReplayJoin, tick)
+	recorder.buffer.Write(objectID[:])
+	recorder.slots[playerID] = uint64(len(recorder.slots))
+}
+
+func (recorder *ReplayRecorder) Leave(tick uint64, playerID string) {
+	slot, exists := recorder.slots[playerID]
+	if !exists {
+		return
+	}
+
+	recorder.writeHeader(ReplayLeave, tick)
+	recorder.writeUvarint(slot)
+}
+
+func (recorder *ReplayRecorder) Input(tick uint64, input PlayerInput) {
+	slot, exists := recorder.slots[input.PlayerID]
+	if !exists {
+		return
+	}
+
+	recorder.writeHeader(ReplayInput, tick)
+	recorder.writeUvarint(slot)
+	recorder.buffer.WriteByte(byte(input.Event))
+	recorder.writeFloat(input.Direction.X)
+	recorder.writeFloat(input.Direction.Y)
+	recorder.writeUvarint(input.Rewind)
+}
+
+func (recorder *ReplayRecorder) End(tick uint64) {
+	recorder.writeHeader(ReplayEnd, tick)
+}
+
+// Bytes returns the compressed log recorded so far.
+func (recorder *ReplayRecorder) Bytes() ([]byte, error) {
+	document, err := bson.Marshal(recorder.terrain)
+	if err != nil {
+		return nil, err
+	}
+
+	var header bytes.Buffer
+	header.WriteString(replayMagic)
+	header.WriteByte(replayVersion)
+	header.Write(binary.AppendUvarint(nil, uint64(len(document))))
+	header.Write(document)
+	for _, value := range []uint64{
+		uint64(recorder.config.TickRate),
+		math.Float64bits(recorder.config.MoveSpeed),
+		math.Float64bits(recorder.config.DashDistance),
+		uint64(recorder.config.DashCooldown),
+		math.Float64bits(recorder.config.DashDamage),
+		math.Float64bits(recorder.config.HitRadius),
+		math.Float64bits(recorder.config.PlayerRadius),
+		math.Float64bits(recorder.config.MaxHealth),
+		uint64(recorder.config.MaxRewind),
+	} {
+		header.Write(binary.LittleEndian.AppendUint64(nil, value))
+	}
+
+	var compressed bytes.Buffer
+	writer := gzip.NewWriter(&compressed)
+	if _, err := writer.Write(header.Bytes()); err != nil {
+		return nil, err
+	}
+	if _, err := writer.Write(recorder.buffer.Bytes()); err != nil {
+		return nil, err
+	}
+	if err := writer.Close(); err != nil {
+		return nil, err
+	}
+
+	return compressed.Bytes(), nil
+}
+
+func (recorder *ReplayRecorder) writeHeader(kind ReplayRecordKind, tick uint64) {
+	recorder.buffer.WriteByte(byte(kind))
+	recorder.writeUvarint(tick - recorder.lastTick)
+	recorder.lastTick = tick
+}
+
+func (recorder *ReplayRecorder) writeUvarint(value uint64) {
+	recorder.buffer.Write(binary.AppendUvarint(nil, value))
+}
+
+func (recorder *ReplayRecorder) writeFloat(value float64) {
+	recorder.buffer.Write(binary.LittleEndian.AppendUint64(nil, math.Float64bits(value)))
+}
+
+// DecodeReplay parses a compressed replay log.
+func DecodeReplay(data []byte) (*Replay, error) {
+	reader, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	raw, err := io.ReadAll(reader)
+	if err != nil {
+		return nil, err
+	}
+
+	decoder := &replayDecoder{data: raw}
+	if string(decoder.next(len(replayMagic))) != replayMagic {
+		return nil, errInvalidReplay
+	}
+	if decoder.byte() != replayVersion {
+		return nil, errInvalidReplay
+	}
+
+	length := decoder.uvarint()
+	if decoder.err != nil || length > uint64(len(decoder.data)-decoder.offset) {
+		return nil, errInvalidReplay
+	}
+	replay := &Replay{Terrain: &models.Terrain{}}
+	if err := bson.Unmarshal(decoder.next(int(length)), replay.Terrain); err != nil {
+		return nil, errInvalidReplay
+	}
+
+	replay.Config = RoomConfig{
+		TickRate:     int(decoder.uint64()),
+		MoveSpeed:    decoder.float(),
+		DashDistance: decoder.float(),
+		DashCooldown: time.Duration(decoder.uint64()),
+		DashDamage:   decoder.float(),
+		HitRadius:    decoder.float(),
+		PlayerRadius: decoder.float(),
+		MaxHealth:    decoder.float(),
+		MaxRewind:    time.Duration(decoder.uint64()),
+	}
+
+	var tick uint64
+	var players []string
+	for decoder.err == nil && decoder.offset < len(decoder.data) {
+		record := ReplayRecord{Kind: ReplayRecordKind(decoder.byte())}
+		tick += decoder.uvarint()
+		record.Tick = tick
+
+		switch record.Kind {
+		case ReplayJoin:
+			var objectID primitive.ObjectID
+			copy(objectID[:], decoder.next(12))
+			record.PlayerID = objectID.Hex()
+			players = append(players, record.PlayerID)
+		case ReplayLeave, ReplayInput:
+			slot := decoder.uvarint()
+			if slot >= uint64(len(players)) {
+				return nil, errInvalidReplay
+			}
+			record.PlayerID = players[slot]
+			if record.Kind == ReplayInput {
+				record.Input = PlayerInput{
+					PlayerID:  record.PlayerID,
+					Event:     GameEvent(decoder.byte()),
+					Direction: models.Vector2{X: decoder.float(), Y: decoder.float()},
+					Rewind:    decoder.uvarint(),
+				}
+			}
+		case ReplayEnd:
+		default:
+			return nil, errInvalidReplay
+		}
+
+		replay.Records = append(replay.Records, record)
+	}
+
+	if decoder.err != nil || replay.Config.TickRate <= 0 {
+		return nil, errInvalidReplay
+	}
+
+	return replay, nil
+}
+
+type replayDecoder struct {
+	data   []byte
+	offset int
+	err    error
+}
+
+func (decoder *replayDecoder) next(n int) []byte {
+	if decoder.err != nil || decoder.offset+n > len(decoder.data) {
+		decoder.err = errInvalidReplay
+		return make([]byte, n)
+	}
+
+	chunk := decoder.data[decoder.offset : decoder.offset+n]
+	decoder.offset += n
+	return chunk
+}
+
+func (decoder *replayDecoder) byte() byte {
+	return decoder.next(1)[0]
+}
+
+func (decoder *replayDecoder) uint64() uint64 {
+	return binary.LittleEndian.Uint64(decoder.next(8))
+}
+
+func (decoder *replayDecoder) float() float64 {
+	return math.Float64frombits(decoder.uint64())
+}
+
+func (decoder *replayDecoder) uvarint() uint64 {
+	if decoder.err != nil {
+		return 0
+	}
+
+	value, n := binary.Uvarint(decoder.data[decoder.offset:])
+	if n <= 0 {
+		decoder.err = errInvalidReplay
+		return 0
+	}
+
+	decoder.offset += n
+	return value
+}
+
+// ReplayPlayer rebuilds the state sequence of a recorded room by feeding
+// its records into the same simulation step the room ran.
+type ReplayPlayer struct {
+	replay     *Replay
+	simulation *Simulation
+	stats      map[string]*models.PlayerStats
+	next       int
+	ended      bool
+}
+
+// NewReplayPlayer plays the replay on the terrain recorded with it.
+func NewReplayPlayer(replay *Replay) *ReplayPlayer {
+	return &ReplayPlayer{
+		replay:     replay,
+		simulation: NewSimulation(replay.Config, terrainSpawner(replay.Terrain), geometry.NewTerrainCollider(replay.Terrain)),
+		stats:      make(map[string]*models.PlayerStats),
+	}
+}
+
+func (player *ReplayPlayer) Config() RoomConfig {
+	return player.replay.Config
+}
+
+// Step applies the records of the current tick, advances the simulation
+// and returns the resulting state. It returns false once the log ends.
+func (player *ReplayPlayer) Step(now time.Time) (*GameState, bool) {
+	if player.ended {
+		return nil, false
+	}
+
+	tick := player.simulation.Tick()
+	for player.next < len(player.replay.Records) && player.replay.Records[player.next].Tick <= tick {
+		record := player.replay.Records[player.next]
+		player.next++
+
+		switch record.Kind {
+		case ReplayJoin:
+			stats, exists := player.stats[record.PlayerID]
+			if !exists {
+				stats = &models.PlayerStats{}
+				player.stats[record.PlayerID] = stats
+			}
+			player.simulation.AddPlayer(&Player{ID: record.PlayerID}, stats)
+		case ReplayLeave:
+			player.simulation.RemovePlayer(record.PlayerID)
+		case ReplayInput:
+			player.simulation.Apply(record.Input)
+		case ReplayEnd:
+			player.ended = true
+			return nil, false
+		}
+	}
+
+	if player.next >= len(player.replay.Records) {
+		player.ended = true
+		return nil, false
+	}
+
+	player.simulation.Step()
+	return player.simulation.State(now), true
+}
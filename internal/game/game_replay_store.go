@@ -0,0 +1,72 @@
+package game
+
+import (
+	"ais-summoner/internal/database"
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// ReplayStore persists replay logs by match id. Load returns nil when the
+// match has no replay.
+type ReplayStore interface {
+	Save(ctx context.Context, matchID string, data []byte) error
+	Load(ctx context.Context, matchID string) ([]byte, error)
+}
+
+// NewReplayStore keeps replays in MongoDB GridFS, or in the directory named
+// by REPLAY_DIR when it is set.
+func NewReplayStore(mongodb *database.MongoDB) ReplayStore {
+	if dir := os.Getenv("REPLAY_DIR"); dir != "" {
+		return NewFileReplayStore(dir)
+	}
+
+	return mongodb.ReplayRepository()
+}
+
+// FileReplayStore keeps one file per replay in a local directory.
+type FileReplayStore struct {
+	dir string
+}
+
+func NewFileReplayStore(dir string) *FileReplayStore {
+	return &FileReplayStore{dir: dir}
+}
+
+func (store *FileReplayStore) Save(ctx context.Context, matchID string, data []byte) error {
+	path, err := store.path(matchID)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(store.dir, 0o755); err != nil {
+		return err
+	}
+
+	return os.WriteFile(path, data, 0o644)
+}
+
+func (store *FileReplayStore) Load(ctx context.Context, matchID string) ([]byte, error) {
+	path, err := store.path(matchID)
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+
+	return data, err
+}
+
+// path only accepts match ids so a request cannot escape the directory.
+func (store *FileReplayStore) path(matchID string) (string, error) {
+	if !primitive.IsValidObjectID(matchID) {
+		return "", errors.New("invalid match id")
+	}
+
+	return filepath.Join(store.dir, matchID+".replay"), nil
+}
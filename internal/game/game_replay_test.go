@@ -0,0 +1,77 @@
+package game
+
+import (
+	"ais-summoner/internal/models"
+	"bytes"
+	"compress/gzip"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestReplayKeepsItsTerrain(t *testing.T) {
+	terrain := squareTerrain()
+	terrain.Obstacles = [][]models.Vector2{{{X: 2, Y: -2}, {X: 4, Y: -2}, {X: 4, Y: 2}, {X: 2, Y: 2}}}
+	terrain.SpawnPoints = []models.SpawnPoint{{}}
+	config := DefaultRoomConfig()
+	playerID := primitive.NewObjectID().Hex()
+
+	recorder := NewReplayRecorder(terrain, config)
+	recorder.Join(0, playerID)
+	recorder.Input(1, PlayerInput{PlayerID: playerID, Event: PlayerMove, Direction: models.Vector2{X: 1}, Rewind: 2})
+	recorder.End(40)
+
+	data, err := recorder.Bytes()
+	if err != nil {
+		t.Fatalf("encoding replay: %v", err)
+	}
+
+	// Editing the terrain once the match is recorded changes nothing.
+	terrain.Obstacles = nil
+
+	replay, err := DecodeReplay(data)
+	if err != nil {
+		t.Fatalf("decoding replay: %v", err)
+	}
+	if replay.Terrain.ID != terrain.ID || len(replay.Terrain.Boundary) != 4 || len(replay.Terrain.Obstacles) != 1 {
+		t.Fatalf("got terrain %+v", replay.Terrain)
+	}
+	// Only what the simulation runs with is recorded.
+	want := config
+	want.MatchDuration, want.FullSnapshotInterval, want.ResumeGrace = 0, 0, 0
+	if replay.Config != want {
+		t.Fatalf("got config %+v, want %+v", replay.Config, want)
+	}
+	if len(replay.Records) != 3 || replay.Records[1].Input.Rewind != 2 || replay.Records[2].Tick != 40 {
+		t.Fatalf("got records %+v", replay.Records)
+	}
+
+	// The player runs into the recorded obstacle and stops in front of it.
+	player := NewReplayPlayer(replay)
+	var last *GameState
+	for {
+		state, ok := player.Step(time.Unix(0, 0))
+		if !ok {
+			break
+		}
+		last = state
+	}
+	if last == nil || len(last.Players) != 1 {
+		t.Fatalf("got final state %+v", last)
+	}
+	if x := last.Players[0].Position.X; x > 2-config.PlayerRadius+1e-6 {
+		t.Fatalf("player went through the obstacle to x %v", x)
+	}
+}
+
+func TestDecodeReplayRejectsOtherVersions(t *testing.T) {
+	var compressed bytes.Buffer
+	writer := gzip.NewWriter(&compressed)
+	writer.Write([]byte(replayMagic + "\x02"))
+	writer.Close()
+
+	if _, err := DecodeReplay(compressed.Bytes()); err != errInvalidReplay {
+		t.Fatalf("got %v for a version 2 log, want errInvalidReplay", err)
+	}
+}
@@ -0,0 +1,418 @@
+package game
+
+import (
+	"ais-summoner/internal/models"
+	"ais-summoner/internal/pkg/geometry"
+	"ais-summoner/internal/pkg/navmesh"
+	"sync"
+	"time"
+)
+
+type Player struct {
+	ID        string         `json:"id"`
+	Position  models.Vector3 `json:"position"`
+	Direction models.Vector2 `json:"direction"`
+	Health    float64        `json:"health"`
+
+	stats        *models.PlayerStats
+	dash         models.Vector2
+	dashQueued   bool
+	dashCooldown int
+	dashRewind   uint64
+	spawnedAt    uint64
+}
+
+// RoomSnapshot is sent to a client joining or resuming a room. ResumeToken
+// lets the client take its slot back after its socket drops.
+type RoomSnapshot struct {
+	RoomID      string    `json:"roomId"`
+	TerrainID   string    `json:"terrainId"`
+	Players     []*Player `json:"players"`
+	ResumeToken string    `json:"resumeToken,omitempty"`
+}
+
+// GameRoom groups the clients playing on the same terrain. Rooms are
+// created, looked up and torn down by the GameGateway, and each one runs
+// its own fixed-rate simulation loop.
+type GameRoom struct {
+	id         string
+	terrain    *models.Terrain
+	config     RoomConfig
+	clock      Clock
+	players    map[*GameClient]*Player
+	stats      map[string]*models.PlayerStats
+	bots       map[string]bool
+	acks       map[string]uint32
+	snapshots  map[*GameClient]*snapshotHistory
+	sessions   map[string]string
+	suspended  map[string]*suspendedPlayer
+	simulation *Simulation
+	recorder   *ReplayRecorder
+	inputs     []PlayerInput
+	match      *roomMatch
+	onMatchEnd func(room *GameRoom, outcome *MatchOutcome)
+	startedAt  time.Time
+	mutex      sync.RWMutex
+	done       chan struct{}
+	stopOnce   sync.Once
+	mesh       *navmesh.Mesh
+	meshErr    error
+	meshOnce   sync.Once
+}
+
+func NewGameRoom(id string, terrain *models.Terrain, config RoomConfig, clock Clock) *GameRoom {
+	room := &GameRoom{
+		id:        id,
+		terrain:   terrain,
+		config:    config,
+		clock:     clock,
+		players:   make(map[*GameClient]*Player),
+		stats:     make(map[string]*models.PlayerStats),
+		bots:      make(map[string]bool),
+		acks:      make(map[string]uint32),
+		snapshots: make(map[*GameClient]*snapshotHistory),
+		sessions:  make(map[string]string),
+		suspended: make(map[string]*suspendedPlayer),
+		startedAt: clock.Now(),
+		done:      make(chan struct{}),
+	}
+	room.simulation = NewSimulation(config, terrainSpawner(terrain), geometry.NewTerrainCollider(terrain))
+	room.recorder = NewReplayRecorder(terrain, config)
+
+	return room
+}
+
+func (room *GameRoom) ID() string {
+	return room.id
+}
+
+func (room *GameRoom) Terrain() *models.Terrain {
+	return room.terrain
+}
+
+// Run drives the simulation at the configured tick rate until the room is stopped.
+func (room *GameRoom) Run() {
+	ticker := room.clock.NewTicker(room.config.TickInterval())
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C():
+			room.Tick()
+		case <-room.done:
+			return
+		}
+	}
+}
+
+// Tick applies the queued inputs, advances the simulation by one step and
+// broadcasts the resulting state to every member.
+func (room *GameRoom) Tick() {
+	room.mutex.Lock()
+	defer room.mutex.Unlock()
+
+	for _, input := range room.inputs {
+		room.recorder.Input(room.simulation.Tick(), input)
+		room.simulation.Apply(input)
+		if input.Seq != 0 {
+			room.acks[input.PlayerID] = input.Seq
+		}
+	}
+	room.inputs = room.inputs[:0]
+
+	room.simulation.Step()
+
+	// Every member gets the players around them, as a delta when they
+	// acknowledge snapshots, along with their own input acknowledgement.
+	state := room.simulation.State(room.clock.Now())
+	interest := newInterestGrid(state.Players, room.config.InterestRadius)
+	fullInterval := room.config.Ticks(room.config.FullSnapshotInterval)
+	for member, player := range room.players {
+		update := room.snapshots[member].update(state, interest.visible(player), fullInterval)
+		update.LastProcessedSeq = room.acks[player.ID]
+		member.sendMessage(GameStateUpdate, update)
+	}
+
+	room.checkMatchEnd()
+}
+
+// checkMatchEnd ends a ranked match once its end condition is met. It must
+// be called with the room mutex held.
+func (room *GameRoom) checkMatchEnd() {
+	if room.match == nil || !room.match.shouldEnd(room.simulation.Tick()) {
+		return
+	}
+
+	outcome := room.finishMatch()
+	if room.onMatchEnd != nil {
+		// The handler tears the room down, which needs the room mutex, so
+		// it must not wait for that.
+		room.onMatchEnd(room, outcome)
+	}
+}
+
+// endMatch ends the ranked match of the room right away, without calling
+// onMatchEnd, and returns its outcome. It returns nil when the room has
+// no match or the match has already ended.
+func (room *GameRoom) endMatch() *MatchOutcome {
+	room.mutex.Lock()
+	defer room.mutex.Unlock()
+
+	if room.match == nil || room.match.ended {
+		return nil
+	}
+
+	return room.finishMatch()
+}
+
+// finishMatch must be called with the room mutex held.
+func (room *GameRoom) finishMatch() *MatchOutcome {
+	room.match.ended = true
+	outcome := room.match.outcome(room.id, room.kills())
+	room.broadcast(MatchEnded, outcome)
+
+	return outcome
+}
+
+// hasRunningMatch reports whether the room plays a ranked match that has
+// not ended yet.
+func (room *GameRoom) hasRunningMatch() bool {
+	room.mutex.RLock()
+	defer room.mutex.RUnlock()
+
+	return room.match != nil && !room.match.ended
+}
+
+func (room *GameRoom) stop() {
+	room.stopOnce.Do(func() {
+		close(room.done)
+	})
+}
+
+// enqueue queues an input from a member for the next tick.
+func (room *GameRoom) enqueue(client *GameClient, event GameEvent, direction models.Vector2, seq uint32) {
+	room.mutex.Lock()
+	defer room.mutex.Unlock()
+
+	if _, exists := room.players[client]; !exists {
+		return
+	}
+
+	input := PlayerInput{
+		PlayerID:  client.ID(),
+		Event:     event,
+		Direction: direction,
+		Seq:       seq,
+	}
+	if event == PlayerDash {
+		input.Rewind = room.rewindTicks(client)
+	}
+	room.inputs = append(room.inputs, input)
+}
+
+// acknowledge records the newest state update the member holds, which
+// later updates are sent as deltas against.
+func (room *GameRoom) acknowledge(client *GameClient, tick uint64) {
+	room.mutex.Lock()
+	defer room.mutex.Unlock()
+
+	if history, exists := room.snapshots[client]; exists {
+		history.acknowledge(tick)
+	}
+}
+
+// join adds the client to the room, announces it to the other members
+// and sends the joining client a snapshot of the room. Ranked rooms only
+// accept the participants of their match. A player whose socket dropped
+// gets their suspended slot back.
+func (room *GameRoom) join(client *GameClient) error {
+	room.mutex.Lock()
+	defer room.mutex.Unlock()
+
+	playerID := client.ID()
+
+	if suspended, exists := room.suspended[playerID]; exists {
+		room.reattach(client, suspended)
+		return nil
+	}
+
+	if room.match != nil {
+		if room.match.ended || !room.match.isParticipant(playerID) {
+			return ErrNotParticipant
+		}
+		room.match.playerJoined(playerID)
+	}
+
+	stats, exists := room.stats[playerID]
+	if !exists {
+		stats = &models.PlayerStats{}
+		room.stats[playerID] = stats
+	}
+
+	if client.bot != nil {
+		room.bots[playerID] = true
+	} else {
+		room.sessions[playerID] = newResumeToken(room.id)
+	}
+
+	player := &Player{ID: playerID}
+	room.recorder.Join(room.simulation.Tick(), playerID)
+	room.simulation.AddPlayer(player, stats)
+
+	joined := *player
+	room.broadcast(PlayerJoin, &joined)
+
+	room.players[client] = player
+	room.snapshots[client] = newSnapshotHistory()
+	client.room.Store(room)
+	client.sendMessage(JoinGame, room.snapshotFor(playerID))
+	return nil
+}
+
+// forfeit gives up the seat of a participant that will not join the match,
+// which counts as joining and leaving right away. A match whose other
+// players are in the room then ends instead of waiting for it.
+func (room *GameRoom) forfeit(playerID string) {
+	room.mutex.Lock()
+	defer room.mutex.Unlock()
+
+	if room.match == nil || room.match.ended || !room.match.isParticipant(playerID) || room.match.joined[playerID] {
+		return
+	}
+
+	room.match.playerJoined(playerID)
+	room.match.playerLeft(playerID)
+}
+
+// leave removes the client from the room and notifies the remaining members.
+func (room *GameRoom) leave(client *GameClient) {
+	room.mutex.Lock()
+	defer room.mutex.Unlock()
+
+	player, exists := room.players[client]
+	if !exists {
+		return
+	}
+
+	delete(room.players, client)
+	delete(room.snapshots, client)
+	client.room.Store(nil)
+	room.removePlayer(player)
+}
+
+// removePlayer takes a player that is no longer a member out of the game
+// and notifies the remaining members. It must be called with the room
+// mutex held.
+func (room *GameRoom) removePlayer(player *Player) {
+	delete(room.acks, player.ID)
+	delete(room.sessions, player.ID)
+	room.recorder.Leave(room.simulation.Tick(), player.ID)
+	room.simulation.RemovePlayer(player.ID)
+
+	room.broadcast(PlayerLeave, player)
+
+	if room.match != nil {
+		room.match.playerLeft(player.ID)
+		room.checkMatchEnd()
+	}
+}
+
+func (room *GameRoom) members() []*GameClient {
+	room.mutex.RLock()
+	defer room.mutex.RUnlock()
+
+	members := make([]*GameClient, 0, len(room.players))
+	for member := range room.players {
+		members = append(members, member)
+	}
+
+	return members
+}
+
+// isEmpty reports whether the room has neither members nor suspended
+// players.
+func (room *GameRoom) isEmpty() bool {
+	room.mutex.RLock()
+	defer room.mutex.RUnlock()
+
+	return len(room.players) == 0 && len(room.suspended) == 0
+}
+
+// hasHumans reports whether any member is a player rather than a bot.
+// Suspended players count, as they may still come back.
+func (room *GameRoom) hasHumans() bool {
+	room.mutex.RLock()
+	defer room.mutex.RUnlock()
+
+	if len(room.suspended) > 0 {
+		return true
+	}
+	for member := range room.players {
+		if member.bot == nil {
+			return true
+		}
+	}
+
+	return false
+}
+
+// navigationMesh triangulates the terrain the first time a bot needs it and
+// shares the mesh between every bot of the room.
+func (room *GameRoom) navigationMesh() (*navmesh.Mesh, error) {
+	room.meshOnce.Do(func() {
+		room.mesh, room.meshErr = navmesh.NewMesh(room.terrain)
+	})
+
+	return room.mesh, room.meshErr
+}
+
+// snapshotFor returns the snapshot of the room along with the resume token
+// of the player. It must be called with the room mutex held.
+func (room *GameRoom) snapshotFor(playerID string) *RoomSnapshot {
+	snapshot := room.snapshot()
+	snapshot.ResumeToken = room.sessions[playerID]
+	return snapshot
+}
+
+// snapshot lists the members and the suspended players, who are still in
+// the game. It must be called with the room mutex held.
+func (room *GameRoom) snapshot() *RoomSnapshot {
+	players := make([]*Player, 0, len(room.players)+len(room.suspended))
+	for _, player := range room.players {
+		copied := *player
+		players = append(players, &copied)
+	}
+	for _, suspended := range room.suspended {
+		copied := *suspended.player
+		players = append(players, &copied)
+	}
+
+	return &RoomSnapshot{
+		RoomID:    room.id,
+		TerrainID: room.terrain.ID.Hex(),
+		Players:   players,
+	}
+}
+
+// terrainSpawner cycles through the spawn points of the rotated terrain in
+// order, so a replay spawns players exactly where the room did. Terrains
+// without spawn points place every player at their fallback spawn.
+// Terrain points live on the horizontal plane, so Y maps to the Z axis.
+func terrainSpawner(terrain *models.Terrain) func() models.Vector3 {
+	shape := geometry.NewShape(terrain)
+	if len(shape.SpawnPoints) == 0 {
+		point, _ := shape.FallbackSpawn()
+		spawn := models.Vector3{X: point.X, Z: point.Y}
+
+		return func() models.Vector3 {
+			return spawn
+		}
+	}
+
+	next := 0
+	return func() models.Vector3 {
+		spawn := shape.SpawnPoints[next%len(shape.SpawnPoints)]
+		next++
+		return spawn.Position
+	}
+}
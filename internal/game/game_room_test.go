@@ -0,0 +1,53 @@
+package game
+
+import (
+	"ais-summoner/internal/models"
+	"testing"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestRoomTicksOnItsClock(t *testing.T) {
+	clock := newManualClock()
+	config := DefaultRoomConfig()
+	room := NewGameRoom(primitive.NewObjectID().Hex(), squareTerrain(), config, clock)
+	client, inbox := newTestClient(nil, "player")
+	if err := room.join(client); err != nil {
+		t.Fatalf("join: %v", err)
+	}
+	inbox.expect(t, JoinGame, nil)
+
+	go room.Run()
+	defer room.stop()
+	clock.waitForTickers(t, 1)
+
+	// Nothing happens until a whole tick interval has passed.
+	clock.Advance(config.TickInterval() / 2)
+	inbox.expectNone(t)
+
+	room.enqueue(client, PlayerMove, models.Vector2{X: 1}, 1)
+	clock.Advance(config.TickInterval() - config.TickInterval()/2)
+
+	var state GameState
+	inbox.expect(t, GameStateUpdate, &state)
+	if state.Tick != 1 || state.Timestamp != clock.Now().UnixMilli() {
+		t.Fatalf("got tick %d at %d, want tick 1 at %d", state.Tick, state.Timestamp, clock.Now().UnixMilli())
+	}
+	if state.LastProcessedSeq != 1 {
+		t.Fatalf("got last processed seq %d, want 1", state.LastProcessedSeq)
+	}
+
+	step := config.MoveSpeed / float64(config.TickRate)
+	if len(state.Players) != 1 || state.Players[0].Position.X != step {
+		t.Fatalf("got players %+v, want one moved by %v", state.Players, step)
+	}
+
+	for tick := uint64(2); tick <= 4; tick++ {
+		clock.Advance(config.TickInterval())
+		inbox.expect(t, GameStateUpdate, &state)
+		if state.Tick != tick {
+			t.Fatalf("got tick %d, want %d", state.Tick, tick)
+		}
+	}
+	inbox.expectNone(t)
+}
@@ -0,0 +1,141 @@
+package game
+
+import (
+	"errors"
+	"sync"
+)
+
+// sendQueueLimit is how many messages may wait for a client before it is
+// considered too slow and disconnected.
+const sendQueueLimit = 256
+
+var errSendQueueFull = errors.New("send queue is full")
+
+// sendQueue holds the messages waiting to be written to a client. Messages
+// pushed as coalescible, the state updates, replace the one still queued
+// so a slow client skips stale states; every other message is kept in
+// order. The queue is closed once, with the close code and reason the
+// transport should end with.
+type sendQueue struct {
+	messages    []queuedMessage
+	limit       int
+	closed      bool
+	closeCode   int
+	closeReason string
+	// ready is signalled whenever messages are pushed or the queue is
+	// closed. Readers then take everything at once.
+	ready chan struct{}
+	mutex sync.Mutex
+}
+
+type queuedMessage struct {
+	data     []byte
+	coalesce bool
+}
+
+func newSendQueue(limit int) *sendQueue {
+	return &sendQueue{
+		limit: limit,
+		ready: make(chan struct{}, 1),
+	}
+}
+
+// push queues a message, reporting whether it replaced an older
+// coalescible one. It fails with errSendQueueFull when the queue is full
+// of messages that must be kept. Pushing to a closed queue does nothing.
+func (queue *sendQueue) push(data []byte, coalesce bool) (bool, error) {
+	queue.mutex.Lock()
+	defer queue.mutex.Unlock()
+
+	if queue.closed {
+		return false, nil
+	}
+
+	replaced := false
+	if coalesce {
+		for i, message := range queue.messages {
+			if message.coalesce {
+				// The newer state goes last so it never precedes the
+				// messages queued before it, like the join of a player
+				// it shows.
+				queue.messages = append(queue.messages[:i], queue.messages[i+1:]...)
+				replaced = true
+				break
+			}
+		}
+	}
+	if len(queue.messages) >= queue.limit {
+		return replaced, errSendQueueFull
+	}
+
+	queue.messages = append(queue.messages, queuedMessage{data: data, coalesce: coalesce})
+	queue.signal()
+	return replaced, nil
+}
+
+// take removes every queued message. It reports false once the queue is
+// closed.
+func (queue *sendQueue) take() ([][]byte, bool) {
+	queue.mutex.Lock()
+	defer queue.mutex.Unlock()
+
+	messages := make([][]byte, len(queue.messages))
+	for i, message := range queue.messages {
+		messages[i] = message.data
+	}
+	queue.messages = queue.messages[:0]
+
+	return messages, !queue.closed
+}
+
+// close lets the messages already queued be written, then ends the
+// transport with the close code and reason. Only the first close counts.
+func (queue *sendQueue) close(code int, reason string) {
+	queue.mutex.Lock()
+	defer queue.mutex.Unlock()
+
+	if queue.closed {
+		return
+	}
+
+	queue.closed = true
+	queue.closeCode, queue.closeReason = code, reason
+	queue.signal()
+}
+
+// abort closes the queue dropping every message still queued, so the
+// transport is closed right away.
+func (queue *sendQueue) abort(code int, reason string) {
+	queue.mutex.Lock()
+	defer queue.mutex.Unlock()
+
+	if queue.closed {
+		return
+	}
+
+	queue.messages = nil
+	queue.closed = true
+	queue.closeCode, queue.closeReason = code, reason
+	queue.signal()
+}
+
+// closeStatus returns the close code and reason the queue was closed with,
+// or a normal closure while it is open.
+func (queue *sendQueue) closeStatus() (int, string) {
+	queue.mutex.Lock()
+	defer queue.mutex.Unlock()
+
+	if !queue.closed {
+		return CloseNormal, ""
+	}
+
+	return queue.closeCode, queue.closeReason
+}
+
+// signal must be called with the queue mutex held.
+func (queue *sendQueue) signal() {
+	select {
+	case queue.ready <- struct{}{}:
+	default:
+	}
+}
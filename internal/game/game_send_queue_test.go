@@ -0,0 +1,157 @@
+package game
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func queued(messages [][]byte) []string {
+	names := make([]string, len(messages))
+	for i, message := range messages {
+		names[i] = string(message)
+	}
+	return names
+}
+
+func expectTaken(t *testing.T, queue *sendQueue, want []string, wantOpen bool) {
+	t.Helper()
+
+	messages, open := queue.take()
+	if got := queued(messages); fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Fatalf("took %v, want %v", got, want)
+	}
+	if open != wantOpen {
+		t.Fatalf("take reported open %v, want %v", open, wantOpen)
+	}
+}
+
+func TestSendQueueKeepsOrderAndCoalescesStates(t *testing.T) {
+	queue := newSendQueue(8)
+
+	for _, push := range []struct {
+		data         string
+		coalesce     bool
+		wantReplaced bool
+	}{
+		{"join", false, false},
+		{"state 1", true, false},
+		{"chat", false, false},
+		{"state 2", true, true},
+		{"state 3", true, true},
+	} {
+		replaced, err := queue.push([]byte(push.data), push.coalesce)
+		if err != nil {
+			t.Fatalf("pushing %s: %v", push.data, err)
+		}
+		if replaced != push.wantReplaced {
+			t.Fatalf("pushing %s replaced %v, want %v", push.data, replaced, push.wantReplaced)
+		}
+	}
+
+	select {
+	case <-queue.ready:
+	default:
+		t.Fatal("queue not signalled after pushing")
+	}
+
+	// The newest state goes after the messages queued before it.
+	expectTaken(t, queue, []string{"join", "chat", "state 3"}, true)
+	expectTaken(t, queue, []string{}, true)
+}
+
+func TestSendQueueLimit(t *testing.T) {
+	queue := newSendQueue(3)
+
+	for i := 0; i < 3; i++ {
+		if _, err := queue.push([]byte(fmt.Sprint(i)), false); err != nil {
+			t.Fatalf("pushing %d: %v", i, err)
+		}
+	}
+	if _, err := queue.push([]byte("3"), false); !errors.Is(err, errSendQueueFull) {
+		t.Fatalf("pushing past the limit returned %v, want errSendQueueFull", err)
+	}
+
+	// A state replacing the queued one fits a full queue.
+	queue = newSendQueue(2)
+	queue.push([]byte("join"), false)
+	queue.push([]byte("state 1"), true)
+	if replaced, err := queue.push([]byte("state 2"), true); err != nil || !replaced {
+		t.Fatalf("replacing a state in a full queue returned %v, %v", replaced, err)
+	}
+	expectTaken(t, queue, []string{"join", "state 2"}, true)
+}
+
+func TestSendQueueClose(t *testing.T) {
+	queue := newSendQueue(8)
+	if code, _ := queue.closeStatus(); code != CloseNormal {
+		t.Fatalf("open queue has close code %d", code)
+	}
+
+	queue.push([]byte("goodbye"), false)
+	queue.close(CloseServiceRestart, "server restarting")
+	queue.close(CloseNormal, "")
+
+	if replaced, err := queue.push([]byte("late"), false); replaced || err != nil {
+		t.Fatalf("pushing to a closed queue returned %v, %v", replaced, err)
+	}
+	expectTaken(t, queue, []string{"goodbye"}, false)
+
+	if code, reason := queue.closeStatus(); code != CloseServiceRestart || reason != "server restarting" {
+		t.Fatalf("close status %d %q, want the first close", code, reason)
+	}
+}
+
+func TestSendQueueAbort(t *testing.T) {
+	queue := newSendQueue(8)
+	queue.push([]byte("state"), true)
+	queue.push([]byte("chat"), false)
+
+	queue.abort(CloseSlowConsumer, "client too slow")
+	expectTaken(t, queue, []string{}, false)
+
+	if code, _ := queue.closeStatus(); code != CloseSlowConsumer {
+		t.Fatalf("aborted queue has close code %d, want %d", code, CloseSlowConsumer)
+	}
+
+	// Aborting a closed queue neither drops its messages nor changes the
+	// close code.
+	queue = newSendQueue(8)
+	queue.push([]byte("goodbye"), false)
+	queue.close(CloseNormal, "")
+	queue.abort(CloseSlowConsumer, "client too slow")
+	expectTaken(t, queue, []string{"goodbye"}, false)
+	if code, _ := queue.closeStatus(); code != CloseNormal {
+		t.Fatalf("abort after close changed the close code to %d", code)
+	}
+}
+
+func TestSlowReaderIsDisconnected(t *testing.T) {
+	clock := newManualClock()
+	gateway := newGameGateway(NewInMemoryCluster(clock), NewInMemoryMatchQueue(clock), clock)
+
+	server, far := NewPipe()
+	client := newGameClient(gateway, JSONCodec{}, server)
+	client.setID(primitive.NewObjectID().Hex())
+	go client.Write()
+
+	// The reader never reads, so the pipe, the batch Write holds and then
+	// the queue fill up. States coalesce rather than count against it.
+	for i := 0; i < 4*(sendQueueLimit+pipeBuffer); i++ {
+		client.sendMessage(GameStateUpdate, &GameState{})
+		client.sendMessage(Error, NewGameError(CodeInternal, "filler").Payload())
+	}
+
+	peer := &testPeer{transport: far, codec: JSONCodec{}}
+	peer.expectClose(t, CloseSlowConsumer)
+
+	stats := client.Stats()
+	if stats.DroppedOut != 1 {
+		t.Fatalf("dropped %d messages, want the one that overflowed", stats.DroppedOut)
+	}
+	if stats.SkippedOut == 0 {
+		t.Fatal("no state updates skipped")
+	}
+}
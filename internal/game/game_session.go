@@ -0,0 +1,230 @@
+package game
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"strings"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// maxMissedMessages bounds how many messages are kept for a suspended
+// player. A player that missed more only gets a fresh snapshot on resume.
+const maxMissedMessages = 64
+
+// suspendedPlayer is the slot a player keeps in the room while their socket
+// is gone. The player stays in the simulation, standing still, and the
+// messages that cannot be recovered from a snapshot are kept for them.
+type suspendedPlayer struct {
+	player     *Player
+	inputSeq   uint32
+	missed     []missedMessage
+	overflowed bool
+}
+
+type missedMessage struct {
+	event   GameEvent
+	payload interface{}
+}
+
+// newResumeToken creates the token a member of the room resumes their
+// session with. It starts with the room id so that any instance can tell
+// where the session lives.
+func newResumeToken(roomID string) string {
+	secret := make([]byte, 16)
+	if _, err := rand.Read(secret); err != nil {
+		panic(err)
+	}
+
+	return roomID + "." + hex.EncodeToString(secret)
+}
+
+// resumeTokenRoom returns the id of the room a resume token belongs to, or
+// false when the token is malformed.
+func resumeTokenRoom(token string) (string, bool) {
+	roomID, secret, found := strings.Cut(token, ".")
+	if !found || !primitive.IsValidObjectID(roomID) || len(secret) != 32 {
+		return "", false
+	}
+	if _, err := hex.DecodeString(secret); err != nil {
+		return "", false
+	}
+
+	return roomID, true
+}
+
+// broadcast sends a message every member must see to the connected members
+// and keeps it for the suspended ones. It must be called with the room
+// mutex held, and the payload must not change afterwards.
+func (room *GameRoom) broadcast(event GameEvent, payload interface{}) {
+	for member := range room.players {
+		member.sendMessage(event, payload)
+	}
+
+	for _, suspended := range room.suspended {
+		if len(suspended.missed) == maxMissedMessages {
+			suspended.missed, suspended.overflowed = nil, true
+		}
+		if !suspended.overflowed {
+			suspended.missed = append(suspended.missed, missedMessage{event: event, payload: payload})
+		}
+	}
+}
+
+// suspend detaches the client from the room but keeps its player for a
+// later resume, returning the slot it keeps. Players of a match that has
+// ended are not kept.
+func (room *GameRoom) suspend(client *GameClient) *suspendedPlayer {
+	room.mutex.Lock()
+	defer room.mutex.Unlock()
+
+	player, exists := room.players[client]
+	if !exists || (room.match != nil && room.match.ended) {
+		return nil
+	}
+
+	delete(room.players, client)
+	delete(room.snapshots, client)
+	client.room.Store(nil)
+
+	// Stopping the player goes through the inputs so replays see it too.
+	room.inputs = append(room.inputs, PlayerInput{PlayerID: player.ID, Event: PlayerMove})
+	suspended := &suspendedPlayer{player: player, inputSeq: client.inputSeq}
+	room.suspended[player.ID] = suspended
+
+	return suspended
+}
+
+// resume hands the suspended slot of the client's player over to the
+// client. The token must be the one issued for the player.
+func (room *GameRoom) resume(client *GameClient, token string) error {
+	room.mutex.Lock()
+	defer room.mutex.Unlock()
+
+	issued, exists := room.sessions[client.ID()]
+	if !exists || subtle.ConstantTimeCompare([]byte(issued), []byte(token)) != 1 {
+		return ErrSessionExpired
+	}
+
+	suspended, exists := room.suspended[client.ID()]
+	if !exists {
+		return ErrSessionExpired
+	}
+
+	room.reattach(client, suspended)
+	return nil
+}
+
+// reattach puts the client in the slot of its suspended player, replays
+// the messages it missed, or just the snapshot when too many were missed,
+// and sends it the room snapshot. The next state update it gets is a full
+// one. It must be called with the room mutex held.
+func (room *GameRoom) reattach(client *GameClient, suspended *suspendedPlayer) {
+	delete(room.suspended, client.ID())
+
+	room.players[client] = suspended.player
+	room.snapshots[client] = newSnapshotHistory()
+	client.room.Store(room)
+	client.inputSeq = suspended.inputSeq
+
+	if !suspended.overflowed {
+		for _, message := range suspended.missed {
+			client.sendMessage(message.event, message.payload)
+		}
+	}
+	client.sendMessage(JoinGame, room.snapshotFor(client.ID()))
+}
+
+// expire removes the player of a slot that was not resumed in time, and
+// reports whether it did. A slot that was resumed is left alone, even if
+// the player was suspended again since.
+func (room *GameRoom) expire(suspended *suspendedPlayer) bool {
+	room.mutex.Lock()
+	defer room.mutex.Unlock()
+
+	playerID := suspended.player.ID
+	if room.suspended[playerID] != suspended {
+		return false
+	}
+
+	delete(room.suspended, playerID)
+	room.removePlayer(suspended.player)
+	return true
+}
+
+// dropClient handles a socket that went away. Players keep their slot for
+// the resume grace period; bots, and everyone when resuming is disabled,
+// leave the room right away.
+func (gateway *GameGateway) dropClient(client *GameClient) {
+	gateway.mutex.Lock()
+	room := client.room.Load()
+	var suspended *suspendedPlayer
+	if room != nil && client.bot == nil && gateway.config.ResumeGrace > 0 {
+		suspended = room.suspend(client)
+	}
+	gateway.mutex.Unlock()
+
+	if suspended == nil {
+		gateway.leaveRoom(client)
+		return
+	}
+
+	gateway.clock.AfterFunc(gateway.config.ResumeGrace, func() {
+		gateway.expireSession(room, suspended)
+	})
+	gateway.logger.Printf("Player %s suspended in room %s", client.ID(), room.id)
+}
+
+// resumeSession moves the client back into the slot it held when its
+// previous socket dropped. Sessions hosted by another instance yield a
+// RoomRedirect.
+func (gateway *GameGateway) resumeSession(client *GameClient, token string) error {
+	if client.room.Load() != nil {
+		return ErrAlreadyInRoom
+	}
+
+	roomID, valid := resumeTokenRoom(token)
+	if !valid {
+		return ErrSessionExpired
+	}
+
+	room := gateway.FindRoom(roomID)
+	if room == nil {
+		if err := gateway.locateRoom(roomID); err != ErrRoomNotFound {
+			return err
+		}
+		return ErrSessionExpired
+	}
+
+	gateway.mutex.Lock()
+	defer gateway.mutex.Unlock()
+
+	if gateway.rooms[room.id] != room {
+		return ErrSessionExpired
+	}
+	if client.room.Load() != nil {
+		return ErrAlreadyInRoom
+	}
+
+	if err := room.resume(client, token); err != nil {
+		return err
+	}
+
+	gateway.logger.Printf("Player %s resumed in room %s", client.ID(), room.id)
+	return nil
+}
+
+// expireSession removes a player whose grace period ran out, closing the
+// room when nobody else is left.
+func (gateway *GameGateway) expireSession(room *GameRoom, suspended *suspendedPlayer) {
+	gateway.mutex.Lock()
+	defer gateway.mutex.Unlock()
+
+	if gateway.rooms[room.id] != room || !room.expire(suspended) {
+		return
+	}
+
+	gateway.logger.Printf("Session of player %s in room %s expired", suspended.player.ID, room.id)
+	gateway.tidyRoom(room, true)
+}
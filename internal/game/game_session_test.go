@@ -0,0 +1,50 @@
+package game
+
+import (
+	"testing"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestSuspendedSessionsExpireOnTheClock(t *testing.T) {
+	clock := newManualClock()
+	gateway := newGameGateway(NewInMemoryCluster(clock), NewInMemoryMatchQueue(clock), clock)
+	grace := gateway.config.ResumeGrace
+	room := gateway.CreateRoom(squareTerrain())
+	defer room.stop()
+
+	playerID := primitive.NewObjectID().Hex()
+	client, inbox := newTestClient(gateway, playerID)
+	if err := gateway.joinRoom(client, room.ID(), ""); err != nil {
+		t.Fatalf("join: %v", err)
+	}
+	var snapshot RoomSnapshot
+	inbox.expect(t, JoinGame, &snapshot)
+
+	// A player that comes back within the grace period keeps its slot.
+	gateway.dropClient(client)
+	clock.Advance(grace - 1)
+	client, inbox = newTestClient(gateway, playerID)
+	if err := gateway.resumeSession(client, snapshot.ResumeToken); err != nil {
+		t.Fatalf("resuming within the grace period: %v", err)
+	}
+	inbox.expect(t, JoinGame, nil)
+
+	// The timer of the first drop does not cut the resumed session short.
+	clock.Advance(1)
+	if gateway.FindRoom(room.ID()) == nil {
+		t.Fatal("room closed under a resumed player")
+	}
+
+	// Once the grace period of a drop runs out the player is gone, and
+	// with it the room.
+	gateway.dropClient(client)
+	clock.Advance(grace)
+	client, _ = newTestClient(gateway, playerID)
+	if err := gateway.resumeSession(client, snapshot.ResumeToken); err != ErrSessionExpired {
+		t.Fatalf("got %v resuming after the grace period, want ErrSessionExpired", err)
+	}
+	if gateway.FindRoom(room.ID()) != nil {
+		t.Fatal("room outlived its last player")
+	}
+}
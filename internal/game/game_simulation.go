@@ -0,0 +1,322 @@
+package game
+
+import (
+	"ais-summoner/internal/models"
+	"ais-summoner/internal/pkg/geometry"
+	"math"
+	"os"
+	"strconv"
+	"time"
+)
+
+const (
+	DefaultTickRate = 30
+	MinTickRate     = 10
+	MaxTickRate     = 128
+)
+
+type RoomConfig struct {
+	TickRate      int
+	MoveSpeed     float64
+	DashDistance  float64
+	DashCooldown  time.Duration
+	DashDamage    float64
+	HitRadius     float64
+	PlayerRadius  float64
+	MaxHealth     float64
+	MatchDuration time.Duration
+	// MaxRewind caps how far back dash hits are judged for lagging
+	// clients.
+	MaxRewind time.Duration
+	// InterestRadius limits state updates to the players within that
+	// distance of the receiver. Zero sends everyone.
+	InterestRadius float64
+	// FullSnapshotInterval is how often a client that acknowledges
+	// snapshots still gets a full one instead of a delta.
+	FullSnapshotInterval time.Duration
+	// ResumeGrace is how long a player whose socket dropped keeps their
+	// slot. Zero removes them right away.
+	ResumeGrace time.Duration
+}
+
+// DefaultRoomConfig returns the room configuration, reading the tick
+// rate from GAME_TICK_RATE, the interest radius from GAME_INTEREST_RADIUS
+// and the resume grace period from GAME_RESUME_GRACE when they are set.
+func DefaultRoomConfig() RoomConfig {
+	config := RoomConfig{
+		TickRate:      DefaultTickRate,
+		MoveSpeed:     6,
+		DashDistance:  4,
+		DashCooldown:  2 * time.Second,
+		DashDamage:    34,
+		HitRadius:     1,
+		PlayerRadius:  0.5,
+		MaxHealth:     100,
+		MatchDuration: 5 * time.Minute,
+		MaxRewind:     200 * time.Millisecond,
+
+		FullSnapshotInterval: time.Second,
+		ResumeGrace:          30 * time.Second,
+	}
+
+	if tickRate, err := strconv.Atoi(os.Getenv("GAME_TICK_RATE")); err == nil {
+		config.TickRate = tickRate
+	}
+	if radius, err := strconv.ParseFloat(os.Getenv("GAME_INTEREST_RADIUS"), 64); err == nil && radius > 0 {
+		config.InterestRadius = radius
+	}
+	if grace, err := time.ParseDuration(os.Getenv("GAME_RESUME_GRACE")); err == nil && grace >= 0 {
+		config.ResumeGrace = grace
+	}
+	if config.TickRate < MinTickRate {
+		config.TickRate = MinTickRate
+	}
+	if config.TickRate > MaxTickRate {
+		config.TickRate = MaxTickRate
+	}
+
+	return config
+}
+
+// Ticks converts a duration into a number of simulation steps.
+func (config RoomConfig) Ticks(duration time.Duration) uint64 {
+	return uint64(duration / config.TickInterval())
+}
+
+// TickInterval is the wall-clock duration of a single simulation step.
+func (config RoomConfig) TickInterval() time.Duration {
+	return time.Second / time.Duration(config.TickRate)
+}
+
+// PlayerInput is a move or dash of a player. Seq is the client sequence
+// number of the input, or 0 when the client does not number its inputs.
+// Rewind is how many ticks old the state the client dashed on was.
+type PlayerInput struct {
+	PlayerID  string
+	Event     GameEvent
+	Direction models.Vector2
+	Seq       uint32
+	Rewind    uint64
+}
+
+// GameState is the state of a room after a tick. LastProcessedSeq is the
+// sequence number of the last input of the receiving player applied up to
+// that tick, which lets the client drop the predicted inputs the server
+// has caught up with.
+//
+// A state with a BaseTick is a delta against the snapshot of that tick,
+// which the client acknowledged: Players only holds the players that
+// changed or came into view since, and Removed the ids of those that left
+// the room or the interest radius of the receiving player.
+type GameState struct {
+	Tick             uint64    `json:"tick"`
+	Timestamp        int64     `json:"timestamp"`
+	Players          []*Player `json:"players"`
+	LastProcessedSeq uint32    `json:"lastProcessedSeq,omitempty"`
+	BaseTick         uint64    `json:"baseTick,omitempty"`
+	Removed          []string  `json:"removed,omitempty"`
+}
+
+// Simulation advances the players of a room by fixed steps. It holds no
+// locks of its own; the owning room serializes access to it.
+type Simulation struct {
+	config   RoomConfig
+	tick     uint64
+	players  []*Player
+	spawn    func() models.Vector3
+	collider *geometry.Collider
+	history  *positionHistory
+}
+
+// NewSimulation creates a simulation that places new and killed players at
+// the position returned by spawn. When collider is set it clamps every
+// movement to the walkable area of the terrain.
+func NewSimulation(config RoomConfig, spawn func() models.Vector3, collider *geometry.Collider) *Simulation {
+	return &Simulation{
+		config:   config,
+		spawn:    spawn,
+		collider: collider,
+		history:  newPositionHistory(config.Ticks(config.MaxRewind) + 1),
+	}
+}
+
+func (sim *Simulation) Tick() uint64 {
+	return sim.tick
+}
+
+// AddPlayer spawns the player with full health. Stats collects the battle
+// statistics of the player and may be shared across rejoins.
+func (sim *Simulation) AddPlayer(player *Player, stats *models.PlayerStats) {
+	player.Position = sim.spawn()
+	player.Health = sim.config.MaxHealth
+	player.stats = stats
+	player.spawnedAt = sim.tick
+	sim.players = append(sim.players, player)
+}
+
+func (sim *Simulation) RemovePlayer(id string) {
+	for i, player := range sim.players {
+		if player.ID == id {
+			sim.players = append(sim.players[:i], sim.players[i+1:]...)
+			return
+		}
+	}
+}
+
+func (sim *Simulation) Player(id string) *Player {
+	for _, player := range sim.players {
+		if player.ID == id {
+			return player
+		}
+	}
+
+	return nil
+}
+
+// Apply records an input so it takes effect on the next Step.
+func (sim *Simulation) Apply(input PlayerInput) {
+	player := sim.Player(input.PlayerID)
+	if player == nil {
+		return
+	}
+
+	direction := clampDirection(input.Direction)
+	switch input.Event {
+	case PlayerMove:
+		player.Direction = direction
+	case PlayerDash:
+		if direction == (models.Vector2{}) {
+			direction = player.Direction
+		}
+		player.dash = direction
+		player.dashQueued = true
+		player.dashRewind = min(input.Rewind, sim.config.Ticks(sim.config.MaxRewind))
+	}
+}
+
+// Step advances every player by one tick.
+func (sim *Simulation) Step() {
+	dt := 1 / float64(sim.config.TickRate)
+	cooldownTicks := int(sim.config.DashCooldown / sim.config.TickInterval())
+
+	for _, player := range sim.players {
+		if player.dashCooldown > 0 {
+			player.dashCooldown--
+		}
+
+		sim.move(player, models.Vector2{
+			X: player.Direction.X * sim.config.MoveSpeed * dt,
+			Y: player.Direction.Y * sim.config.MoveSpeed * dt,
+		}, false)
+
+		if player.dashQueued && player.dashCooldown == 0 && player.dash != (models.Vector2{}) {
+			start := player.Position
+			length := math.Hypot(player.dash.X, player.dash.Y)
+			sim.move(player, models.Vector2{
+				X: player.dash.X / length * sim.config.DashDistance,
+				Y: player.dash.Y / length * sim.config.DashDistance,
+			}, true)
+			player.dashCooldown = cooldownTicks
+			player.stats.Dashes++
+
+			sim.resolveDashHits(player, start)
+		}
+		player.dashQueued = false
+	}
+
+	sim.tick++
+	sim.history.record(sim.tick, sim.players)
+}
+
+// move displaces the player on the horizontal plane, where input Y maps to
+// the Z axis, sliding along the terrain walls when there is a collider.
+// Dashes fly over the holes of the terrain.
+func (sim *Simulation) move(player *Player, delta models.Vector2, dashing bool) {
+	if delta == (models.Vector2{}) {
+		return
+	}
+
+	if sim.collider == nil {
+		player.Position.X += delta.X
+		player.Position.Z += delta.Y
+		return
+	}
+
+	from := models.Vector2{X: player.Position.X, Y: player.Position.Z}
+	var to models.Vector2
+	if dashing {
+		to = sim.collider.Dash(from, delta, sim.config.PlayerRadius)
+	} else {
+		to = sim.collider.Move(from, delta, sim.config.PlayerRadius)
+	}
+	player.Position.X, player.Position.Z = to.X, to.Y
+}
+
+// resolveDashHits damages every other player within the hit radius of the
+// path the attacker dashed along, respawning those it kills. Victims are
+// placed where the attacker saw them when the dash carries a rewind.
+func (sim *Simulation) resolveDashHits(attacker *Player, start models.Vector3) {
+	for _, victim := range sim.players {
+		if victim == attacker {
+			continue
+		}
+		if distanceToSegment(sim.rewound(victim, attacker.dashRewind), start, attacker.Position) > sim.config.HitRadius {
+			continue
+		}
+
+		damage := math.Min(sim.config.DashDamage, victim.Health)
+		victim.Health -= damage
+		attacker.stats.Damage += damage
+
+		if victim.Health <= 0 {
+			attacker.stats.Kills++
+			victim.stats.Deaths++
+			victim.Position = sim.spawn()
+			victim.Direction = models.Vector2{}
+			victim.Health = sim.config.MaxHealth
+			victim.spawnedAt = sim.tick
+		}
+	}
+}
+
+// State copies the current player positions into a GameState.
+func (sim *Simulation) State(now time.Time) *GameState {
+	players := make([]*Player, 0, len(sim.players))
+	for _, player := range sim.players {
+		copied := *player
+		players = append(players, &copied)
+	}
+
+	return &GameState{
+		Tick:      sim.tick,
+		Timestamp: now.UnixMilli(),
+		Players:   players,
+	}
+}
+
+// distanceToSegment measures on the horizontal plane how far point is from
+// the segment between start and end.
+func distanceToSegment(point models.Vector3, start models.Vector3, end models.Vector3) float64 {
+	dx, dz := end.X-start.X, end.Z-start.Z
+	t := 0.0
+	if lengthSquared := dx*dx + dz*dz; lengthSquared > 0 {
+		t = math.Max(0, math.Min(1, ((point.X-start.X)*dx+(point.Z-start.Z)*dz)/lengthSquared))
+	}
+
+	return math.Hypot(point.X-(start.X+t*dx), point.Z-(start.Z+t*dz))
+}
+
+// clampDirection limits the input to the unit circle so analog input can
+// slow a player down but never speed them up.
+func clampDirection(direction models.Vector2) models.Vector2 {
+	if math.IsNaN(direction.X) || math.IsNaN(direction.Y) || math.IsInf(direction.X, 0) || math.IsInf(direction.Y, 0) {
+		return models.Vector2{}
+	}
+
+	length := math.Hypot(direction.X, direction.Y)
+	if length <= 1 {
+		return direction
+	}
+
+	return models.Vector2{X: direction.X / length, Y: direction.Y / length}
+}
@@ -0,0 +1,176 @@
+package game
+
+import (
+	"ais-summoner/internal/models"
+	"math"
+	"sort"
+)
+
+// snapshotHistorySize is how many sent snapshots are kept per member. A
+// client acknowledging a snapshot older than that gets a full one.
+const snapshotHistorySize = 32
+
+// playerSnapshot holds the fields of a player a client was sent.
+type playerSnapshot struct {
+	Position  models.Vector3
+	Direction models.Vector2
+	Health    float64
+}
+
+type sentSnapshot struct {
+	tick    uint64
+	players map[string]playerSnapshot
+}
+
+// snapshotHistory remembers the snapshots sent to one member so the next
+// update can be a delta against the newest one the client acknowledged.
+type snapshotHistory struct {
+	sent     [snapshotHistorySize]sentSnapshot
+	acked    uint64
+	lastFull uint64
+}
+
+func newSnapshotHistory() *snapshotHistory {
+	return &snapshotHistory{}
+}
+
+// acknowledge records that the client holds the snapshot of the given tick.
+// Acknowledgements for snapshots no longer kept, or older than the current
+// baseline, are ignored.
+func (history *snapshotHistory) acknowledge(tick uint64) {
+	if tick <= history.acked {
+		return
+	}
+	if sent := &history.sent[tick%snapshotHistorySize]; sent.tick == tick && sent.players != nil {
+		history.acked = tick
+	}
+}
+
+// baseline returns the acknowledged snapshot, or nil when there is none
+// or the snapshot of tick is about to take its place in the history.
+func (history *snapshotHistory) baseline(tick uint64) *sentSnapshot {
+	if history.acked == 0 || tick-history.acked >= snapshotHistorySize {
+		return nil
+	}
+
+	sent := &history.sent[history.acked%snapshotHistorySize]
+	if sent.tick != history.acked {
+		return nil
+	}
+
+	return sent
+}
+
+// update builds the GameStateUpdate of the member from the players it can
+// see. It sends every visible player when the client has no usable
+// baseline or its last full snapshot is fullInterval ticks old, and
+// otherwise only the players that changed since the baseline along with
+// the ids of those no longer visible.
+func (history *snapshotHistory) update(state *GameState, visible []*Player, fullInterval uint64) *GameState {
+	update := &GameState{Tick: state.Tick, Timestamp: state.Timestamp}
+	base := history.baseline(state.Tick)
+	players := history.record(state.Tick, visible)
+
+	if base == nil || state.Tick-history.lastFull >= fullInterval {
+		update.Players = visible
+		history.lastFull = state.Tick
+		return update
+	}
+
+	// A delta always lists its players, if only to show none changed.
+	update.BaseTick = base.tick
+	update.Players = []*Player{}
+	for _, player := range visible {
+		if previous, exists := base.players[player.ID]; !exists || previous != players[player.ID] {
+			update.Players = append(update.Players, player)
+		}
+	}
+	for id := range base.players {
+		if _, exists := players[id]; !exists {
+			update.Removed = append(update.Removed, id)
+		}
+	}
+	sort.Strings(update.Removed)
+
+	return update
+}
+
+// record stores the visible players as the snapshot of the tick, reusing
+// the map of the snapshot it replaces.
+func (history *snapshotHistory) record(tick uint64, visible []*Player) map[string]playerSnapshot {
+	sent := &history.sent[tick%snapshotHistorySize]
+	if sent.players == nil {
+		sent.players = make(map[string]playerSnapshot, len(visible))
+	} else {
+		clear(sent.players)
+	}
+
+	sent.tick = tick
+	for _, player := range visible {
+		sent.players[player.ID] = playerSnapshot{
+			Position:  player.Position,
+			Direction: player.Direction,
+			Health:    player.Health,
+		}
+	}
+
+	return sent.players
+}
+
+// interestGrid buckets players into cells as wide as the interest radius,
+// so finding the players near one only looks at the nine cells around it.
+type interestGrid struct {
+	radius  float64
+	players []*Player
+	cells   map[[2]int][]int
+}
+
+// newInterestGrid indexes the players. A radius of 0 or less turns
+// interest management off and everyone sees everyone.
+func newInterestGrid(players []*Player, radius float64) *interestGrid {
+	grid := &interestGrid{radius: radius, players: players}
+	if radius <= 0 {
+		return grid
+	}
+
+	grid.cells = make(map[[2]int][]int)
+	for i, player := range players {
+		cell := grid.cell(player.Position)
+		grid.cells[cell] = append(grid.cells[cell], i)
+	}
+
+	return grid
+}
+
+// visible returns the players within the radius of the viewer, the viewer
+// included, in the order of the state.
+func (grid *interestGrid) visible(viewer *Player) []*Player {
+	if grid.cells == nil {
+		return grid.players
+	}
+
+	var indices []int
+	center := grid.cell(viewer.Position)
+	for x := center[0] - 1; x <= center[0]+1; x++ {
+		for z := center[1] - 1; z <= center[1]+1; z++ {
+			for _, i := range grid.cells[[2]int{x, z}] {
+				player := grid.players[i]
+				if player.ID == viewer.ID || math.Hypot(player.Position.X-viewer.Position.X, player.Position.Z-viewer.Position.Z) <= grid.radius {
+					indices = append(indices, i)
+				}
+			}
+		}
+	}
+	sort.Ints(indices)
+
+	visible := make([]*Player, len(indices))
+	for i, index := range indices {
+		visible[i] = grid.players[index]
+	}
+
+	return visible
+}
+
+func (grid *interestGrid) cell(position models.Vector3) [2]int {
+	return [2]int{int(math.Floor(position.X / grid.radius)), int(math.Floor(position.Z / grid.radius))}
+}
@@ -0,0 +1,125 @@
+package game
+
+import (
+	"ais-summoner/internal/models"
+	"encoding/json"
+	"fmt"
+	"math"
+	"math/rand"
+	"strings"
+	"testing"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestSnapshotDeltas(t *testing.T) {
+	history := newSnapshotHistory()
+	a := &Player{ID: "a", Health: 100}
+	b := &Player{ID: "b", Health: 100}
+	state := func(tick uint64) *GameState {
+		return &GameState{Tick: tick}
+	}
+
+	// Without an acknowledged baseline every update is full.
+	update := history.update(state(1), []*Player{a, b}, 30)
+	if update.BaseTick != 0 || len(update.Players) != 2 {
+		t.Fatalf("got %+v, want a full update", update)
+	}
+	history.acknowledge(1)
+
+	// Nothing changed: the delta says so with an empty player list.
+	update = history.update(state(2), []*Player{a, b}, 30)
+	if update.BaseTick != 1 || update.Players == nil || len(update.Players) != 0 || update.Removed != nil {
+		t.Fatalf("got %+v, want an empty delta against tick 1", update)
+	}
+	data, _ := json.Marshal(update)
+	if !strings.Contains(string(data), `"players":[]`) {
+		t.Fatalf("encoded an empty delta as %s", data)
+	}
+
+	// Deltas stay against the acknowledged tick until a newer one is.
+	a.Position.X = 1
+	update = history.update(state(3), []*Player{a}, 30)
+	if update.BaseTick != 1 || len(update.Players) != 1 || update.Players[0] != a || fmt.Sprint(update.Removed) != "[b]" {
+		t.Fatalf("got %+v, want a moved and b removed against tick 1", update)
+	}
+
+	// A full update goes out once the interval is up.
+	history.acknowledge(3)
+	if update = history.update(state(31), []*Player{a}, 30); update.BaseTick != 0 {
+		t.Fatalf("got a delta against %d, want a full update", update.BaseTick)
+	}
+}
+
+// benchmarkStateBytes runs a room of 64 players walking about a large
+// terrain, each acknowledging every state update as it arrives, and
+// reports the state bytes sent per client and second.
+func benchmarkStateBytes(b *testing.B, codec Codec, interestRadius float64, deltas bool) {
+	const players = 64
+	rng := rand.New(rand.NewSource(1))
+
+	terrain := &models.Terrain{
+		ID:       primitive.NewObjectID(),
+		Boundary: []models.Vector2{{X: -100, Y: -100}, {X: 100, Y: -100}, {X: 100, Y: 100}, {X: -100, Y: 100}},
+	}
+	for i := 0; i < players; i++ {
+		terrain.SpawnPoints = append(terrain.SpawnPoints, models.SpawnPoint{
+			Position: models.Vector3{X: rng.Float64()*180 - 90, Z: rng.Float64()*180 - 90},
+		})
+	}
+
+	config := DefaultRoomConfig()
+	config.InterestRadius = interestRadius
+	room := NewGameRoom(primitive.NewObjectID().Hex(), terrain, config, newManualClock())
+
+	clients := make([]*GameClient, players)
+	for i := range clients {
+		clients[i], _ = newTestClient(nil, primitive.NewObjectID().Hex())
+		clients[i].codec = codec
+		if err := room.join(clients[i]); err != nil {
+			b.Fatalf("join: %v", err)
+		}
+	}
+	for _, client := range clients {
+		client.queue.take()
+	}
+
+	var sent int
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		// Everyone turns now and then.
+		for seq, client := range clients {
+			if rng.Intn(30) == 0 {
+				angle := rng.Float64() * 2 * math.Pi
+				room.enqueue(client, PlayerMove, models.Vector2{X: math.Cos(angle), Y: math.Sin(angle)}, uint32(i*players+seq+1))
+			}
+		}
+
+		room.Tick()
+		for _, client := range clients {
+			messages, _ := client.queue.take()
+			for _, message := range messages {
+				sent += len(message)
+			}
+			if deltas {
+				room.acknowledge(client, room.simulation.Tick())
+			}
+		}
+	}
+
+	seconds := float64(b.N) / float64(config.TickRate)
+	b.ReportMetric(float64(sent)/players/seconds, "bytes/client/s")
+}
+
+func BenchmarkStateBytes(b *testing.B) {
+	codecs := []struct {
+		name  string
+		codec Codec
+	}{{"json", JSONCodec{}}, {"binary", BinaryCodec{}}}
+
+	for _, c := range codecs {
+		b.Run(c.name+"/full", func(b *testing.B) { benchmarkStateBytes(b, c.codec, 0, false) })
+		b.Run(c.name+"/deltas", func(b *testing.B) { benchmarkStateBytes(b, c.codec, 0, true) })
+		b.Run(c.name+"/deltas+interest", func(b *testing.B) { benchmarkStateBytes(b, c.codec, 30, true) })
+	}
+}
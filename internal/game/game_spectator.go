@@ -0,0 +1,95 @@
+package game
+
+import (
+	"context"
+	"net/http"
+	"time"
+)
+
+// HandleReplaySpectator upgrades the request to a socket that streams the
+// replay of a match as GameStateUpdate events at the recorded tick rate.
+func (gateway *GameGateway) HandleReplaySpectator(w http.ResponseWriter, r *http.Request, matchID string) {
+	select {
+	case <-gateway.done:
+		http.Error(w, ErrServerDraining.Error(), http.StatusServiceUnavailable)
+		return
+	default:
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 10*time.Second)
+	defer cancel()
+
+	data, err := gateway.replays.Load(ctx, matchID)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if data == nil {
+		http.Error(w, "replay not found", http.StatusNotFound)
+		return
+	}
+
+	replay, err := DecodeReplay(data)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	conn, err := gateway.upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		gateway.logger.Printf("Error upgrading connection: %v", err)
+		return
+	}
+
+	codec := codecForSubprotocol(conn.Subprotocol())
+	gateway.serveReplay(NewWebSocketTransport(conn, codec.MessageType()), codec, replay)
+}
+
+// serveReplay streams the replay to a spectator over the transport. The
+// spectator is registered like a player, so draining closes its socket too.
+func (gateway *GameGateway) serveReplay(transport Transport, codec Codec, replay *Replay) {
+	client := newGameClient(gateway, codec, transport)
+	gateway.register <- client
+
+	go client.Write()
+	go client.streamReplay(NewReplayPlayer(replay))
+}
+
+// streamReplay plays the replay into the send queue until it ends or the
+// spectator goes away. A spectator that cannot keep up skips states, like
+// players do. Spectators never send anything, so reading only serves to
+// notice the socket closing. Unregistering the spectator once done closes
+// the socket.
+func (client *GameClient) streamReplay(player *ReplayPlayer) {
+	defer func() {
+		client.gateway.unregister <- client
+	}()
+
+	closed := make(chan struct{})
+	go func() {
+		defer close(closed)
+		for {
+			if _, err := client.transport.Receive(); err != nil {
+				return
+			}
+		}
+	}()
+
+	ticker := client.gateway.clock.NewTicker(player.Config().TickInterval())
+	defer ticker.Stop()
+
+	for {
+		select {
+		case now := <-ticker.C():
+			state, ok := player.Step(now)
+			if !ok {
+				return
+			}
+
+			client.sendMessage(GameStateUpdate, state)
+
+		case <-closed:
+			return
+		}
+	}
+}
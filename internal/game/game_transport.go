@@ -0,0 +1,242 @@
+package game
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+)
+
+// Close codes a transport ends with. They are the status codes of RFC 6455,
+// which the WebSocketTransport sends as they are, and CloseSlowConsumer.
+const (
+	CloseNormal          = 1000
+	CloseGoingAway       = 1001
+	ClosePolicyViolation = 1008
+	CloseServiceRestart  = 1012
+	// CloseAbnormal is never sent. It reports a peer that went away
+	// without closing the transport.
+	CloseAbnormal = 1006
+	// CloseSlowConsumer is the close code, from the range left to
+	// applications, of transports closed because their queue of outgoing
+	// messages overflowed.
+	CloseSlowConsumer = 4001
+)
+
+var errTransportClosed = errors.New("transport closed")
+
+// CloseError is the error Receive fails with once the peer closed the
+// transport.
+type CloseError struct {
+	Code   int
+	Reason string
+}
+
+func (err *CloseError) Error() string {
+	return fmt.Sprintf("transport closed with code %d: %s", err.Code, err.Reason)
+}
+
+// isUnexpectedClose reports whether err is a CloseError with a code other
+// than those of a peer leaving as it should.
+func isUnexpectedClose(err error) bool {
+	var closeErr *CloseError
+	if !errors.As(err, &closeErr) {
+		return false
+	}
+
+	switch closeErr.Code {
+	case CloseNormal, CloseGoingAway, CloseAbnormal:
+		return false
+	default:
+		return true
+	}
+}
+
+// Transport carries the messages between a GameClient and its peer. Send
+// and Ping are only called from the write goroutine of the client, Receive
+// and the deadline and pong handler setters from its read goroutine; Close
+// and RemoteAddr may be called from anywhere.
+type Transport interface {
+	// Send writes the messages, batching them as the transport allows.
+	Send(messages [][]byte) error
+	// Receive blocks until the next message arrives. Pongs are handed to
+	// the pong handler on the way. Once the peer closed the transport it
+	// fails with a *CloseError. A PipeTransport fails the same way when
+	// closed at this end, while a WebSocketTransport closed at this end
+	// fails with the error of reading from a closed network connection.
+	Receive() ([]byte, error)
+	// Ping asks the peer to echo the payload back as a pong.
+	Ping(payload []byte) error
+	SetPongHandler(handler func(payload []byte))
+	// SetReadDeadline makes Receive fail once the deadline passes. The
+	// zero time means no deadline.
+	SetReadDeadline(deadline time.Time) error
+	// Close ends the transport with the close code and reason. Closing
+	// twice is harmless.
+	Close(code int, reason string) error
+	RemoteAddr() string
+}
+
+const (
+	pipeMessage = iota
+	pipePing
+	pipePong
+)
+
+// pipeBuffer is how many frames each direction of a pipe holds before
+// Send blocks, like a socket with full buffers.
+const pipeBuffer = 64
+
+type pipeFrame struct {
+	kind int
+	data []byte
+}
+
+// pipeState is shared by both ends of a pipe.
+type pipeState struct {
+	done      chan struct{}
+	closeErr  *CloseError
+	closeOnce sync.Once
+}
+
+// PipeTransport is one end of an in-memory Transport, for clients that
+// live in the process like bots and tests. Messages are delivered one by
+// one and pings are answered by the other end while it receives.
+type PipeTransport struct {
+	incoming    chan pipeFrame
+	outgoing    chan pipeFrame
+	state       *pipeState
+	remote      string
+	pongHandler func(payload []byte)
+	deadline    time.Time
+	mutex       sync.Mutex
+}
+
+// NewPipe connects two PipeTransports to each other.
+func NewPipe() (*PipeTransport, *PipeTransport) {
+	ab := make(chan pipeFrame, pipeBuffer)
+	ba := make(chan pipeFrame, pipeBuffer)
+	state := &pipeState{done: make(chan struct{})}
+
+	return &PipeTransport{incoming: ba, outgoing: ab, state: state, remote: "pipe"},
+		&PipeTransport{incoming: ab, outgoing: ba, state: state, remote: "pipe"}
+}
+
+func (pipe *PipeTransport) Send(messages [][]byte) error {
+	for _, message := range messages {
+		if err := pipe.write(pipeFrame{kind: pipeMessage, data: message}); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (pipe *PipeTransport) Receive() ([]byte, error) {
+	for {
+		frame, err := pipe.read()
+		if err != nil {
+			return nil, err
+		}
+
+		switch frame.kind {
+		case pipePing:
+			if err := pipe.write(pipeFrame{kind: pipePong, data: frame.data}); err != nil {
+				return nil, err
+			}
+		case pipePong:
+			pipe.mutex.Lock()
+			handler := pipe.pongHandler
+			pipe.mutex.Unlock()
+			if handler != nil {
+				handler(frame.data)
+			}
+		default:
+			return frame.data, nil
+		}
+	}
+}
+
+func (pipe *PipeTransport) Ping(payload []byte) error {
+	return pipe.write(pipeFrame{kind: pipePing, data: payload})
+}
+
+func (pipe *PipeTransport) SetPongHandler(handler func(payload []byte)) {
+	pipe.mutex.Lock()
+	defer pipe.mutex.Unlock()
+
+	pipe.pongHandler = handler
+}
+
+func (pipe *PipeTransport) SetReadDeadline(deadline time.Time) error {
+	pipe.mutex.Lock()
+	defer pipe.mutex.Unlock()
+
+	pipe.deadline = deadline
+	return nil
+}
+
+// Close ends both ends of the pipe. Frames already sent can still be
+// received; after them Receive fails with the close code and reason.
+func (pipe *PipeTransport) Close(code int, reason string) error {
+	pipe.state.closeOnce.Do(func() {
+		pipe.state.closeErr = &CloseError{Code: code, Reason: reason}
+		close(pipe.state.done)
+	})
+
+	return nil
+}
+
+func (pipe *PipeTransport) RemoteAddr() string {
+	return pipe.remote
+}
+
+func (pipe *PipeTransport) write(frame pipeFrame) error {
+	select {
+	case <-pipe.state.done:
+		return errTransportClosed
+	default:
+	}
+
+	select {
+	case pipe.outgoing <- frame:
+		return nil
+	case <-pipe.state.done:
+		return errTransportClosed
+	}
+}
+
+func (pipe *PipeTransport) read() (pipeFrame, error) {
+	// Frames sent before the pipe was closed are delivered first.
+	select {
+	case frame := <-pipe.incoming:
+		return frame, nil
+	default:
+	}
+
+	pipe.mutex.Lock()
+	deadline := pipe.deadline
+	pipe.mutex.Unlock()
+
+	var expired <-chan time.Time
+	if !deadline.IsZero() {
+		timer := time.NewTimer(time.Until(deadline))
+		defer timer.Stop()
+		expired = timer.C
+	}
+
+	select {
+	case frame := <-pipe.incoming:
+		return frame, nil
+	case <-pipe.state.done:
+		select {
+		case frame := <-pipe.incoming:
+			return frame, nil
+		default:
+			return pipeFrame{}, pipe.state.closeErr
+		}
+	case <-expired:
+		return pipeFrame{}, os.ErrDeadlineExceeded
+	}
+}
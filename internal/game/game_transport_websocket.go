@@ -0,0 +1,104 @@
+package game
+
+import (
+	"errors"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+const (
+	// maxMessageSize leaves room for an ID token in the Authentication
+	// message.
+	maxMessageSize = 4096
+	// writeWait bounds how long a frame may take to go out.
+	writeWait = 10 * time.Second
+	// closeWait bounds the close frame, which is only a courtesy to a peer
+	// that may already be gone.
+	closeWait = time.Second
+)
+
+// WebSocketTransport is the Transport of sockets upgraded by the gateway.
+// Text frames batch the messages separated by newlines; binary frames
+// carry exactly one message each because the binary layout has no
+// delimiter.
+type WebSocketTransport struct {
+	connection  *websocket.Conn
+	messageType int
+}
+
+func NewWebSocketTransport(connection *websocket.Conn, messageType int) *WebSocketTransport {
+	connection.SetReadLimit(maxMessageSize)
+
+	return &WebSocketTransport{
+		connection:  connection,
+		messageType: messageType,
+	}
+}
+
+func (transport *WebSocketTransport) Send(messages [][]byte) error {
+	if len(messages) == 0 {
+		return nil
+	}
+
+	transport.connection.SetWriteDeadline(time.Now().Add(writeWait))
+	if transport.messageType == websocket.BinaryMessage {
+		for _, message := range messages {
+			if err := transport.connection.WriteMessage(transport.messageType, message); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	w, err := transport.connection.NextWriter(transport.messageType)
+	if err != nil {
+		return err
+	}
+	for i, message := range messages {
+		if i > 0 {
+			w.Write([]byte{'\n'})
+		}
+		w.Write(message)
+	}
+
+	return w.Close()
+}
+
+func (transport *WebSocketTransport) Receive() ([]byte, error) {
+	_, message, err := transport.connection.ReadMessage()
+
+	var closeErr *websocket.CloseError
+	if errors.As(err, &closeErr) {
+		return nil, &CloseError{Code: closeErr.Code, Reason: closeErr.Text}
+	}
+	return message, err
+}
+
+func (transport *WebSocketTransport) Ping(payload []byte) error {
+	return transport.connection.WriteControl(websocket.PingMessage, payload, time.Now().Add(writeWait))
+}
+
+func (transport *WebSocketTransport) SetPongHandler(handler func(payload []byte)) {
+	transport.connection.SetPongHandler(func(data string) error {
+		handler([]byte(data))
+		return nil
+	})
+}
+
+func (transport *WebSocketTransport) SetReadDeadline(deadline time.Time) error {
+	return transport.connection.SetReadDeadline(deadline)
+}
+
+// Close sends the close frame, if the socket still takes it, and closes
+// the connection.
+func (transport *WebSocketTransport) Close(code int, reason string) error {
+	message := websocket.FormatCloseMessage(code, reason)
+	transport.connection.WriteControl(websocket.CloseMessage, message, time.Now().Add(closeWait))
+
+	return transport.connection.Close()
+}
+
+func (transport *WebSocketTransport) RemoteAddr() string {
+	return transport.connection.RemoteAddr().String()
+}
@@ -0,0 +1,87 @@
+package handler
+
+import (
+	"ais-summoner/internal/game"
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// maxBotsPerRequest keeps a single request from flooding the server.
+const maxBotsPerRequest = 64
+
+type addBotsRequest struct {
+	RoomID    string `json:"roomId"`
+	TerrainID string `json:"terrainId"`
+	Count     int    `json:"count"`
+	Behaviour string `json:"behaviour"`
+}
+
+// AddBotsHandler puts bots in an existing room, or in a new room on the
+// given terrain, for load testing and to keep quiet rooms busy.
+func AddBotsHandler(gateway *game.GameGateway) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		var request addBotsRequest
+		if err := ctx.ShouldBindJSON(&request); err != nil {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		if request.Count < 1 || request.Count > maxBotsPerRequest {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": "count must be between 1 and 64"})
+			return
+		}
+		if request.RoomID == "" && request.TerrainID == "" {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": "roomId or terrainId is required"})
+			return
+		}
+		if request.TerrainID != "" && !primitive.IsValidObjectID(request.TerrainID) {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": "invalid terrain id"})
+			return
+		}
+		if request.Behaviour == "" {
+			request.Behaviour = game.DefaultBotBehaviour
+		}
+
+		room, botIDs, err := gateway.AddBots(request.RoomID, request.TerrainID, request.Count, request.Behaviour)
+		if err != nil {
+			ctx.JSON(botErrorStatus(err), gin.H{"error": err.Error(), "bots": botIDs})
+			return
+		}
+
+		ctx.JSON(http.StatusCreated, gin.H{"roomId": room.ID(), "bots": botIDs})
+	}
+}
+
+// RemoveBotsHandler stops every bot in the room.
+func RemoveBotsHandler(gateway *game.GameGateway) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		removed, err := gateway.RemoveBots(ctx.Param("roomId"))
+		if err != nil {
+			ctx.JSON(botErrorStatus(err), gin.H{"error": err.Error()})
+			return
+		}
+
+		ctx.JSON(http.StatusOK, gin.H{"removed": removed})
+	}
+}
+
+func botErrorStatus(err error) int {
+	var redirect *game.RoomRedirect
+	if errors.As(err, &redirect) {
+		return http.StatusConflict
+	}
+
+	var gameError *game.GameError
+	if !errors.As(err, &gameError) {
+		return http.StatusInternalServerError
+	}
+
+	switch gameError.Code {
+	case game.CodeRoomNotFound, game.CodeTerrainNotFound:
+		return http.StatusNotFound
+	default:
+		return http.StatusBadRequest
+	}
+}
@@ -0,0 +1,16 @@
+package handler
+
+import (
+	"ais-summoner/internal/game"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GetConnectionsHandler lists the clients connected to this instance with
+// their round trip times and traffic counters.
+func GetConnectionsHandler(gateway *game.GameGateway) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		ctx.JSON(http.StatusOK, gateway.Connections())
+	}
+}
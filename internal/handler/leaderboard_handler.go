@@ -0,0 +1,152 @@
+package handler
+
+import (
+	"ais-summoner/internal/database"
+	"ais-summoner/internal/leaderboard"
+	"context"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+func GetLeaderboardHandler(mongodb *database.MongoDB, board *leaderboard.Leaderboard) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		page, err := queryInt(ctx, "page", 1, 1, 1<<20)
+		if err != nil {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		pageSize, err := queryInt(ctx, "pageSize", leaderboard.DefaultPageSize, 1, leaderboard.MaxPageSize)
+		if err != nil {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		season := querySeason(ctx)
+		entries, total, err := board.Page(season, page, pageSize)
+		if err != nil {
+			ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+
+		if err := fillUsernames(ctx, mongodb, entries); err != nil {
+			ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+
+		ctx.JSON(http.StatusOK, gin.H{
+			"season":   season,
+			"page":     page,
+			"pageSize": pageSize,
+			"total":    total,
+			"entries":  entries,
+		})
+	}
+}
+
+func GetLeaderboardAroundUserHandler(mongodb *database.MongoDB, board *leaderboard.Leaderboard) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		radius, err := queryInt(ctx, "radius", 5, 0, leaderboard.MaxPageSize/2)
+		if err != nil {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		season := querySeason(ctx)
+		entries, err := board.Around(season, ctx.Param("userId"), radius)
+		if err != nil {
+			ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		if entries == nil {
+			ctx.JSON(http.StatusNotFound, gin.H{"error": "user is not ranked"})
+			return
+		}
+
+		if err := fillUsernames(ctx, mongodb, entries); err != nil {
+			ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+
+		ctx.JSON(http.StatusOK, gin.H{
+			"season":  season,
+			"entries": entries,
+		})
+	}
+}
+
+func GetUserRankHandler(mongodb *database.MongoDB, board *leaderboard.Leaderboard) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		season := querySeason(ctx)
+		entry, err := board.Rank(season, ctx.Param("id"))
+		if err != nil {
+			ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		if entry == nil {
+			ctx.JSON(http.StatusNotFound, gin.H{"error": "user is not ranked"})
+			return
+		}
+
+		if err := fillUsernames(ctx, mongodb, []*leaderboard.Entry{entry}); err != nil {
+			ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+
+		ctx.JSON(http.StatusOK, gin.H{
+			"season": season,
+			"entry":  entry,
+		})
+	}
+}
+
+// querySeason reads the season query parameter. It is empty for the global
+// leaderboard, and "current" selects the season that is running now.
+func querySeason(ctx *gin.Context) string {
+	season := ctx.Query("season")
+	if season == "current" {
+		return leaderboard.CurrentSeason(time.Now())
+	}
+
+	return season
+}
+
+func queryInt(ctx *gin.Context, name string, fallback int, min int, max int) (int, error) {
+	raw := ctx.Query(name)
+	if raw == "" {
+		return fallback, nil
+	}
+
+	value, err := strconv.Atoi(raw)
+	if err != nil || value < min || value > max {
+		return 0, fmt.Errorf("%s must be an integer between %d and %d", name, min, max)
+	}
+
+	return value, nil
+}
+
+func fillUsernames(ctx context.Context, mongodb *database.MongoDB, entries []*leaderboard.Entry) error {
+	ids := make([]string, len(entries))
+	for i, entry := range entries {
+		ids[i] = entry.UserID
+	}
+
+	users, err := mongodb.UserRepository().FindByIDs(ctx, ids)
+	if err != nil {
+		return err
+	}
+
+	usernames := make(map[string]string, len(users))
+	for _, user := range users {
+		usernames[user.ID.Hex()] = user.Username
+	}
+	for _, entry := range entries {
+		entry.Username = usernames[entry.UserID]
+	}
+
+	return nil
+}
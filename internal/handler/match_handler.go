@@ -0,0 +1,24 @@
+package handler
+
+import (
+	"ais-summoner/internal/database"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+func GetMatchByIdHandler(mongodb *database.MongoDB) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		match, err := mongodb.MatchRepository().GetByID(ctx, ctx.Param("id"))
+		if err != nil {
+			ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		if match == nil {
+			ctx.JSON(http.StatusNotFound, gin.H{"error": "match not found"})
+			return
+		}
+
+		ctx.JSON(http.StatusOK, match)
+	}
+}
@@ -0,0 +1,26 @@
+package handler
+
+import (
+	"ais-summoner/internal/game"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+func GetReplayHandler(replays game.ReplayStore) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		matchID := ctx.Param("matchId")
+		data, err := replays.Load(ctx, matchID)
+		if err != nil {
+			ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		if data == nil {
+			ctx.JSON(http.StatusNotFound, gin.H{"error": "replay not found"})
+			return
+		}
+
+		ctx.Header("Content-Disposition", `attachment; filename="`+matchID+`.replay"`)
+		ctx.Data(http.StatusOK, "application/octet-stream", data)
+	}
+}
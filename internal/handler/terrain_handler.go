@@ -2,9 +2,15 @@ package handler
 
 import (
 	"ais-summoner/internal/database"
+	"ais-summoner/internal/models"
+	"ais-summoner/internal/pkg/geometry"
+	"ais-summoner/internal/pkg/terrainfile"
+	"io"
 	"net/http"
+	"strconv"
 
 	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
 func GetTerrainByIdHandler(mongodb *database.MongoDB) gin.HandlerFunc {
@@ -30,3 +36,172 @@ func GetTerrainListHandler(mongodb *database.MongoDB) gin.HandlerFunc {
 		ctx.JSON(http.StatusOK, terrain)
 	}
 }
+
+func CreateTerrainHandler(mongodb *database.MongoDB) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		terrain, ok := bindTerrain(ctx)
+		if !ok {
+			return
+		}
+
+		terrain, err := mongodb.TerrainRepository().Insert(ctx, terrain)
+		if err != nil {
+			ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+
+		ctx.JSON(http.StatusCreated, terrain)
+	}
+}
+
+func UpdateTerrainHandler(mongodb *database.MongoDB) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		if !primitive.IsValidObjectID(ctx.Param("id")) {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": "invalid terrain id"})
+			return
+		}
+
+		terrain, ok := bindTerrain(ctx)
+		if !ok {
+			return
+		}
+
+		terrain, err := mongodb.TerrainRepository().Update(ctx, ctx.Param("id"), terrain)
+		if err != nil {
+			ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		if terrain == nil {
+			ctx.JSON(http.StatusNotFound, gin.H{"error": "terrain not found"})
+			return
+		}
+
+		ctx.JSON(http.StatusOK, terrain)
+	}
+}
+
+func DeleteTerrainHandler(mongodb *database.MongoDB) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		if !primitive.IsValidObjectID(ctx.Param("id")) {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": "invalid terrain id"})
+			return
+		}
+
+		terrain, err := mongodb.TerrainRepository().GetByID(ctx, ctx.Param("id"))
+		if err != nil {
+			ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		if terrain == nil {
+			ctx.JSON(http.StatusNotFound, gin.H{"error": "terrain not found"})
+			return
+		}
+
+		if err := mongodb.TerrainRepository().Delete(ctx, ctx.Param("id")); err != nil {
+			ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+
+		ctx.Status(http.StatusNoContent)
+	}
+}
+
+// bindTerrain decodes and validates the terrain in the request body. It
+// answers with 400 and the list of problems when the terrain is invalid.
+func bindTerrain(ctx *gin.Context) (*models.Terrain, bool) {
+	var terrain models.Terrain
+	if err := ctx.ShouldBindJSON(&terrain); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "invalid terrain", "details": []string{err.Error()}})
+		return nil, false
+	}
+
+	terrain.MigrateLegacyOutline()
+	if problems := geometry.ValidateTerrain(&terrain); problems != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "invalid terrain", "details": problems})
+		return nil, false
+	}
+
+	terrain.ID = primitive.NilObjectID
+	return &terrain, true
+}
+
+// maxTerrainFileSize bounds the body of a terrain import.
+const maxTerrainFileSize = 1 << 20
+
+func ImportTerrainHandler(mongodb *database.MongoDB) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		format, err := terrainfile.ParseFormat(ctx.DefaultQuery("format", string(terrainfile.FormatGeoJSON)))
+		if err != nil {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		options := terrainfile.ImportOptions{Name: ctx.Query("name")}
+		if value := ctx.Query("rotation"); value != "" {
+			rotation, err := strconv.ParseFloat(value, 64)
+			if err != nil {
+				ctx.JSON(http.StatusBadRequest, gin.H{"error": "invalid rotation"})
+				return
+			}
+			options.Rotation = &rotation
+		}
+
+		data, err := io.ReadAll(http.MaxBytesReader(ctx.Writer, ctx.Request.Body, maxTerrainFileSize))
+		if err != nil {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		terrain, err := terrainfile.Import(format, data, options)
+		if err != nil {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": "invalid terrain", "details": []string{err.Error()}})
+			return
+		}
+		if problems := geometry.ValidateTerrain(terrain); problems != nil {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": "invalid terrain", "details": problems})
+			return
+		}
+
+		terrain, err = mongodb.TerrainRepository().Insert(ctx, terrain)
+		if err != nil {
+			ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+
+		ctx.JSON(http.StatusCreated, terrain)
+	}
+}
+
+func ExportTerrainHandler(mongodb *database.MongoDB) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		format, err := terrainfile.ParseFormat(ctx.DefaultQuery("format", string(terrainfile.FormatGeoJSON)))
+		if err != nil {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		if !primitive.IsValidObjectID(ctx.Param("id")) {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": "invalid terrain id"})
+			return
+		}
+
+		terrain, err := mongodb.TerrainRepository().GetByID(ctx, ctx.Param("id"))
+		if err != nil {
+			ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		if terrain == nil {
+			ctx.JSON(http.StatusNotFound, gin.H{"error": "terrain not found"})
+			return
+		}
+
+		data, err := terrainfile.Export(format, terrain)
+		if err != nil {
+			ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+
+		ctx.Header("Content-Disposition", `attachment; filename="`+terrain.ID.Hex()+format.Extension()+`"`)
+		ctx.Data(http.StatusOK, format.ContentType(), data)
+	}
+}
@@ -3,10 +3,29 @@ package handler
 import (
 	"ais-summoner/internal/database"
 	"net/http"
+	"strings"
 
 	"github.com/gin-gonic/gin"
 )
 
+// GetUserHandler serves GET /v1/user/:id, which also answers the lookups
+// by email of GET /v1/user/:email: gin cannot route both wildcards on the
+// same segment, so a parameter holding an @ is taken as an email address.
+func GetUserHandler(mongo *database.MongoDB) gin.HandlerFunc {
+	byID := GetUserByIdHandler(mongo)
+	byEmail := GetUserByEmailHandler(mongo)
+
+	return func(ctx *gin.Context) {
+		if param := ctx.Param("id"); strings.Contains(param, "@") {
+			ctx.AddParam("email", param)
+			byEmail(ctx)
+			return
+		}
+
+		byID(ctx)
+	}
+}
+
 func GetUserByIdHandler(mongo *database.MongoDB) gin.HandlerFunc {
 	return func(ctx *gin.Context) {
 		user, err := mongo.UserRepository().GetByID(ctx, ctx.Param("id"))
@@ -42,3 +61,19 @@ func GetUserListHandler(mongo *database.MongoDB) gin.HandlerFunc {
 		ctx.JSON(http.StatusOK, users)
 	}
 }
+
+func GetUserStatsHandler(mongo *database.MongoDB) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		user, err := mongo.UserRepository().GetByID(ctx, ctx.Param("id"))
+		if err != nil {
+			ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		if user == nil {
+			ctx.JSON(http.StatusNotFound, gin.H{"error": "user not found"})
+			return
+		}
+
+		ctx.JSON(http.StatusOK, user.Stats)
+	}
+}
@@ -0,0 +1,120 @@
+// Package leaderboard keeps the global and seasonal player rankings in
+// Redis sorted sets scored by rating.
+package leaderboard
+
+import (
+	"ais-summoner/internal/database"
+	"fmt"
+	"time"
+)
+
+const (
+	DefaultPageSize = 25
+	MaxPageSize     = 100
+)
+
+type Entry struct {
+	Rank     int64   `json:"rank"`
+	UserID   string  `json:"userId"`
+	Username string  `json:"username,omitempty"`
+	Rating   float64 `json:"rating"`
+}
+
+type Leaderboard struct {
+	redis *database.Redis
+}
+
+func NewLeaderboard(cache *database.Redis) *Leaderboard {
+	return &Leaderboard{redis: cache}
+}
+
+// CurrentSeason names the season containing t. Seasons run for one
+// calendar quarter, e.g. "2026-Q4".
+func CurrentSeason(t time.Time) string {
+	return fmt.Sprintf("%d-Q%d", t.Year(), (int(t.Month())-1)/3+1)
+}
+
+// Submit records the lifetime rating of a user on the global leaderboard
+// and the rating of the season on the leaderboard of that season.
+func (lb *Leaderboard) Submit(userID string, rating float64, season string, seasonRating float64) error {
+	if err := lb.redis.AddToSortedSet(key(""), userID, rating); err != nil {
+		return err
+	}
+
+	return lb.redis.AddToSortedSet(key(season), userID, seasonRating)
+}
+
+// Page returns one page of the leaderboard, highest rated first, and the
+// number of ranked users. Pages start at 1. An empty season selects the
+// global leaderboard.
+func (lb *Leaderboard) Page(season string, page int, pageSize int) ([]*Entry, int64, error) {
+	start := int64(page-1) * int64(pageSize)
+	entries, err := lb.rangeEntries(season, start, start+int64(pageSize)-1)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	total, err := lb.redis.GetSortedSetCount(key(season))
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return entries, total, nil
+}
+
+// Around returns the entries ranked within radius places of the user, or
+// nil when the user is not ranked.
+func (lb *Leaderboard) Around(season string, userID string, radius int) ([]*Entry, error) {
+	rank, found, err := lb.redis.GetSortedSetReverseRank(key(season), userID)
+	if err != nil || !found {
+		return nil, err
+	}
+
+	start := rank - int64(radius)
+	if start < 0 {
+		start = 0
+	}
+
+	return lb.rangeEntries(season, start, rank+int64(radius))
+}
+
+// Rank returns the entry of the user, or nil when the user is not ranked.
+func (lb *Leaderboard) Rank(season string, userID string) (*Entry, error) {
+	rank, found, err := lb.redis.GetSortedSetReverseRank(key(season), userID)
+	if err != nil || !found {
+		return nil, err
+	}
+
+	rating, err := lb.redis.GetSortedSetScore(key(season), userID)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Entry{Rank: rank + 1, UserID: userID, Rating: rating}, nil
+}
+
+func (lb *Leaderboard) rangeEntries(season string, start int64, stop int64) ([]*Entry, error) {
+	members, err := lb.redis.GetSortedSetReverseRange(key(season), start, stop)
+	if err != nil {
+		return nil, err
+	}
+
+	entries := make([]*Entry, len(members))
+	for i, member := range members {
+		entries[i] = &Entry{
+			Rank:   start + int64(i) + 1,
+			UserID: member.Member,
+			Rating: member.Score,
+		}
+	}
+
+	return entries, nil
+}
+
+func key(season string) string {
+	if season == "" {
+		return "leaderboard:global"
+	}
+
+	return "leaderboard:season:" + season
+}
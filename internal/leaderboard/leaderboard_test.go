@@ -0,0 +1,211 @@
+package leaderboard
+
+import (
+	"ais-summoner/internal/database"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+)
+
+// newTestLeaderboard backs a leaderboard with a local stand-in for Redis.
+func newTestLeaderboard(t *testing.T) (*Leaderboard, *miniredis.Miniredis) {
+	t.Helper()
+
+	server := miniredis.RunT(t)
+	t.Setenv("REDIS_CONNECTION_STRING", server.Addr())
+	cache := database.NewRedis()
+	t.Cleanup(func() {
+		cache.Close()
+	})
+
+	return NewLeaderboard(cache), server
+}
+
+// submitPlayers ranks player-0 to player-9, player-9 first, with season
+// ratings in the opposite order.
+func submitPlayers(t *testing.T, lb *Leaderboard, season string) {
+	t.Helper()
+
+	for i := 0; i < 10; i++ {
+		if err := lb.Submit(fmt.Sprintf("player-%d", i), float64(1000+100*i), season, float64(2000-100*i)); err != nil {
+			t.Fatalf("submitting player-%d: %v", i, err)
+		}
+	}
+}
+
+// userIDs lists the users of the entries and checks that their ranks
+// follow each other from first.
+func userIDs(t *testing.T, entries []*Entry, first int64) []string {
+	t.Helper()
+
+	ids := make([]string, len(entries))
+	for i, entry := range entries {
+		if entry.Rank != first+int64(i) {
+			t.Fatalf("entry %d of %s has rank %d, want %d", i, entry.UserID, entry.Rank, first+int64(i))
+		}
+		ids[i] = entry.UserID
+	}
+
+	return ids
+}
+
+func equalIDs(a []string, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+
+	return true
+}
+
+func TestCurrentSeason(t *testing.T) {
+	for _, test := range []struct {
+		date   time.Time
+		season string
+	}{
+		{time.Date(2026, time.January, 1, 0, 0, 0, 0, time.UTC), "2026-Q1"},
+		{time.Date(2026, time.March, 31, 23, 59, 59, 0, time.UTC), "2026-Q1"},
+		{time.Date(2026, time.April, 1, 0, 0, 0, 0, time.UTC), "2026-Q2"},
+		{time.Date(2026, time.October, 18, 12, 0, 0, 0, time.UTC), "2026-Q4"},
+		{time.Date(2027, time.December, 31, 0, 0, 0, 0, time.UTC), "2027-Q4"},
+	} {
+		if season := CurrentSeason(test.date); season != test.season {
+			t.Errorf("CurrentSeason(%s) = %s, want %s", test.date.Format(time.DateOnly), season, test.season)
+		}
+	}
+}
+
+func TestSubmitKeysSeasons(t *testing.T) {
+	lb, server := newTestLeaderboard(t)
+
+	if err := lb.Submit("player", 1600, "2026-Q3", 1550); err != nil {
+		t.Fatal(err)
+	}
+	if err := lb.Submit("player", 1650, "2026-Q4", 1530); err != nil {
+		t.Fatal(err)
+	}
+
+	// The global rating is overwritten while every season keeps its own.
+	for key, want := range map[string]float64{
+		"leaderboard:global":         1650,
+		"leaderboard:season:2026-Q3": 1550,
+		"leaderboard:season:2026-Q4": 1530,
+	} {
+		score, err := server.ZScore(key, "player")
+		if err != nil {
+			t.Fatalf("reading %s: %v", key, err)
+		}
+		if score != want {
+			t.Errorf("%s holds %v, want %v", key, score, want)
+		}
+	}
+}
+
+func TestPage(t *testing.T) {
+	lb, _ := newTestLeaderboard(t)
+	submitPlayers(t, lb, "2026-Q4")
+
+	for _, test := range []struct {
+		season   string
+		page     int
+		pageSize int
+		want     []string
+		total    int64
+	}{
+		{"", 1, 3, []string{"player-9", "player-8", "player-7"}, 10},
+		{"", 2, 3, []string{"player-6", "player-5", "player-4"}, 10},
+		// The last page is short and the ones past it are empty.
+		{"", 4, 3, []string{"player-0"}, 10},
+		{"", 5, 3, []string{}, 10},
+		{"2026-Q4", 1, 3, []string{"player-0", "player-1", "player-2"}, 10},
+		{"2026-Q3", 1, 3, []string{}, 0},
+	} {
+		entries, total, err := lb.Page(test.season, test.page, test.pageSize)
+		if err != nil {
+			t.Fatalf("page %d of %q: %v", test.page, test.season, err)
+		}
+
+		first := int64((test.page-1)*test.pageSize) + 1
+		if ids := userIDs(t, entries, first); !equalIDs(ids, test.want) {
+			t.Errorf("page %d of %q lists %v, want %v", test.page, test.season, ids, test.want)
+		}
+		if total != test.total {
+			t.Errorf("%q counts %d users, want %d", test.season, total, test.total)
+		}
+	}
+
+	entries, _, err := lb.Page("", 1, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if entries[0].Rating != 1900 {
+		t.Fatalf("top rating is %v, want 1900", entries[0].Rating)
+	}
+}
+
+func TestAround(t *testing.T) {
+	lb, _ := newTestLeaderboard(t)
+	submitPlayers(t, lb, "2026-Q4")
+
+	for _, test := range []struct {
+		season string
+		userID string
+		first  int64
+		want   []string
+	}{
+		{"", "player-5", 3, []string{"player-7", "player-6", "player-5", "player-4", "player-3"}},
+		// The window is cut at both ends of the leaderboard.
+		{"", "player-9", 1, []string{"player-9", "player-8", "player-7"}},
+		{"", "player-0", 8, []string{"player-2", "player-1", "player-0"}},
+		{"2026-Q4", "player-0", 1, []string{"player-0", "player-1", "player-2"}},
+	} {
+		entries, err := lb.Around(test.season, test.userID, 2)
+		if err != nil {
+			t.Fatalf("around %s in %q: %v", test.userID, test.season, err)
+		}
+		if ids := userIDs(t, entries, test.first); !equalIDs(ids, test.want) {
+			t.Errorf("around %s in %q lists %v, want %v", test.userID, test.season, ids, test.want)
+		}
+	}
+
+	entries, err := lb.Around("", "stranger", 2)
+	if err != nil || entries != nil {
+		t.Fatalf("around an unranked user got %v, %v, want nothing", entries, err)
+	}
+}
+
+func TestRank(t *testing.T) {
+	lb, _ := newTestLeaderboard(t)
+	submitPlayers(t, lb, "2026-Q4")
+
+	for _, test := range []struct {
+		season string
+		userID string
+		want   Entry
+	}{
+		{"", "player-9", Entry{Rank: 1, UserID: "player-9", Rating: 1900}},
+		{"", "player-2", Entry{Rank: 8, UserID: "player-2", Rating: 1200}},
+		{"2026-Q4", "player-2", Entry{Rank: 3, UserID: "player-2", Rating: 1800}},
+	} {
+		entry, err := lb.Rank(test.season, test.userID)
+		if err != nil {
+			t.Fatalf("rank of %s in %q: %v", test.userID, test.season, err)
+		}
+		if entry == nil || *entry != test.want {
+			t.Errorf("rank of %s in %q is %+v, want %+v", test.userID, test.season, entry, test.want)
+		}
+	}
+
+	for _, season := range []string{"", "2026-Q3"} {
+		entry, err := lb.Rank(season, "stranger")
+		if err != nil || entry != nil {
+			t.Fatalf("rank of an unranked user in %q is %+v, %v, want nothing", season, entry, err)
+		}
+	}
+}
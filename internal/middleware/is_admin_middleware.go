@@ -0,0 +1,40 @@
+package middleware
+
+import (
+	"ais-summoner/internal/database"
+	"ais-summoner/internal/models"
+	"net/http"
+
+	"github.com/gin-contrib/sessions"
+	"github.com/gin-gonic/gin"
+)
+
+// IsAdmin is a middleware that only lets through requests whose
+// session belongs to a user with the admin role.
+func IsAdmin(mongodb *database.MongoDB) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		profile, ok := sessions.Default(ctx).Get("profile").(map[string]interface{})
+		if !ok {
+			ctx.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "authentication required"})
+			return
+		}
+
+		subject, _ := profile["sub"].(string)
+		if subject == "" {
+			ctx.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "authentication required"})
+			return
+		}
+
+		user, err := mongodb.UserRepository().GetBySubject(ctx, subject)
+		if err != nil {
+			ctx.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		if user == nil || user.Role != models.RoleAdmin {
+			ctx.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "admin role required"})
+			return
+		}
+
+		ctx.Next()
+	}
+}
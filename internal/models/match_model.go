@@ -0,0 +1,29 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type Match struct {
+	ID           primitive.ObjectID `json:"id" bson:"_id,omitempty"`
+	RoomID       string             `json:"roomId" bson:"roomId"`
+	TerrainID    primitive.ObjectID `json:"terrainId" bson:"terrainId"`
+	Ranked       bool               `json:"ranked" bson:"ranked"`
+	Participants []MatchParticipant `json:"participants" bson:"participants"`
+	WinnerID     string             `json:"winnerId,omitempty" bson:"winnerId,omitempty"`
+	StartedAt    time.Time          `json:"startedAt" bson:"startedAt"`
+	EndedAt      time.Time          `json:"endedAt" bson:"endedAt"`
+	DurationMs   int64              `json:"durationMs" bson:"durationMs"`
+	CreatedAt    time.Time          `json:"createdAt" bson:"createdAt"`
+}
+
+// MatchParticipant is a player of a match. Bots have no user behind their
+// UserID.
+type MatchParticipant struct {
+	UserID    string      `json:"userId" bson:"userId"`
+	Bot       bool        `json:"bot,omitempty" bson:"bot,omitempty"`
+	Placement int         `json:"placement" bson:"placement"`
+	Stats     PlayerStats `json:"stats" bson:"stats"`
+}
@@ -0,0 +1,8 @@
+package models
+
+type Rating struct {
+	Rating      float64 `json:"rating" bson:"rating"`
+	Deviation   float64 `json:"deviation" bson:"deviation"`
+	Volatility  float64 `json:"volatility" bson:"volatility"`
+	GamesPlayed int     `json:"gamesPlayed" bson:"gamesPlayed"`
+}
@@ -0,0 +1,19 @@
+package models
+
+// PlayerStats are the battle statistics of one player in one match.
+type PlayerStats struct {
+	Kills  int     `json:"kills" bson:"kills"`
+	Deaths int     `json:"deaths" bson:"deaths"`
+	Damage float64 `json:"damage" bson:"damage"`
+	Dashes int     `json:"dashes" bson:"dashes"`
+}
+
+// UserStats are the lifetime statistics of a user across all matches.
+type UserStats struct {
+	Matches int     `json:"matches" bson:"matches"`
+	Wins    int     `json:"wins" bson:"wins"`
+	Kills   int     `json:"kills" bson:"kills"`
+	Deaths  int     `json:"deaths" bson:"deaths"`
+	Damage  float64 `json:"damage" bson:"damage"`
+	Dashes  int     `json:"dashes" bson:"dashes"`
+}
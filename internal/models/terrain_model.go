@@ -6,11 +6,61 @@ import (
 	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
+// Terrain coordinates live on the horizontal plane, so Vector2.Y is the world
+// Z axis. Every outline, spawn point and zone is stored unrotated and turned
+// by Rotation degrees about the Y axis when the terrain is used.
 type Terrain struct {
-	ID        primitive.ObjectID `json:"id" bson:"_id,omitempty"`
-	Name      string             `json:"name" bson:"name"`
-	Rotation  float64            `json:"rotation" bson:"rotation"`
-	Points    []Vector2          `json:"points" bson:"points"`
-	CreatedAt time.Time          `json:"createdAt" bson:"createdAt"`
-	UpdatedAt time.Time          `json:"updatedAt" bson:"updatedAt"`
+	ID       primitive.ObjectID `json:"id" bson:"_id,omitempty"`
+	Name     string             `json:"name" bson:"name"`
+	Rotation float64            `json:"rotation" bson:"rotation"`
+	// Boundary is the outer outline players are kept inside of.
+	Boundary []Vector2 `json:"boundary" bson:"boundary"`
+	// Holes are gaps in the floor that cannot be walked over but can be
+	// seen and dashed across.
+	Holes [][]Vector2 `json:"holes" bson:"holes"`
+	// Obstacles are solid and block both movement and sight.
+	Obstacles   [][]Vector2  `json:"obstacles" bson:"obstacles"`
+	SpawnPoints []SpawnPoint `json:"spawnPoints" bson:"spawnPoints"`
+	Zones       []Zone       `json:"zones" bson:"zones"`
+	// Points is the single outline terrains had before Boundary. It is only
+	// read to migrate legacy documents and requests.
+	Points    []Vector2 `json:"points,omitempty" bson:"points,omitempty"`
+	CreatedAt time.Time `json:"createdAt" bson:"createdAt"`
+	UpdatedAt time.Time `json:"updatedAt" bson:"updatedAt"`
+}
+
+// MigrateLegacyOutline moves a legacy single outline into Boundary. It
+// reports whether the terrain changed.
+func (terrain *Terrain) MigrateLegacyOutline() bool {
+	if len(terrain.Points) == 0 {
+		return false
+	}
+
+	if len(terrain.Boundary) == 0 {
+		terrain.Boundary = terrain.Points
+	}
+	terrain.Points = nil
+
+	return true
+}
+
+type SpawnPoint struct {
+	Position Vector3 `json:"position" bson:"position"`
+	// Facing is the yaw in degrees the player looks towards after spawning.
+	Facing float64  `json:"facing" bson:"facing"`
+	Tags   []string `json:"tags" bson:"tags"`
+}
+
+type ZoneKind string
+
+const (
+	ZoneCapture ZoneKind = "capture"
+	ZoneHazard  ZoneKind = "hazard"
+	ZoneSafe    ZoneKind = "safe"
+)
+
+type Zone struct {
+	Name   string    `json:"name" bson:"name"`
+	Kind   ZoneKind  `json:"kind" bson:"kind"`
+	Points []Vector2 `json:"points" bson:"points"`
 }
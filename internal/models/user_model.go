@@ -6,11 +6,22 @@ import (
 	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
+// RoleAdmin grants access to the terrain editing endpoints. Admins are
+// promoted directly in the database.
+const RoleAdmin = "admin"
+
 type User struct {
-	ID        primitive.ObjectID `json:"id" bson:"_id,omitempty"`
-	Username  string             `json:"username" bson:"username"`
-	Email     string             `json:"email" bson:"email"`
-	Metadata  UserMetadata       `json:"metadata" bson:"metadata"`
-	CreatedAt time.Time          `json:"createdAt" bson:"createdAt"`
-	UpdatedAt time.Time          `json:"updatedAt" bson:"updatedAt"`
+	ID       primitive.ObjectID `json:"id" bson:"_id,omitempty"`
+	Subject  string             `json:"-" bson:"subject"`
+	Username string             `json:"username" bson:"username"`
+	Email    string             `json:"email" bson:"email"`
+	Role     string             `json:"role" bson:"role,omitempty"`
+	Metadata UserMetadata       `json:"metadata" bson:"metadata"`
+	Rating   Rating             `json:"rating" bson:"rating"`
+	// SeasonRatings holds the rating of every season played, by season
+	// name. Each season starts from a default rating.
+	SeasonRatings map[string]Rating `json:"seasonRatings,omitempty" bson:"seasonRatings,omitempty"`
+	Stats         UserStats         `json:"stats" bson:"stats"`
+	CreatedAt     time.Time         `json:"createdAt" bson:"createdAt"`
+	UpdatedAt     time.Time         `json:"updatedAt" bson:"updatedAt"`
 }
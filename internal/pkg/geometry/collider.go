@@ -0,0 +1,236 @@
+package geometry
+
+import (
+	"ais-summoner/internal/models"
+	"math"
+)
+
+// resolveIterations bounds how often a position is pushed away from the
+// edges it overlaps; corners need more than one push to settle.
+const resolveIterations = 4
+
+// maxStepWithoutRadius is the sub-step length used for point-sized bodies.
+const maxStepWithoutRadius = 0.25
+
+// insideMargin is how far past the nearest edge a body found outside the
+// walkable area is put back, on top of its radius.
+const insideMargin = 1e-3
+
+// Collider keeps circular bodies inside a boundary polygon and outside any
+// obstacle polygons. The first obstacles may be holes, which only block
+// walking: dashes fly over them. It looks edges up through a Grid and
+// shares its scratch state, so a collider is not safe for concurrent use.
+type Collider struct {
+	grid *Grid
+	// owners maps every edge to its polygon: 0 is the boundary and i+1
+	// the obstacle i.
+	owners   []int
+	crossing []bool
+	// holes is how many of the obstacles are holes.
+	holes int
+}
+
+func NewCollider(boundary Polygon, obstacles ...Polygon) *Collider {
+	collider := &Collider{crossing: make([]bool, len(obstacles)+1)}
+
+	var edges []Segment
+	for i, polygon := range append([]Polygon{boundary}, obstacles...) {
+		for _, edge := range polygon.Edges() {
+			edges = append(edges, edge)
+			collider.owners = append(collider.owners, i)
+		}
+	}
+	collider.grid = NewGrid(edges)
+
+	return collider
+}
+
+// Grid returns the broadphase over the edges of every polygon.
+func (collider *Collider) Grid() *Grid {
+	return collider.grid
+}
+
+// NewTerrainCollider builds a collider from the rotated terrain, whose
+// holes can be dashed across. It returns nil when the terrain has no
+// usable boundary.
+func NewTerrainCollider(terrain *models.Terrain) *Collider {
+	if len(terrain.Boundary) < 3 {
+		return nil
+	}
+
+	shape := NewShape(terrain)
+	collider := NewCollider(shape.Boundary, shape.Blockers()...)
+	collider.holes = len(shape.Holes)
+
+	return collider
+}
+
+// Inside reports whether the point lies in the walkable area.
+func (collider *Collider) Inside(point models.Vector2) bool {
+	return collider.inside(point, false)
+}
+
+// inside applies the even-odd rule of Polygon.Contains to every polygon at
+// once, counting only the edges the grid finds to the right of the point.
+// Points over a hole count as inside when overHoles is set.
+func (collider *Collider) inside(point models.Vector2, overHoles bool) bool {
+	for i := range collider.crossing {
+		collider.crossing[i] = false
+	}
+
+	bounds := collider.grid.bounds
+	if !bounds.Contains(point) {
+		return false
+	}
+
+	ray := AABB{Min: point, Max: models.Vector2{X: bounds.Max.X, Y: point.Y}}
+	for _, index := range collider.grid.Query(ray) {
+		edge := collider.grid.Edge(index)
+		a, b := edge.A, edge.B
+		if (a.Y > point.Y) != (b.Y > point.Y) &&
+			point.X < (b.X-a.X)*(point.Y-a.Y)/(b.Y-a.Y)+a.X {
+			owner := collider.owners[index]
+			collider.crossing[owner] = !collider.crossing[owner]
+		}
+	}
+
+	if !collider.crossing[0] {
+		return false
+	}
+	for i, inside := range collider.crossing[1:] {
+		if inside && !(overHoles && collider.isHole(i+1)) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// isHole reports whether the polygon owning an edge is a hole.
+func (collider *Collider) isHole(owner int) bool {
+	return owner >= 1 && owner <= collider.holes
+}
+
+// Move displaces a body of the given radius by delta and returns where it
+// ends up. The displacement is split into steps no longer than half the
+// radius so fast bodies cannot tunnel through thin walls, and after every
+// step the body is pushed out of the edges it overlaps, which makes it
+// slide along walls instead of stopping dead. A body that starts outside
+// the walkable area is first put back inside, next to the closest edge.
+func (collider *Collider) Move(from models.Vector2, delta models.Vector2, radius float64) models.Vector2 {
+	if !collider.Inside(from) {
+		return collider.pushInside(from, radius)
+	}
+
+	return collider.move(from, delta, radius, false)
+}
+
+// Dash displaces a body like Move, except that it flies over holes. A dash
+// that would come down over a hole moves as far as Move would instead.
+func (collider *Collider) Dash(from models.Vector2, delta models.Vector2, radius float64) models.Vector2 {
+	if !collider.Inside(from) {
+		return collider.pushInside(from, radius)
+	}
+
+	if landing := collider.resolve(collider.move(from, delta, radius, true), radius, false); collider.Inside(landing) {
+		return landing
+	}
+
+	return collider.move(from, delta, radius, false)
+}
+
+func (collider *Collider) move(from models.Vector2, delta models.Vector2, radius float64, overHoles bool) models.Vector2 {
+	maxStep := radius / 2
+	if maxStep <= 0 {
+		maxStep = maxStepWithoutRadius
+	}
+
+	length := math.Hypot(delta.X, delta.Y)
+	steps := int(math.Ceil(length / maxStep))
+	if steps == 0 {
+		return from
+	}
+	step := models.Vector2{X: delta.X / float64(steps), Y: delta.Y / float64(steps)}
+
+	position := from
+	for i := 0; i < steps; i++ {
+		next := collider.resolve(models.Vector2{X: position.X + step.X, Y: position.Y + step.Y}, radius, overHoles)
+		if !collider.inside(next, overHoles) {
+			break
+		}
+		position = next
+	}
+
+	return position
+}
+
+// pushInside moves a point outside the walkable area across the closest
+// edge, far enough for a body of the given radius to clear it. The point
+// stays where it is when no side of that edge is walkable.
+func (collider *Collider) pushInside(point models.Vector2, radius float64) models.Vector2 {
+	index, distance, found := collider.grid.Nearest(point, math.Inf(1))
+	if !found {
+		return point
+	}
+
+	edge := collider.grid.Edge(index)
+	closest := edge.ClosestPoint(point)
+	direction := models.Vector2{X: closest.X - point.X, Y: closest.Y - point.Y}
+	if distance < epsilon {
+		// On the edge itself either side may be the walkable one.
+		direction = models.Vector2{X: edge.A.Y - edge.B.Y, Y: edge.B.X - edge.A.X}
+	}
+	length := math.Hypot(direction.X, direction.Y)
+	if length < epsilon {
+		return point
+	}
+
+	offset := radius + insideMargin
+	for _, sign := range []float64{1, -1} {
+		candidate := collider.resolve(models.Vector2{
+			X: closest.X + sign*direction.X/length*offset,
+			Y: closest.Y + sign*direction.Y/length*offset,
+		}, radius, false)
+		if collider.Inside(candidate) {
+			return candidate
+		}
+	}
+
+	return point
+}
+
+// resolve pushes the point away from every edge closer than radius, other
+// than the edges of holes when overHoles is set. The candidates of an
+// iteration are gathered around the point with room for the pushes made
+// during that iteration.
+func (collider *Collider) resolve(point models.Vector2, radius float64, overHoles bool) models.Vector2 {
+	if radius <= 0 {
+		return point
+	}
+
+	for i := 0; i < resolveIterations; i++ {
+		moved := false
+		for _, index := range collider.grid.Query(AroundPoint(point, 2*radius)) {
+			if overHoles && collider.isHole(collider.owners[index]) {
+				continue
+			}
+			closest := collider.grid.Edge(index).ClosestPoint(point)
+			dx, dy := point.X-closest.X, point.Y-closest.Y
+			distance := math.Hypot(dx, dy)
+			if distance >= radius || distance < epsilon {
+				continue
+			}
+
+			push := (radius - distance) / distance
+			point.X += dx * push
+			point.Y += dy * push
+			moved = true
+		}
+
+		if !moved {
+			break
+		}
+	}
+
+	return point
+}
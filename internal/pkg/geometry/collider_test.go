@@ -0,0 +1,74 @@
+package geometry
+
+import (
+	"ais-summoner/internal/models"
+	"math"
+	"testing"
+)
+
+// trench is a 40 by 20 terrain crossed by a hole 2 wide at x 0 to 2, with
+// an obstacle of the same size at x 10 to 12.
+func trench() *models.Terrain {
+	return &models.Terrain{
+		Name:     "trench",
+		Boundary: []models.Vector2{{X: -20, Y: -10}, {X: 20, Y: -10}, {X: 20, Y: 10}, {X: -20, Y: 10}},
+		Holes:    [][]models.Vector2{{{X: 0, Y: -9}, {X: 2, Y: -9}, {X: 2, Y: 9}, {X: 0, Y: 9}}},
+		Obstacles: [][]models.Vector2{
+			{{X: 10, Y: -9}, {X: 12, Y: -9}, {X: 12, Y: 9}, {X: 10, Y: 9}},
+		},
+	}
+}
+
+func near(a models.Vector2, b models.Vector2) bool {
+	return math.Abs(a.X-b.X) < 1e-6 && math.Abs(a.Y-b.Y) < 1e-6
+}
+
+func TestColliderDashesOverHoles(t *testing.T) {
+	collider := NewTerrainCollider(trench())
+	from := models.Vector2{X: -2}
+	const radius = 0.5
+
+	// Walking stops at the edge of the hole.
+	if to := collider.Move(from, models.Vector2{X: 6}, radius); to.X > -radius+1e-6 {
+		t.Fatalf("walked over the hole to %+v", to)
+	}
+
+	// A dash clears it.
+	if to := collider.Dash(from, models.Vector2{X: 6}, radius); !near(to, models.Vector2{X: 4}) {
+		t.Fatalf("dashed to %+v, want to x 4", to)
+	}
+
+	// A dash coming down over the hole stops at its edge like a walk.
+	if to, walked := collider.Dash(from, models.Vector2{X: 3}, radius), collider.Move(from, models.Vector2{X: 3}, radius); to != walked {
+		t.Fatalf("dash into the hole ended at %+v, walking at %+v", to, walked)
+	}
+
+	// Obstacles stop dashes too.
+	if to := collider.Dash(models.Vector2{X: 8}, models.Vector2{X: 6}, radius); to.X > 10-radius+1e-6 {
+		t.Fatalf("dashed through the obstacle to %+v", to)
+	}
+}
+
+func TestColliderPushesOutsideBodiesBackIn(t *testing.T) {
+	collider := NewTerrainCollider(trench())
+	const radius = 0.5
+
+	for _, from := range []models.Vector2{
+		{X: -25, Y: 3},   // past the boundary
+		{X: 10.2, Y: -3}, // inside the obstacle
+		{X: 1.9, Y: 0},   // inside the hole
+		{X: 20, Y: 0},    // on the boundary
+	} {
+		_, outside, _ := collider.Grid().Nearest(from, math.Inf(1))
+		to := collider.Move(from, models.Vector2{X: 100}, radius)
+		if !collider.Inside(to) {
+			t.Fatalf("body at %+v stayed outside at %+v", from, to)
+		}
+		if _, distance, _ := collider.Grid().Nearest(to, math.Inf(1)); distance < radius {
+			t.Fatalf("body at %+v put back at %+v, %v from a wall", from, to, distance)
+		}
+		if math.Hypot(to.X-from.X, to.Y-from.Y) > outside+radius+0.01 {
+			t.Fatalf("body at %+v moved to %+v, farther than the closest edge", from, to)
+		}
+	}
+}
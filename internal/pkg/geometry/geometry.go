@@ -0,0 +1,218 @@
+// Package geometry answers the 2D questions the game asks about terrains.
+// Terrain points live on the horizontal plane, so Vector2.Y is the world Z
+// axis.
+package geometry
+
+import (
+	"ais-summoner/internal/models"
+	"math"
+)
+
+const epsilon = 1e-9
+
+type Polygon []models.Vector2
+
+type Segment struct {
+	A models.Vector2
+	B models.Vector2
+}
+
+// Rotate turns the point around the origin by the given angle in degrees,
+// matching a Unity rotation about the Y axis (clockwise seen from above).
+func Rotate(point models.Vector2, degrees float64) models.Vector2 {
+	if degrees == 0 {
+		return point
+	}
+
+	sin, cos := math.Sincos(degrees * math.Pi / 180)
+	return models.Vector2{
+		X: point.X*cos + point.Y*sin,
+		Y: -point.X*sin + point.Y*cos,
+	}
+}
+
+// NewPolygon rotates the points into a polygon.
+func NewPolygon(points []models.Vector2, rotation float64) Polygon {
+	polygon := make(Polygon, len(points))
+	for i, point := range points {
+		polygon[i] = Rotate(point, rotation)
+	}
+
+	return polygon
+}
+
+// Edges returns the segments between consecutive vertices, closing the ring.
+func (polygon Polygon) Edges() []Segment {
+	edges := make([]Segment, len(polygon))
+	for i := range polygon {
+		edges[i] = Segment{A: polygon[i], B: polygon[(i+1)%len(polygon)]}
+	}
+
+	return edges
+}
+
+// Contains reports whether the point is inside the polygon using the
+// even-odd rule.
+func (polygon Polygon) Contains(point models.Vector2) bool {
+	inside := false
+	for i, j := 0, len(polygon)-1; i < len(polygon); j, i = i, i+1 {
+		a, b := polygon[i], polygon[j]
+		if (a.Y > point.Y) != (b.Y > point.Y) &&
+			point.X < (b.X-a.X)*(point.Y-a.Y)/(b.Y-a.Y)+a.X {
+			inside = !inside
+		}
+	}
+
+	return inside
+}
+
+// SignedArea is positive for counter-clockwise polygons.
+func (polygon Polygon) SignedArea() float64 {
+	var area float64
+	for i := range polygon {
+		a, b := polygon[i], polygon[(i+1)%len(polygon)]
+		area += a.X*b.Y - b.X*a.Y
+	}
+
+	return area / 2
+}
+
+// Bounds returns the box around the polygon.
+func (polygon Polygon) Bounds() AABB {
+	var box AABB
+	for i, point := range polygon {
+		if i == 0 {
+			box = AABB{Min: point, Max: point}
+			continue
+		}
+		box = box.Union(AABB{Min: point, Max: point})
+	}
+
+	return box
+}
+
+// Centroid returns the average of the vertices.
+func (polygon Polygon) Centroid() models.Vector2 {
+	var centroid models.Vector2
+	for _, point := range polygon {
+		centroid.X += point.X / float64(len(polygon))
+		centroid.Y += point.Y / float64(len(polygon))
+	}
+
+	return centroid
+}
+
+// ClosestPoint returns the point of the segment closest to point.
+func (segment Segment) ClosestPoint(point models.Vector2) models.Vector2 {
+	dx, dy := segment.B.X-segment.A.X, segment.B.Y-segment.A.Y
+	lengthSquared := dx*dx + dy*dy
+	if lengthSquared < epsilon {
+		return segment.A
+	}
+
+	t := ((point.X-segment.A.X)*dx + (point.Y-segment.A.Y)*dy) / lengthSquared
+	t = math.Max(0, math.Min(1, t))
+	return models.Vector2{X: segment.A.X + t*dx, Y: segment.A.Y + t*dy}
+}
+
+// Distance returns how far point is from the segment.
+func (segment Segment) Distance(point models.Vector2) float64 {
+	closest := segment.ClosestPoint(point)
+	return math.Hypot(point.X-closest.X, point.Y-closest.Y)
+}
+
+// Intersect returns the point where two segments cross. Collinear
+// overlapping segments report their first shared point.
+func (segment Segment) Intersect(other Segment) (models.Vector2, bool) {
+	r := models.Vector2{X: segment.B.X - segment.A.X, Y: segment.B.Y - segment.A.Y}
+	s := models.Vector2{X: other.B.X - other.A.X, Y: other.B.Y - other.A.Y}
+	qp := models.Vector2{X: other.A.X - segment.A.X, Y: other.A.Y - segment.A.Y}
+
+	denominator := cross(r, s)
+	if math.Abs(denominator) < epsilon {
+		if math.Abs(cross(qp, r)) >= epsilon {
+			return models.Vector2{}, false
+		}
+
+		// Collinear: project the other segment onto this one.
+		rr := r.X*r.X + r.Y*r.Y
+		if rr < epsilon {
+			return segment.A, other.Distance(segment.A) < epsilon
+		}
+		t0 := (qp.X*r.X + qp.Y*r.Y) / rr
+		t1 := t0 + (s.X*r.X+s.Y*r.Y)/rr
+		lo, hi := math.Min(t0, t1), math.Max(t0, t1)
+		if hi < 0 || lo > 1 {
+			return models.Vector2{}, false
+		}
+		t := math.Max(0, lo)
+		return models.Vector2{X: segment.A.X + t*r.X, Y: segment.A.Y + t*r.Y}, true
+	}
+
+	t := cross(qp, s) / denominator
+	u := cross(qp, r) / denominator
+	if t < -epsilon || t > 1+epsilon || u < -epsilon || u > 1+epsilon {
+		return models.Vector2{}, false
+	}
+
+	return models.Vector2{X: segment.A.X + t*r.X, Y: segment.A.Y + t*r.Y}, true
+}
+
+// CircleIntersects reports whether a circle overlaps the polygon, either by
+// touching an edge or by lying inside it.
+func (polygon Polygon) CircleIntersects(center models.Vector2, radius float64) bool {
+	if polygon.Contains(center) {
+		return true
+	}
+
+	for _, edge := range polygon.Edges() {
+		if edge.Distance(center) < radius {
+			return true
+		}
+	}
+
+	return false
+}
+
+// IsSimple reports whether no two edges of the polygon cross and no edge
+// doubles back over the one before it.
+func (polygon Polygon) IsSimple() bool {
+	edges := polygon.Edges()
+	for i := range edges {
+		for j := i + 1; j < len(edges); j++ {
+			switch {
+			case j == i+1:
+				if folds(edges[i], edges[j]) {
+					return false
+				}
+			case i == 0 && j == len(edges)-1:
+				if folds(edges[j], edges[i]) {
+					return false
+				}
+			default:
+				if _, crosses := edges[i].Intersect(edges[j]); crosses {
+					return false
+				}
+			}
+		}
+	}
+
+	return true
+}
+
+// folds reports whether next, which starts where previous ends, runs back
+// along previous.
+func folds(previous Segment, next Segment) bool {
+	r := models.Vector2{X: previous.B.X - previous.A.X, Y: previous.B.Y - previous.A.Y}
+	s := models.Vector2{X: next.B.X - next.A.X, Y: next.B.Y - next.A.Y}
+
+	return math.Abs(cross(r, s)) < epsilon && dot(r, s) <= 0
+}
+
+func cross(a models.Vector2, b models.Vector2) float64 {
+	return a.X*b.Y - a.Y*b.X
+}
+
+func dot(a models.Vector2, b models.Vector2) float64 {
+	return a.X*b.X + a.Y*b.Y
+}
@@ -0,0 +1,348 @@
+package geometry
+
+import (
+	"ais-summoner/internal/models"
+	"math"
+	"sort"
+)
+
+const (
+	// minCellSize keeps tiny edges from producing a needlessly fine grid.
+	minCellSize = 0.5
+	// maxGridCells bounds the memory of a grid over a huge, sparse map.
+	maxGridCells = 1 << 16
+)
+
+// AABB is an axis-aligned bounding box.
+type AABB struct {
+	Min models.Vector2
+	Max models.Vector2
+}
+
+// Bounds returns the box around the segment.
+func (segment Segment) Bounds() AABB {
+	return AABB{
+		Min: models.Vector2{X: math.Min(segment.A.X, segment.B.X), Y: math.Min(segment.A.Y, segment.B.Y)},
+		Max: models.Vector2{X: math.Max(segment.A.X, segment.B.X), Y: math.Max(segment.A.Y, segment.B.Y)},
+	}
+}
+
+// AroundPoint returns the box of the given half size centred on point.
+func AroundPoint(point models.Vector2, halfSize float64) AABB {
+	return AABB{
+		Min: models.Vector2{X: point.X - halfSize, Y: point.Y - halfSize},
+		Max: models.Vector2{X: point.X + halfSize, Y: point.Y + halfSize},
+	}
+}
+
+// Union returns the box covering both boxes.
+func (box AABB) Union(other AABB) AABB {
+	return AABB{
+		Min: models.Vector2{X: math.Min(box.Min.X, other.Min.X), Y: math.Min(box.Min.Y, other.Min.Y)},
+		Max: models.Vector2{X: math.Max(box.Max.X, other.Max.X), Y: math.Max(box.Max.Y, other.Max.Y)},
+	}
+}
+
+// Contains reports whether the point lies in the box.
+func (box AABB) Contains(point models.Vector2) bool {
+	return point.X >= box.Min.X && point.X <= box.Max.X && point.Y >= box.Min.Y && point.Y <= box.Max.Y
+}
+
+// Intersects reports whether the boxes overlap or touch.
+func (box AABB) Intersects(other AABB) bool {
+	return box.Min.X <= other.Max.X && other.Min.X <= box.Max.X &&
+		box.Min.Y <= other.Max.Y && other.Min.Y <= box.Max.Y
+}
+
+// RayHit describes where a ray first meets an edge.
+type RayHit struct {
+	Point    models.Vector2
+	Distance float64
+	Edge     int
+}
+
+// Grid is a uniform-grid broadphase over a fixed set of edges. Edges are
+// referred to by their index in the slice the grid was built from. Queries
+// return edges in ascending index order, so callers that walk the result
+// behave exactly as a scan over every edge would. A grid reuses scratch
+// state between queries and is not safe for concurrent use.
+type Grid struct {
+	edges    []Segment
+	bounds   AABB
+	cellSize float64
+	columns  int
+	rows     int
+	cells    [][]int
+	stamps   []uint32
+	stamp    uint32
+	scratch  []int
+}
+
+// NewGrid indexes the edges in cells about as large as the average edge.
+func NewGrid(edges []Segment) *Grid {
+	grid := &Grid{edges: edges, stamps: make([]uint32, len(edges))}
+	if len(edges) == 0 {
+		return grid
+	}
+
+	var total float64
+	grid.bounds = edges[0].Bounds()
+	for _, edge := range edges {
+		grid.bounds = grid.bounds.Union(edge.Bounds())
+		total += math.Hypot(edge.B.X-edge.A.X, edge.B.Y-edge.A.Y)
+	}
+
+	width := grid.bounds.Max.X - grid.bounds.Min.X
+	height := grid.bounds.Max.Y - grid.bounds.Min.Y
+	grid.cellSize = math.Max(total/float64(len(edges)), minCellSize)
+	for math.Ceil(width/grid.cellSize+1)*math.Ceil(height/grid.cellSize+1) > maxGridCells {
+		grid.cellSize *= 2
+	}
+	grid.columns = int(width/grid.cellSize) + 1
+	grid.rows = int(height/grid.cellSize) + 1
+	grid.cells = make([][]int, grid.columns*grid.rows)
+
+	for i, edge := range edges {
+		grid.visitCells(edge.Bounds(), func(cell int) {
+			grid.cells[cell] = append(grid.cells[cell], i)
+		})
+	}
+
+	return grid
+}
+
+// Edge returns the indexed edge.
+func (grid *Grid) Edge(index int) Segment {
+	return grid.edges[index]
+}
+
+// Query returns the indices of the edges whose boxes overlap the box. The
+// slice is reused by the next query.
+func (grid *Grid) Query(box AABB) []int {
+	grid.begin()
+	grid.visitCells(box, func(cell int) {
+		for _, index := range grid.cells[cell] {
+			if grid.mark(index) && grid.edges[index].Bounds().Intersects(box) {
+				grid.scratch = append(grid.scratch, index)
+			}
+		}
+	})
+
+	sort.Ints(grid.scratch)
+	return grid.scratch
+}
+
+// Raycast finds the first edge hit by the ray within maxDistance. The
+// direction does not need to be normalized.
+func (grid *Grid) Raycast(origin models.Vector2, direction models.Vector2, maxDistance float64) (RayHit, bool) {
+	length := math.Hypot(direction.X, direction.Y)
+	if len(grid.edges) == 0 || length < epsilon || maxDistance <= 0 {
+		return RayHit{}, false
+	}
+	direction = models.Vector2{X: direction.X / length, Y: direction.Y / length}
+	ray := Segment{A: origin, B: models.Vector2{X: origin.X + direction.X*maxDistance, Y: origin.Y + direction.Y*maxDistance}}
+
+	// Clip the ray to the grid so the walk starts in a valid cell.
+	enter, exit, ok := grid.clip(origin, direction, maxDistance)
+	if !ok {
+		return RayHit{}, false
+	}
+
+	start := models.Vector2{X: origin.X + direction.X*enter, Y: origin.Y + direction.Y*enter}
+	column, row := grid.cellOf(start)
+	stepColumn, nextX, deltaX := grid.dda(start.X, direction.X, grid.bounds.Min.X, column)
+	stepRow, nextY, deltaY := grid.dda(start.Y, direction.Y, grid.bounds.Min.Y, row)
+	nextX += enter
+	nextY += enter
+
+	best := RayHit{Distance: math.Inf(1), Edge: -1}
+	grid.begin()
+	for column >= 0 && column < grid.columns && row >= 0 && row < grid.rows {
+		for _, index := range grid.cells[row*grid.columns+column] {
+			if !grid.mark(index) {
+				continue
+			}
+			point, hit := ray.Intersect(grid.edges[index])
+			if !hit {
+				continue
+			}
+			distance := math.Hypot(point.X-origin.X, point.Y-origin.Y)
+			if distance < best.Distance || distance == best.Distance && index < best.Edge {
+				best = RayHit{Point: point, Distance: distance, Edge: index}
+			}
+		}
+
+		cellExit := math.Min(nextX, nextY)
+		if best.Edge >= 0 && best.Distance <= cellExit || cellExit > exit {
+			break
+		}
+		if nextX < nextY {
+			column += stepColumn
+			nextX += deltaX
+		} else {
+			row += stepRow
+			nextY += deltaY
+		}
+	}
+
+	return best, best.Edge >= 0
+}
+
+// Nearest returns the index of the edge closest to point and its distance,
+// searching rings of cells outwards until no closer edge can exist. It
+// reports false when no edge lies within maxDistance.
+func (grid *Grid) Nearest(point models.Vector2, maxDistance float64) (int, float64, bool) {
+	if len(grid.edges) == 0 {
+		return -1, 0, false
+	}
+
+	column := int(math.Floor((point.X - grid.bounds.Min.X) / grid.cellSize))
+	row := int(math.Floor((point.Y - grid.bounds.Min.Y) / grid.cellSize))
+	maxRing := max(abs(column), abs(column-grid.columns+1), abs(row), abs(row-grid.rows+1))
+
+	best, bestDistance := -1, math.Inf(1)
+	grid.begin()
+	for ring := 0; ring <= maxRing; ring++ {
+		// Cells of this ring are at least ring-1 cells away from the point.
+		if float64(ring-1)*grid.cellSize > math.Min(bestDistance, maxDistance) {
+			break
+		}
+
+		grid.visitRing(column, row, ring, func(cell int) {
+			for _, index := range grid.cells[cell] {
+				if !grid.mark(index) {
+					continue
+				}
+				distance := grid.edges[index].Distance(point)
+				if distance < bestDistance || distance == bestDistance && index < best {
+					best, bestDistance = index, distance
+				}
+			}
+		})
+	}
+
+	if best < 0 || bestDistance > maxDistance {
+		return -1, 0, false
+	}
+
+	return best, bestDistance, true
+}
+
+// begin starts a query, clearing the duplicate marks of the previous one.
+func (grid *Grid) begin() {
+	grid.scratch = grid.scratch[:0]
+	grid.stamp++
+	if grid.stamp == 0 {
+		for i := range grid.stamps {
+			grid.stamps[i] = 0
+		}
+		grid.stamp = 1
+	}
+}
+
+// mark reports whether the edge is seen for the first time in this query.
+func (grid *Grid) mark(index int) bool {
+	if grid.stamps[index] == grid.stamp {
+		return false
+	}
+
+	grid.stamps[index] = grid.stamp
+	return true
+}
+
+func (grid *Grid) cellOf(point models.Vector2) (int, int) {
+	column := int((point.X - grid.bounds.Min.X) / grid.cellSize)
+	row := int((point.Y - grid.bounds.Min.Y) / grid.cellSize)
+
+	return clamp(column, 0, grid.columns-1), clamp(row, 0, grid.rows-1)
+}
+
+// visitCells calls visit for every cell overlapping the box.
+func (grid *Grid) visitCells(box AABB, visit func(cell int)) {
+	if len(grid.cells) == 0 || !box.Intersects(grid.bounds) {
+		return
+	}
+
+	minColumn, minRow := grid.cellOf(box.Min)
+	maxColumn, maxRow := grid.cellOf(box.Max)
+	for row := minRow; row <= maxRow; row++ {
+		for column := minColumn; column <= maxColumn; column++ {
+			visit(row*grid.columns + column)
+		}
+	}
+}
+
+// visitRing calls visit for every cell of the square ring at the given
+// Chebyshev distance from the cell, skipping cells outside the grid.
+func (grid *Grid) visitRing(column int, row int, ring int, visit func(cell int)) {
+	for y := row - ring; y <= row+ring; y++ {
+		if y < 0 || y >= grid.rows {
+			continue
+		}
+
+		step := 1
+		if y != row-ring && y != row+ring {
+			step = 2 * ring
+		}
+		for x := column - ring; x <= column+ring; x += max(step, 1) {
+			if x >= 0 && x < grid.columns {
+				visit(y*grid.columns + x)
+			}
+		}
+	}
+}
+
+// clip returns the range of ray distances that lie inside the grid.
+func (grid *Grid) clip(origin models.Vector2, direction models.Vector2, maxDistance float64) (float64, float64, bool) {
+	enter, exit := 0.0, maxDistance
+	if !clipAxis(origin.X, direction.X, grid.bounds.Min.X, grid.bounds.Max.X, &enter, &exit) ||
+		!clipAxis(origin.Y, direction.Y, grid.bounds.Min.Y, grid.bounds.Max.Y, &enter, &exit) {
+		return 0, 0, false
+	}
+
+	return enter, exit, enter <= exit
+}
+
+// clipAxis narrows the ray range to the slab between low and high.
+func clipAxis(position float64, direction float64, low float64, high float64, enter *float64, exit *float64) bool {
+	if math.Abs(direction) < epsilon {
+		return position >= low && position <= high
+	}
+
+	t0, t1 := (low-position)/direction, (high-position)/direction
+	if t0 > t1 {
+		t0, t1 = t1, t0
+	}
+	*enter, *exit = math.Max(*enter, t0), math.Min(*exit, t1)
+
+	return true
+}
+
+// dda returns the cell step, the ray distance to the first cell boundary
+// and the distance between boundaries along one axis.
+func (grid *Grid) dda(position float64, direction float64, origin float64, cell int) (int, float64, float64) {
+	if math.Abs(direction) < epsilon {
+		return 0, math.Inf(1), math.Inf(1)
+	}
+
+	delta := grid.cellSize / math.Abs(direction)
+	if direction > 0 {
+		boundary := origin + float64(cell+1)*grid.cellSize
+		return 1, (boundary - position) / direction, delta
+	}
+
+	boundary := origin + float64(cell)*grid.cellSize
+	return -1, (boundary - position) / direction, delta
+}
+
+func clamp(value int, low int, high int) int {
+	return min(max(value, low), high)
+}
+
+func abs(value int) int {
+	if value < 0 {
+		return -value
+	}
+
+	return value
+}
@@ -0,0 +1,140 @@
+package geometry
+
+import (
+	"ais-summoner/internal/models"
+	"fmt"
+	"math"
+	"math/rand"
+	"testing"
+)
+
+// largeMap builds the edges of a round arena of the given radius, drawn
+// with 512 points, filled with a lattice of small square pillars.
+func largeMap(radius float64, pillars int) []Segment {
+	boundary := make(Polygon, 512)
+	for i := range boundary {
+		angle := 2 * math.Pi * float64(i) / float64(len(boundary))
+		boundary[i] = models.Vector2{X: radius * math.Cos(angle), Y: radius * math.Sin(angle)}
+	}
+	edges := boundary.Edges()
+
+	spacing := 2 * radius / float64(pillars+1)
+	for i := 1; i <= pillars; i++ {
+		for j := 1; j <= pillars; j++ {
+			x, y := -radius+float64(i)*spacing, -radius+float64(j)*spacing
+			if math.Hypot(x, y) > radius-spacing {
+				continue
+			}
+			pillar := Polygon{{X: x - 1, Y: y - 1}, {X: x + 1, Y: y - 1}, {X: x + 1, Y: y + 1}, {X: x - 1, Y: y + 1}}
+			edges = append(edges, pillar.Edges()...)
+		}
+	}
+
+	return edges
+}
+
+func bruteQuery(edges []Segment, box AABB) []int {
+	var result []int
+	for i, edge := range edges {
+		if edge.Bounds().Intersects(box) {
+			result = append(result, i)
+		}
+	}
+
+	return result
+}
+
+func bruteRaycast(edges []Segment, origin models.Vector2, direction models.Vector2, maxDistance float64) (RayHit, bool) {
+	length := math.Hypot(direction.X, direction.Y)
+	ray := Segment{A: origin, B: models.Vector2{X: origin.X + direction.X/length*maxDistance, Y: origin.Y + direction.Y/length*maxDistance}}
+
+	best := RayHit{Distance: math.Inf(1), Edge: -1}
+	for i, edge := range edges {
+		point, hit := ray.Intersect(edge)
+		if !hit {
+			continue
+		}
+		if distance := math.Hypot(point.X-origin.X, point.Y-origin.Y); distance < best.Distance {
+			best = RayHit{Point: point, Distance: distance, Edge: i}
+		}
+	}
+
+	return best, best.Edge >= 0
+}
+
+func bruteNearest(edges []Segment, point models.Vector2) (int, float64) {
+	best, bestDistance := -1, math.Inf(1)
+	for i, edge := range edges {
+		if distance := edge.Distance(point); distance < bestDistance {
+			best, bestDistance = i, distance
+		}
+	}
+
+	return best, bestDistance
+}
+
+func randomPoint(rng *rand.Rand, radius float64) models.Vector2 {
+	return models.Vector2{X: (rng.Float64()*2 - 1) * radius, Y: (rng.Float64()*2 - 1) * radius}
+}
+
+func TestGridMatchesBruteForce(t *testing.T) {
+	edges := largeMap(100, 20)
+	grid := NewGrid(edges)
+	rng := rand.New(rand.NewSource(1))
+
+	for i := 0; i < 1000; i++ {
+		// Points reach past the map so queries from outside are covered.
+		center := randomPoint(rng, 120)
+		box := AroundPoint(center, rng.Float64()*10)
+		if got, want := grid.Query(box), bruteQuery(edges, box); fmt.Sprint(got) != fmt.Sprint(want) {
+			t.Fatalf("query %+v: got edges %v, want %v", box, got, want)
+		}
+
+		direction := randomPoint(rng, 1)
+		maxDistance := rng.Float64() * 150
+		gotHit, gotOK := grid.Raycast(center, direction, maxDistance)
+		wantHit, wantOK := bruteRaycast(edges, center, direction, maxDistance)
+		if gotOK != wantOK || gotOK && (gotHit.Edge != wantHit.Edge || math.Abs(gotHit.Distance-wantHit.Distance) > 1e-9) {
+			t.Fatalf("raycast from %+v towards %+v: got %+v %v, want %+v %v", center, direction, gotHit, gotOK, wantHit, wantOK)
+		}
+
+		gotEdge, gotDistance, _ := grid.Nearest(center, math.Inf(1))
+		wantEdge, wantDistance := bruteNearest(edges, center)
+		if gotEdge != wantEdge || gotDistance != wantDistance {
+			t.Fatalf("nearest to %+v: got edge %d at %v, want %d at %v", center, gotEdge, gotDistance, wantEdge, wantDistance)
+		}
+	}
+}
+
+// The benchmarks run the queries a collider makes for one body and tick
+// on a large map: a box query around the body, a raycast along its move
+// and a nearest wall lookup. Comparing the two gives the per-tick saving.
+
+func BenchmarkGrid(b *testing.B) {
+	edges := largeMap(200, 40)
+	grid := NewGrid(edges)
+	rng := rand.New(rand.NewSource(1))
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		point := randomPoint(rng, 200)
+		grid.Query(AroundPoint(point, 1))
+		grid.Raycast(point, randomPoint(rng, 1), 5)
+		grid.Nearest(point, math.Inf(1))
+	}
+	b.ReportMetric(float64(len(edges)), "edges")
+}
+
+func BenchmarkBruteForce(b *testing.B) {
+	edges := largeMap(200, 40)
+	rng := rand.New(rand.NewSource(1))
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		point := randomPoint(rng, 200)
+		bruteQuery(edges, AroundPoint(point, 1))
+		bruteRaycast(edges, point, randomPoint(rng, 1), 5)
+		bruteNearest(edges, point)
+	}
+	b.ReportMetric(float64(len(edges)), "edges")
+}
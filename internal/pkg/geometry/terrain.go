@@ -0,0 +1,294 @@
+package geometry
+
+import (
+	"ais-summoner/internal/models"
+	"fmt"
+	"math"
+)
+
+const (
+	MaxTerrainPoints = 1024
+	MaxTerrainShapes = 256
+	MaxRotation      = 360
+)
+
+// minTerrainArea rejects outlines too thin for a player to stand in.
+const minTerrainArea = 1e-6
+
+// spawnSamples is how many points per side of the boundary bounds are
+// tried when looking for a fallback spawn.
+const spawnSamples = 32
+
+// Shape is a terrain turned into world coordinates.
+type Shape struct {
+	Boundary    Polygon
+	Holes       []Polygon
+	Obstacles   []Polygon
+	SpawnPoints []models.SpawnPoint
+	Zones       []ShapeZone
+}
+
+type ShapeZone struct {
+	Name    string
+	Kind    models.ZoneKind
+	Polygon Polygon
+}
+
+// NewShape applies the terrain rotation to every outline, spawn point and
+// zone of the terrain.
+func NewShape(terrain *models.Terrain) *Shape {
+	rotate := func(outlines [][]models.Vector2) []Polygon {
+		polygons := make([]Polygon, len(outlines))
+		for i, outline := range outlines {
+			polygons[i] = NewPolygon(outline, terrain.Rotation)
+		}
+		return polygons
+	}
+
+	shape := &Shape{
+		Boundary:  NewPolygon(terrain.Boundary, terrain.Rotation),
+		Holes:     rotate(terrain.Holes),
+		Obstacles: rotate(terrain.Obstacles),
+	}
+
+	for _, spawn := range terrain.SpawnPoints {
+		position := Rotate(models.Vector2{X: spawn.Position.X, Y: spawn.Position.Z}, terrain.Rotation)
+		shape.SpawnPoints = append(shape.SpawnPoints, models.SpawnPoint{
+			Position: models.Vector3{X: position.X, Y: spawn.Position.Y, Z: position.Y},
+			Facing:   normalizeDegrees(spawn.Facing + terrain.Rotation),
+			Tags:     spawn.Tags,
+		})
+	}
+
+	for _, zone := range terrain.Zones {
+		shape.Zones = append(shape.Zones, ShapeZone{
+			Name:    zone.Name,
+			Kind:    zone.Kind,
+			Polygon: NewPolygon(zone.Points, terrain.Rotation),
+		})
+	}
+
+	return shape
+}
+
+// Blockers returns the polygons players cannot walk into, holes first.
+func (shape *Shape) Blockers() []Polygon {
+	blockers := make([]Polygon, 0, len(shape.Holes)+len(shape.Obstacles))
+	blockers = append(blockers, shape.Holes...)
+	return append(blockers, shape.Obstacles...)
+}
+
+// FallbackSpawn picks where players spawn on a terrain without spawn
+// points: the walkable point farthest from any wall among the boundary
+// centroid and a grid of points over the boundary. It reports false when
+// none of them is walkable.
+func (shape *Shape) FallbackSpawn() (models.Vector2, bool) {
+	if len(shape.Boundary) < 3 {
+		return models.Vector2{}, false
+	}
+
+	blockers := shape.Blockers()
+	collider := NewCollider(shape.Boundary, blockers...)
+	edges := shape.Boundary.Edges()
+	for _, blocker := range blockers {
+		edges = append(edges, blocker.Edges()...)
+	}
+
+	best, bestClearance, found := models.Vector2{}, 0.0, false
+	try := func(point models.Vector2) {
+		if !collider.Inside(point) {
+			return
+		}
+
+		clearance := math.Inf(1)
+		for _, edge := range edges {
+			clearance = math.Min(clearance, edge.Distance(point))
+		}
+		if !found || clearance > bestClearance {
+			best, bestClearance, found = point, clearance, true
+		}
+	}
+
+	try(shape.Boundary.Centroid())
+	bounds := shape.Boundary.Bounds()
+	width, height := bounds.Max.X-bounds.Min.X, bounds.Max.Y-bounds.Min.Y
+	for i := 0; i < spawnSamples; i++ {
+		for j := 0; j < spawnSamples; j++ {
+			try(models.Vector2{
+				X: bounds.Min.X + (float64(i)+0.5)*width/spawnSamples,
+				Y: bounds.Min.Y + (float64(j)+0.5)*height/spawnSamples,
+			})
+		}
+	}
+
+	return best, found
+}
+
+// ValidateTerrain describes every problem that keeps the terrain from
+// being used by the simulation. It returns nil for a valid terrain.
+func ValidateTerrain(terrain *models.Terrain) []string {
+	var problems []string
+
+	if terrain.Name == "" {
+		problems = append(problems, "name is required")
+	}
+	if math.IsNaN(terrain.Rotation) || terrain.Rotation < 0 || terrain.Rotation >= MaxRotation {
+		problems = append(problems, fmt.Sprintf("rotation must be in [0, %d) degrees", MaxRotation))
+	}
+	if count := len(terrain.Holes) + len(terrain.Obstacles) + len(terrain.SpawnPoints) + len(terrain.Zones); count > MaxTerrainShapes {
+		return append(problems, fmt.Sprintf("terrains allow at most %d holes, obstacles, spawn points and zones, got %d", MaxTerrainShapes, count))
+	}
+
+	boundaryProblems := validateOutline("boundary", terrain.Boundary)
+	problems = append(problems, boundaryProblems...)
+
+	// Holes and obstacles must sit apart from each other inside the boundary.
+	var blockers []Polygon
+	var blockerFields []string
+	valid := boundaryProblems == nil
+	groups := []struct {
+		field    string
+		outlines [][]models.Vector2
+	}{{"holes", terrain.Holes}, {"obstacles", terrain.Obstacles}}
+	for _, group := range groups {
+		for i, outline := range group.outlines {
+			name := fmt.Sprintf("%s[%d]", group.field, i)
+			if outlineProblems := validateOutline(name, outline); outlineProblems != nil {
+				problems = append(problems, outlineProblems...)
+				valid = false
+				continue
+			}
+			blockers = append(blockers, outline)
+			blockerFields = append(blockerFields, name)
+		}
+	}
+	if valid {
+		boundary := Polygon(terrain.Boundary)
+		for i, blocker := range blockers {
+			if !contains(boundary, blocker) {
+				problems = append(problems, fmt.Sprintf("%s must lie inside the boundary", blockerFields[i]))
+				valid = false
+			}
+			for j := i + 1; j < len(blockers); j++ {
+				if overlap(blocker, blockers[j]) {
+					problems = append(problems, fmt.Sprintf("%s overlaps %s", blockerFields[i], blockerFields[j]))
+					valid = false
+				}
+			}
+		}
+	}
+
+	var collider *Collider
+	if valid {
+		collider = NewCollider(terrain.Boundary, blockers...)
+	}
+	for i, spawn := range terrain.SpawnPoints {
+		position := spawn.Position
+		switch {
+		case !finite(position.X) || !finite(position.Y) || !finite(position.Z):
+			problems = append(problems, fmt.Sprintf("spawnPoints[%d] is not a finite position", i))
+		case math.IsNaN(spawn.Facing) || spawn.Facing < 0 || spawn.Facing >= MaxRotation:
+			problems = append(problems, fmt.Sprintf("spawnPoints[%d] facing must be in [0, %d) degrees", i, MaxRotation))
+		case collider != nil && !collider.Inside(models.Vector2{X: position.X, Y: position.Z}):
+			problems = append(problems, fmt.Sprintf("spawnPoints[%d] is outside the walkable area", i))
+		}
+	}
+	if collider != nil && len(terrain.SpawnPoints) == 0 {
+		if _, found := NewShape(terrain).FallbackSpawn(); !found {
+			problems = append(problems, "spawnPoints are required, the walkable area is too narrow to pick a spawn")
+		}
+	}
+
+	names := make(map[string]bool)
+	for i, zone := range terrain.Zones {
+		field := fmt.Sprintf("zones[%d]", i)
+		switch {
+		case zone.Name == "":
+			problems = append(problems, field+" needs a name")
+		case names[zone.Name]:
+			problems = append(problems, fmt.Sprintf("%s repeats the zone name %q", field, zone.Name))
+		}
+		names[zone.Name] = true
+
+		switch zone.Kind {
+		case models.ZoneCapture, models.ZoneHazard, models.ZoneSafe:
+		default:
+			problems = append(problems, fmt.Sprintf("%s has unknown kind %q, expected %s, %s or %s", field, zone.Kind, models.ZoneCapture, models.ZoneHazard, models.ZoneSafe))
+		}
+		problems = append(problems, validateOutline(field+".points", zone.Points)...)
+	}
+
+	return problems
+}
+
+// validateOutline checks that the points form a simple polygon with area.
+func validateOutline(field string, points []models.Vector2) []string {
+	if len(points) < 3 {
+		return []string{fmt.Sprintf("%s needs at least 3 points, got %d", field, len(points))}
+	}
+	if len(points) > MaxTerrainPoints {
+		return []string{fmt.Sprintf("%s allows at most %d points, got %d", field, MaxTerrainPoints, len(points))}
+	}
+
+	var problems []string
+	for i, point := range points {
+		if !finite(point.X) || !finite(point.Y) {
+			problems = append(problems, fmt.Sprintf("%s[%d] is not a finite coordinate", field, i))
+		}
+	}
+	if problems != nil {
+		return problems
+	}
+
+	polygon := Polygon(points)
+	if math.Abs(polygon.SignedArea()) < minTerrainArea {
+		return []string{fmt.Sprintf("%s are degenerate and enclose no area", field)}
+	}
+	if !polygon.IsSimple() {
+		return []string{fmt.Sprintf("%s intersect themselves or repeat a vertex", field)}
+	}
+
+	return nil
+}
+
+// contains reports whether inner lies strictly inside outer.
+func contains(outer Polygon, inner Polygon) bool {
+	for _, point := range inner {
+		if !outer.Contains(point) {
+			return false
+		}
+	}
+
+	return !edgesCross(outer, inner)
+}
+
+// overlap reports whether two simple polygons share any area or boundary.
+func overlap(a Polygon, b Polygon) bool {
+	return edgesCross(a, b) || a.Contains(b[0]) || b.Contains(a[0])
+}
+
+func edgesCross(a Polygon, b Polygon) bool {
+	for _, edgeA := range a.Edges() {
+		for _, edgeB := range b.Edges() {
+			if _, crosses := edgeA.Intersect(edgeB); crosses {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// normalizeDegrees wraps the angle into [0, 360).
+func normalizeDegrees(degrees float64) float64 {
+	degrees = math.Mod(degrees, 360)
+	if degrees < 0 {
+		degrees += 360
+	}
+
+	return degrees
+}
+
+func finite(value float64) bool {
+	return !math.IsNaN(value) && !math.IsInf(value, 0)
+}
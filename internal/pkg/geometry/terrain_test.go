@@ -0,0 +1,50 @@
+package geometry
+
+import (
+	"ais-summoner/internal/models"
+	"strings"
+	"testing"
+)
+
+func square(half float64) []models.Vector2 {
+	return []models.Vector2{{X: -half, Y: -half}, {X: half, Y: -half}, {X: half, Y: half}, {X: -half, Y: half}}
+}
+
+func TestFallbackSpawnAvoidsObstacles(t *testing.T) {
+	terrain := &models.Terrain{Name: "pillar", Boundary: square(10), Obstacles: [][]models.Vector2{square(4)}}
+	shape := NewShape(terrain)
+
+	spawn, found := shape.FallbackSpawn()
+	if !found {
+		t.Fatal("found no spawn")
+	}
+	if !NewTerrainCollider(terrain).Inside(spawn) {
+		t.Fatalf("spawn %+v is not walkable", spawn)
+	}
+
+	// Without obstacles the centroid is as far from the walls as it gets.
+	terrain.Obstacles = nil
+	if spawn, _ := NewShape(terrain).FallbackSpawn(); spawn != (models.Vector2{}) {
+		t.Fatalf("got spawn %+v, want the centroid", spawn)
+	}
+}
+
+func TestValidateTerrainNeedsSomewhereToSpawn(t *testing.T) {
+	terrain := &models.Terrain{Name: "pillar", Boundary: square(10), Obstacles: [][]models.Vector2{square(4)}}
+	if problems := ValidateTerrain(terrain); problems != nil {
+		t.Fatalf("got problems %v", problems)
+	}
+
+	// An obstacle filling the boundary leaves a gap too thin to spawn in.
+	terrain.Obstacles = [][]models.Vector2{square(9.99)}
+	problems := ValidateTerrain(terrain)
+	if len(problems) != 1 || !strings.HasPrefix(problems[0], "spawnPoints are required") {
+		t.Fatalf("got problems %v", problems)
+	}
+
+	// Spawn points make the terrain valid again.
+	terrain.SpawnPoints = []models.SpawnPoint{{Position: models.Vector3{X: 9.995}}}
+	if problems := ValidateTerrain(terrain); problems != nil {
+		t.Fatalf("got problems %v", problems)
+	}
+}
@@ -0,0 +1,125 @@
+// Package glicko implements the Glicko-2 rating system as described in
+// Mark Glickman's "Example of the Glicko-2 system".
+package glicko
+
+import "math"
+
+const (
+	DefaultRating     = 1500
+	DefaultDeviation  = 350
+	DefaultVolatility = 0.06
+
+	// Tau constrains how much the volatility may change per rating period.
+	Tau = 0.5
+
+	scale     = 173.7178
+	tolerance = 0.000001
+)
+
+type Rating struct {
+	Rating     float64
+	Deviation  float64
+	Volatility float64
+}
+
+// Result is the outcome of one game against an opponent. Score is 1 for a
+// win, 0.5 for a draw and 0 for a loss.
+type Result struct {
+	Opponent Rating
+	Score    float64
+}
+
+func NewRating() Rating {
+	return Rating{
+		Rating:     DefaultRating,
+		Deviation:  DefaultDeviation,
+		Volatility: DefaultVolatility,
+	}
+}
+
+// Update returns the rating of a player after a rating period with the
+// given results. A period without results only widens the deviation.
+func Update(player Rating, results []Result) Rating {
+	mu := (player.Rating - DefaultRating) / scale
+	phi := player.Deviation / scale
+	sigma := player.Volatility
+
+	if len(results) == 0 {
+		phi = math.Sqrt(phi*phi + sigma*sigma)
+		return Rating{
+			Rating:     player.Rating,
+			Deviation:  math.Min(phi*scale, DefaultDeviation),
+			Volatility: sigma,
+		}
+	}
+
+	var vInverse, deltaSum float64
+	for _, result := range results {
+		muJ := (result.Opponent.Rating - DefaultRating) / scale
+		phiJ := result.Opponent.Deviation / scale
+		gJ := g(phiJ)
+		eJ := expected(mu, muJ, gJ)
+
+		vInverse += gJ * gJ * eJ * (1 - eJ)
+		deltaSum += gJ * (result.Score - eJ)
+	}
+
+	v := 1 / vInverse
+	delta := v * deltaSum
+	sigma = volatility(phi, sigma, v, delta)
+
+	phiStar := math.Sqrt(phi*phi + sigma*sigma)
+	phi = 1 / math.Sqrt(1/(phiStar*phiStar)+1/v)
+	mu += phi * phi * deltaSum
+
+	return Rating{
+		Rating:     mu*scale + DefaultRating,
+		Deviation:  math.Min(phi*scale, DefaultDeviation),
+		Volatility: sigma,
+	}
+}
+
+func g(phi float64) float64 {
+	return 1 / math.Sqrt(1+3*phi*phi/(math.Pi*math.Pi))
+}
+
+func expected(mu float64, muJ float64, gJ float64) float64 {
+	return 1 / (1 + math.Exp(-gJ*(mu-muJ)))
+}
+
+// volatility finds the new volatility with the Illinois variant of the
+// regula falsi method (step 5 of the algorithm).
+func volatility(phi float64, sigma float64, v float64, delta float64) float64 {
+	a := math.Log(sigma * sigma)
+	f := func(x float64) float64 {
+		ex := math.Exp(x)
+		d := phi*phi + v + ex
+		return ex*(delta*delta-d)/(2*d*d) - (x-a)/(Tau*Tau)
+	}
+
+	A := a
+	var B float64
+	if delta*delta > phi*phi+v {
+		B = math.Log(delta*delta - phi*phi - v)
+	} else {
+		k := 1.0
+		for f(a-k*Tau) < 0 {
+			k++
+		}
+		B = a - k*Tau
+	}
+
+	fA, fB := f(A), f(B)
+	for math.Abs(B-A) > tolerance {
+		C := A + (A-B)*fA/(fB-fA)
+		fC := f(C)
+		if fC*fB <= 0 {
+			A, fA = B, fB
+		} else {
+			fA /= 2
+		}
+		B, fB = C, fC
+	}
+
+	return math.Exp(A / 2)
+}
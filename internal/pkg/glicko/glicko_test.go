@@ -0,0 +1,58 @@
+package glicko
+
+import (
+	"math"
+	"testing"
+)
+
+// TestUpdateGlickmanExample replays the worked example of Glickman's
+// paper, whose results are given to two decimals for the rating and the
+// deviation and to five for the volatility.
+func TestUpdateGlickmanExample(t *testing.T) {
+	player := Rating{Rating: 1500, Deviation: 200, Volatility: 0.06}
+	results := []Result{
+		{Opponent: Rating{Rating: 1400, Deviation: 30, Volatility: 0.06}, Score: 1},
+		{Opponent: Rating{Rating: 1550, Deviation: 100, Volatility: 0.06}, Score: 0},
+		{Opponent: Rating{Rating: 1700, Deviation: 300, Volatility: 0.06}, Score: 0},
+	}
+
+	updated := Update(player, results)
+
+	for _, check := range []struct {
+		name      string
+		got, want float64
+		tolerance float64
+	}{
+		{"rating", updated.Rating, 1464.06, 0.01},
+		{"deviation", updated.Deviation, 151.52, 0.01},
+		{"volatility", updated.Volatility, 0.05999, 0.00001},
+	} {
+		if math.Abs(check.got-check.want) > check.tolerance {
+			t.Errorf("%s = %.6f, want %v", check.name, check.got, check.want)
+		}
+	}
+}
+
+// TestUpdateWithoutGames checks that a player who sat a period out keeps
+// their rating and volatility while their deviation widens, by 200.27 for
+// the player of Glickman's example.
+func TestUpdateWithoutGames(t *testing.T) {
+	for _, test := range []struct {
+		player    Rating
+		deviation float64
+	}{
+		{Rating{Rating: 1500, Deviation: 200, Volatility: 0.06}, 200.27},
+		{Rating{Rating: 1832, Deviation: 50, Volatility: 0.09}, 52.39},
+		// The deviation never widens past that of a new player.
+		{Rating{Rating: 1200, Deviation: 349.9, Volatility: 0.06}, DefaultDeviation},
+	} {
+		updated := Update(test.player, nil)
+
+		if updated.Rating != test.player.Rating || updated.Volatility != test.player.Volatility {
+			t.Errorf("idle period moved %+v to %+v", test.player, updated)
+		}
+		if math.Abs(updated.Deviation-test.deviation) > 0.01 {
+			t.Errorf("idle period took the deviation of %+v to %.4f, want %v", test.player, updated.Deviation, test.deviation)
+		}
+	}
+}
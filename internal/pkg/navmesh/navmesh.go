@@ -0,0 +1,130 @@
+// Package navmesh builds navigation meshes over the walkable area of a
+// terrain and finds paths across them. A mesh is immutable once built and
+// safe for concurrent use.
+package navmesh
+
+import (
+	"ais-summoner/internal/models"
+	"ais-summoner/internal/pkg/geometry"
+	"math"
+	"math/rand"
+)
+
+// Mesh is a triangulation of the walkable area in world coordinates. Every
+// triangle is wound counter-clockwise and neighbors[t][i] is the triangle
+// across the edge from corner i to corner i+1, or -1 at a wall.
+type Mesh struct {
+	vertices  []models.Vector2
+	triangles [][3]int
+	neighbors [][3]int
+	centroids []models.Vector2
+	areas     []float64
+	totalArea float64
+}
+
+// NewMesh triangulates the rotated terrain boundary minus its holes and
+// obstacles.
+func NewMesh(terrain *models.Terrain) (*Mesh, error) {
+	shape := geometry.NewShape(terrain)
+	return NewMeshFromPolygons(shape.Boundary, shape.Blockers())
+}
+
+// NewMeshFromPolygons triangulates the area inside the boundary and outside
+// the holes. The holes must lie inside the boundary and apart from each
+// other, as terrain validation guarantees.
+func NewMeshFromPolygons(boundary geometry.Polygon, holes []geometry.Polygon) (*Mesh, error) {
+	if len(boundary) < 3 {
+		return nil, errTriangulation
+	}
+
+	vertices, triangles, err := triangulate(boundary, holes)
+	if err != nil {
+		return nil, err
+	}
+
+	mesh := &Mesh{
+		vertices:  vertices,
+		triangles: triangles,
+		neighbors: make([][3]int, len(triangles)),
+		centroids: make([]models.Vector2, len(triangles)),
+		areas:     make([]float64, len(triangles)),
+	}
+
+	edges := make(map[[2]int]int, len(triangles)*3)
+	for t, triangle := range triangles {
+		a, b, c := vertices[triangle[0]], vertices[triangle[1]], vertices[triangle[2]]
+		mesh.centroids[t] = models.Vector2{X: (a.X + b.X + c.X) / 3, Y: (a.Y + b.Y + c.Y) / 3}
+		mesh.areas[t] = cross(a, b, c) / 2
+		mesh.totalArea += mesh.areas[t]
+
+		for i := 0; i < 3; i++ {
+			mesh.neighbors[t][i] = -1
+			from, to := triangle[i], triangle[(i+1)%3]
+			if other, ok := edges[[2]int{to, from}]; ok {
+				mesh.neighbors[t][i] = other / 3
+				mesh.neighbors[other/3][other%3] = t
+				continue
+			}
+			edges[[2]int{from, to}] = t*3 + i
+		}
+	}
+
+	return mesh, nil
+}
+
+// TriangleCount returns the number of triangles in the mesh.
+func (mesh *Mesh) TriangleCount() int {
+	return len(mesh.triangles)
+}
+
+// Triangle returns the corners of the triangle, counter-clockwise.
+func (mesh *Mesh) Triangle(index int) [3]models.Vector2 {
+	triangle := mesh.triangles[index]
+	return [3]models.Vector2{mesh.vertices[triangle[0]], mesh.vertices[triangle[1]], mesh.vertices[triangle[2]]}
+}
+
+// Locate returns the triangle holding the point. Points off the mesh are
+// moved to the closest point on it.
+func (mesh *Mesh) Locate(point models.Vector2) (int, models.Vector2) {
+	best, bestDistance, bestPoint := -1, math.Inf(1), point
+	for t := range mesh.triangles {
+		corners := mesh.Triangle(t)
+		if cross(corners[0], corners[1], point) >= -epsilon &&
+			cross(corners[1], corners[2], point) >= -epsilon &&
+			cross(corners[2], corners[0], point) >= -epsilon {
+			return t, point
+		}
+
+		for i := 0; i < 3; i++ {
+			closest := geometry.Segment{A: corners[i], B: corners[(i+1)%3]}.ClosestPoint(point)
+			if d := distance(point, closest); d < bestDistance {
+				best, bestDistance, bestPoint = t, d, closest
+			}
+		}
+	}
+
+	return best, bestPoint
+}
+
+// RandomPoint picks a point uniformly over the walkable area. The result
+// only depends on the state of rng, so seeded callers stay deterministic.
+func (mesh *Mesh) RandomPoint(rng *rand.Rand) models.Vector2 {
+	target := rng.Float64() * mesh.totalArea
+	t := 0
+	for ; t < len(mesh.areas)-1; t++ {
+		if target < mesh.areas[t] {
+			break
+		}
+		target -= mesh.areas[t]
+	}
+
+	u, v := rng.Float64(), rng.Float64()
+	if u+v > 1 {
+		u, v = 1-u, 1-v
+	}
+	corners := mesh.Triangle(t)
+	return models.Vector2{
+		X: corners[0].X + u*(corners[1].X-corners[0].X) + v*(corners[2].X-corners[0].X),
+		Y: corners[0].Y + u*(corners[1].Y-corners[0].Y) + v*(corners[2].Y-corners[0].Y),
+	}
+}
@@ -0,0 +1,154 @@
+package navmesh
+
+import (
+	"ais-summoner/internal/models"
+	"ais-summoner/internal/pkg/geometry"
+	"math"
+	"math/rand"
+	"testing"
+)
+
+type room struct {
+	name     string
+	boundary geometry.Polygon
+	holes    []geometry.Polygon
+}
+
+func rect(minX float64, minY float64, maxX float64, maxY float64) geometry.Polygon {
+	return geometry.Polygon{{X: minX, Y: minY}, {X: maxX, Y: minY}, {X: maxX, Y: maxY}, {X: minX, Y: maxY}}
+}
+
+var (
+	convexRoom = room{name: "convex", boundary: rect(0, 0, 20, 10)}
+	// concaveRoom is a U: two 10 wide arms joined along the bottom.
+	concaveRoom = room{name: "concave", boundary: geometry.Polygon{
+		{X: 0, Y: 0}, {X: 30, Y: 0}, {X: 30, Y: 20}, {X: 20, Y: 20},
+		{X: 20, Y: 5}, {X: 10, Y: 5}, {X: 10, Y: 20}, {X: 0, Y: 20},
+	}}
+	// holeyRoom has a tall pillar in the middle and two small ones.
+	holeyRoom = room{name: "holes", boundary: rect(-20, -20, 20, 20), holes: []geometry.Polygon{
+		rect(-5, -15, 5, 15),
+		// Clockwise on purpose, holes may be wound either way.
+		{{X: -15, Y: -15}, {X: -15, Y: -12}, {X: -12, Y: -12}, {X: -12, Y: -15}},
+		rect(12, 12, 15, 15),
+	}}
+)
+
+func (room room) mesh(t *testing.T) *Mesh {
+	t.Helper()
+
+	mesh, err := NewMeshFromPolygons(room.boundary, room.holes)
+	if err != nil {
+		t.Fatalf("%s: %v", room.name, err)
+	}
+
+	return mesh
+}
+
+// walkable reports whether the point lies in the room or on its walls.
+func (room room) walkable(point models.Vector2) bool {
+	collider := geometry.NewCollider(room.boundary, room.holes...)
+	if collider.Inside(point) {
+		return true
+	}
+	_, distance, _ := collider.Grid().Nearest(point, math.Inf(1))
+
+	return distance < 1e-6
+}
+
+func pathLength(path []models.Vector2) float64 {
+	length := 0.0
+	for i := 1; i < len(path); i++ {
+		length += distance(path[i-1], path[i])
+	}
+
+	return length
+}
+
+func TestTriangulationCoversTheWalkableArea(t *testing.T) {
+	for _, room := range []room{convexRoom, concaveRoom, holeyRoom} {
+		mesh := room.mesh(t)
+
+		want := math.Abs(room.boundary.SignedArea())
+		for _, hole := range room.holes {
+			want -= math.Abs(hole.SignedArea())
+		}
+		if math.Abs(mesh.totalArea-want) > 1e-6 {
+			t.Fatalf("%s: triangles cover %v, want %v", room.name, mesh.totalArea, want)
+		}
+
+		for i := 0; i < mesh.TriangleCount(); i++ {
+			if mesh.areas[i] <= 0 {
+				t.Fatalf("%s: triangle %d is not counter-clockwise", room.name, i)
+			}
+			if !room.walkable(mesh.centroids[i]) {
+				t.Fatalf("%s: triangle %d lies outside the room", room.name, i)
+			}
+			for edge, neighbor := range mesh.neighbors[i] {
+				if neighbor >= 0 && mesh.neighbors[neighbor][0] != i && mesh.neighbors[neighbor][1] != i && mesh.neighbors[neighbor][2] != i {
+					t.Fatalf("%s: triangle %d sees %d across edge %d but not the other way", room.name, i, neighbor, edge)
+				}
+			}
+		}
+	}
+}
+
+func TestFindPath(t *testing.T) {
+	cases := []struct {
+		room   room
+		start  models.Vector2
+		goal   models.Vector2
+		length float64
+	}{
+		// Across a convex room the path is a straight line.
+		{convexRoom, models.Vector2{X: 1, Y: 1}, models.Vector2{X: 19, Y: 9}, math.Hypot(18, 8)},
+		// From one arm of the U to the other it hugs the two inner corners.
+		{concaveRoom, models.Vector2{X: 5, Y: 15}, models.Vector2{X: 25, Y: 15}, 2*math.Hypot(5, 10) + 10},
+		// Around the tall pillar over either of its ends.
+		{holeyRoom, models.Vector2{X: -10}, models.Vector2{X: 10}, 2*math.Hypot(5, 15) + 10},
+	}
+
+	for _, c := range cases {
+		path, found := c.room.mesh(t).FindPath(c.start, c.goal)
+		if !found {
+			t.Fatalf("%s: no path", c.room.name)
+		}
+		if path[0] != c.start || path[len(path)-1] != c.goal {
+			t.Fatalf("%s: path %v does not join %v to %v", c.room.name, path, c.start, c.goal)
+		}
+		if length := pathLength(path); math.Abs(length-c.length) > 1e-6 {
+			t.Fatalf("%s: path %v is %v long, want %v", c.room.name, path, length, c.length)
+		}
+	}
+}
+
+func TestPathsStayInside(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	for _, room := range []room{convexRoom, concaveRoom, holeyRoom} {
+		mesh := room.mesh(t)
+
+		for i := 0; i < 200; i++ {
+			start, goal := mesh.RandomPoint(rng), mesh.RandomPoint(rng)
+			path, found := mesh.FindPath(start, goal)
+			if !found {
+				t.Fatalf("%s: no path from %v to %v", room.name, start, goal)
+			}
+			if length := pathLength(path); length < distance(start, goal)-1e-9 {
+				t.Fatalf("%s: path %v is shorter than the straight line", room.name, path)
+			}
+
+			for j := 1; j < len(path); j++ {
+				for step := 0; step <= 20; step++ {
+					f := float64(step) / 20
+					point := models.Vector2{
+						X: path[j-1].X + f*(path[j].X-path[j-1].X),
+						Y: path[j-1].Y + f*(path[j].Y-path[j-1].Y),
+					}
+					if !room.walkable(point) {
+						t.Fatalf("%s: path %v leaves the room at %v", room.name, path, point)
+					}
+				}
+			}
+		}
+	}
+}
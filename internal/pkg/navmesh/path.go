@@ -0,0 +1,185 @@
+package navmesh
+
+import (
+	"ais-summoner/internal/models"
+	"ais-summoner/internal/pkg/geometry"
+	"container/heap"
+)
+
+// FindPath returns the waypoints of the shortest corridor from start to
+// goal, beginning with start and ending with goal. Points off the mesh are
+// first moved onto it. It reports false when the goal cannot be reached.
+func (mesh *Mesh) FindPath(start models.Vector2, goal models.Vector2) ([]models.Vector2, bool) {
+	from, start := mesh.Locate(start)
+	to, goal := mesh.Locate(goal)
+	if from < 0 || to < 0 {
+		return nil, false
+	}
+	if from == to {
+		return []models.Vector2{start, goal}, true
+	}
+
+	corridor := mesh.search(from, to, start, goal)
+	if corridor == nil {
+		return nil, false
+	}
+
+	return mesh.funnel(corridor, start, goal), true
+}
+
+// search runs A* over the triangles and returns the triangles crossed from
+// the first to the last. A triangle is entered at the point of the portal
+// closest to where its parent was entered, which follows the taut path far
+// better than centroids on the long thin triangles ear clipping produces.
+func (mesh *Mesh) search(from int, to int, start models.Vector2, goal models.Vector2) []int {
+	costs := make([]float64, len(mesh.triangles))
+	parents := make([]int, len(mesh.triangles))
+	entries := make([]models.Vector2, len(mesh.triangles))
+	closed := make([]bool, len(mesh.triangles))
+	for i := range parents {
+		parents[i] = -1
+	}
+	entries[from] = start
+
+	open := &searchQueue{{triangle: from, estimate: distance(start, goal)}}
+	for open.Len() > 0 {
+		current := heap.Pop(open).(searchNode).triangle
+		if closed[current] {
+			continue
+		}
+		closed[current] = true
+
+		if current == to {
+			var corridor []int
+			for t := to; t != -1; t = parents[t] {
+				corridor = append(corridor, t)
+			}
+			for i, j := 0, len(corridor)-1; i < j; i, j = i+1, j-1 {
+				corridor[i], corridor[j] = corridor[j], corridor[i]
+			}
+			return corridor
+		}
+
+		for _, next := range mesh.neighbors[current] {
+			if next < 0 || closed[next] {
+				continue
+			}
+
+			left, right := mesh.portal(current, next)
+			entry := geometry.Segment{A: left, B: right}.ClosestPoint(entries[current])
+			cost := costs[current] + distance(entries[current], entry)
+			if parents[next] != -1 && cost >= costs[next] || next == from {
+				continue
+			}
+			costs[next] = cost
+			parents[next] = current
+			entries[next] = entry
+			heap.Push(open, searchNode{triangle: next, estimate: cost + distance(entry, goal)})
+		}
+	}
+
+	return nil
+}
+
+// funnel pulls the path taut through the portals between the corridor
+// triangles using the simple stupid funnel algorithm.
+func (mesh *Mesh) funnel(corridor []int, start models.Vector2, goal models.Vector2) []models.Vector2 {
+	lefts := []models.Vector2{start}
+	rights := []models.Vector2{start}
+	for i := 0; i < len(corridor)-1; i++ {
+		left, right := mesh.portal(corridor[i], corridor[i+1])
+		lefts = append(lefts, left)
+		rights = append(rights, right)
+	}
+	lefts = append(lefts, goal)
+	rights = append(rights, goal)
+
+	path := []models.Vector2{start}
+	apex, left, right := start, start, start
+	apexIndex, leftIndex, rightIndex := 0, 0, 0
+
+	for i := 1; i < len(lefts); i++ {
+		// Narrow the funnel from the right.
+		if cross(apex, right, rights[i]) >= 0 {
+			if apex == right || cross(apex, left, rights[i]) < 0 {
+				right, rightIndex = rights[i], i
+			} else {
+				// The right side crossed the left one, which becomes a corner.
+				path = appendCorner(path, left)
+				apex, apexIndex = left, leftIndex
+				right, rightIndex = apex, apexIndex
+				i = apexIndex
+				continue
+			}
+		}
+
+		// Narrow the funnel from the left.
+		if cross(apex, left, lefts[i]) <= 0 {
+			if apex == left || cross(apex, right, lefts[i]) > 0 {
+				left, leftIndex = lefts[i], i
+			} else {
+				path = appendCorner(path, right)
+				apex, apexIndex = right, rightIndex
+				left, leftIndex = apex, apexIndex
+				i = apexIndex
+				continue
+			}
+		}
+	}
+
+	return appendCorner(path, goal)
+}
+
+// appendCorner adds the point unless the path already ends there.
+func appendCorner(path []models.Vector2, point models.Vector2) []models.Vector2 {
+	if path[len(path)-1] == point {
+		return path
+	}
+
+	return append(path, point)
+}
+
+// portal returns the shared edge of two neighboring triangles as seen when
+// walking from the first into the second.
+func (mesh *Mesh) portal(from int, to int) (models.Vector2, models.Vector2) {
+	triangle := mesh.triangles[from]
+	for i, neighbor := range mesh.neighbors[from] {
+		if neighbor == to {
+			// Leaving a counter-clockwise triangle, the edge start is on the
+			// right and its end on the left.
+			return mesh.vertices[triangle[(i+1)%3]], mesh.vertices[triangle[i]]
+		}
+	}
+
+	return mesh.centroids[from], mesh.centroids[from]
+}
+
+type searchNode struct {
+	triangle int
+	estimate float64
+}
+
+// searchQueue orders nodes by estimate, breaking ties by triangle so the
+// search is deterministic.
+type searchQueue []searchNode
+
+func (queue searchQueue) Len() int { return len(queue) }
+
+func (queue searchQueue) Less(i int, j int) bool {
+	if queue[i].estimate != queue[j].estimate {
+		return queue[i].estimate < queue[j].estimate
+	}
+
+	return queue[i].triangle < queue[j].triangle
+}
+
+func (queue searchQueue) Swap(i int, j int) { queue[i], queue[j] = queue[j], queue[i] }
+
+func (queue *searchQueue) Push(value interface{}) { *queue = append(*queue, value.(searchNode)) }
+
+func (queue *searchQueue) Pop() interface{} {
+	old := *queue
+	node := old[len(old)-1]
+	*queue = old[:len(old)-1]
+	return node
+}
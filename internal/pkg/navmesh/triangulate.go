@@ -0,0 +1,228 @@
+package navmesh
+
+import (
+	"ais-summoner/internal/models"
+	"ais-summoner/internal/pkg/geometry"
+	"errors"
+	"math"
+	"sort"
+)
+
+const epsilon = 1e-9
+
+var errTriangulation = errors.New("navmesh: walkable area cannot be triangulated")
+
+// triangulate splits the area inside the boundary and outside the holes
+// into triangles by ear clipping. Holes are first joined to the boundary by
+// bridges to visible vertices, which turns the area into a single polygon.
+// Vertices keep their index in the returned slice, so triangles on both
+// sides of a bridge share it.
+func triangulate(boundary geometry.Polygon, holes []geometry.Polygon) ([]models.Vector2, [][3]int, error) {
+	var vertices []models.Vector2
+	ring := func(polygon geometry.Polygon, counterClockwise bool) []int {
+		indices := make([]int, len(polygon))
+		for i := range polygon {
+			indices[i] = len(vertices) + i
+		}
+		vertices = append(vertices, polygon...)
+		if (polygon.SignedArea() > 0) != counterClockwise {
+			for i, j := 0, len(indices)-1; i < j; i, j = i+1, j-1 {
+				indices[i], indices[j] = indices[j], indices[i]
+			}
+		}
+		return indices
+	}
+
+	outline := ring(boundary, true)
+	rings := make([][]int, len(holes))
+	for i, hole := range holes {
+		rings[i] = ring(hole, false)
+	}
+
+	// Bridge the holes reaching furthest right first, as their bridges are
+	// least likely to be blocked by the others.
+	sort.SliceStable(rings, func(a, b int) bool {
+		return vertices[rightmost(vertices, rings[a])].X > vertices[rightmost(vertices, rings[b])].X
+	})
+	for i, hole := range rings {
+		var err error
+		outline, err = bridge(vertices, outline, hole, rings[i+1:])
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+
+	triangles, err := clipEars(vertices, outline)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return vertices, triangles, nil
+}
+
+func rightmost(vertices []models.Vector2, ring []int) int {
+	best := ring[0]
+	for _, index := range ring[1:] {
+		if vertices[index].X > vertices[best].X || vertices[index].X == vertices[best].X && vertices[index].Y > vertices[best].Y {
+			best = index
+		}
+	}
+
+	return best
+}
+
+// bridge splices the hole into the outline through the closest outline
+// vertex its rightmost vertex can see.
+func bridge(vertices []models.Vector2, outline []int, hole []int, pending [][]int) ([]int, error) {
+	m, right := 0, rightmost(vertices, hole)
+	for i, index := range hole {
+		if index == right {
+			m = i
+		}
+	}
+	from := vertices[hole[m]]
+
+	candidates := make([]int, len(outline))
+	for i := range candidates {
+		candidates[i] = i
+	}
+	sort.SliceStable(candidates, func(a, b int) bool {
+		return distance(from, vertices[outline[candidates[a]]]) < distance(from, vertices[outline[candidates[b]]])
+	})
+
+	for _, p := range candidates {
+		to := vertices[outline[p]]
+		previous := vertices[outline[(p+len(outline)-1)%len(outline)]]
+		next := vertices[outline[(p+1)%len(outline)]]
+		if !inCone(previous, to, next, from) {
+			continue
+		}
+		if blocked(vertices, from, to, outline) || blocked(vertices, from, to, hole) {
+			continue
+		}
+		visible := true
+		for _, other := range pending {
+			if blocked(vertices, from, to, other) {
+				visible = false
+				break
+			}
+		}
+		if !visible {
+			continue
+		}
+
+		merged := make([]int, 0, len(outline)+len(hole)+2)
+		merged = append(merged, outline[:p+1]...)
+		for i := 0; i <= len(hole); i++ {
+			merged = append(merged, hole[(m+i)%len(hole)])
+		}
+		merged = append(merged, outline[p])
+		return append(merged, outline[p+1:]...), nil
+	}
+
+	return nil, errTriangulation
+}
+
+// inCone reports whether q lies inside the interior angle at vertex p of a
+// counter-clockwise polygon, between the edges from previous and to next.
+func inCone(previous models.Vector2, p models.Vector2, next models.Vector2, q models.Vector2) bool {
+	if cross(previous, p, next) >= 0 {
+		return cross(previous, p, q) > 0 && cross(p, next, q) > 0
+	}
+
+	return cross(previous, p, q) > 0 || cross(p, next, q) > 0
+}
+
+// blocked reports whether the open segment between a and b crosses any edge
+// of the ring that does not end at a or b.
+func blocked(vertices []models.Vector2, a models.Vector2, b models.Vector2, ring []int) bool {
+	segment := geometry.Segment{A: a, B: b}
+	for i := range ring {
+		edge := geometry.Segment{A: vertices[ring[i]], B: vertices[ring[(i+1)%len(ring)]]}
+		if edge.A == a || edge.A == b || edge.B == a || edge.B == b {
+			continue
+		}
+		if _, crosses := segment.Intersect(edge); crosses {
+			return true
+		}
+	}
+
+	return false
+}
+
+// clipEars triangulates a counter-clockwise polygon that may visit the same
+// vertex more than once along its bridges.
+func clipEars(vertices []models.Vector2, polygon []int) ([][3]int, error) {
+	remaining := append([]int(nil), polygon...)
+	triangles := make([][3]int, 0, len(polygon)-2)
+
+	for len(remaining) > 3 {
+		clipped := false
+		for i := range remaining {
+			a := remaining[(i+len(remaining)-1)%len(remaining)]
+			b := remaining[i]
+			c := remaining[(i+1)%len(remaining)]
+			if !isEar(vertices, remaining, a, b, c) {
+				continue
+			}
+
+			triangles = append(triangles, [3]int{a, b, c})
+			remaining = append(remaining[:i], remaining[i+1:]...)
+			clipped = true
+			break
+		}
+		if clipped {
+			continue
+		}
+
+		// Only collinear vertices are left to remove; they add no area.
+		for i := range remaining {
+			a := vertices[remaining[(i+len(remaining)-1)%len(remaining)]]
+			c := vertices[remaining[(i+1)%len(remaining)]]
+			if math.Abs(cross(a, vertices[remaining[i]], c)) < epsilon {
+				remaining = append(remaining[:i], remaining[i+1:]...)
+				clipped = true
+				break
+			}
+		}
+		if !clipped {
+			return nil, errTriangulation
+		}
+	}
+
+	if len(remaining) == 3 && cross(vertices[remaining[0]], vertices[remaining[1]], vertices[remaining[2]]) > epsilon {
+		triangles = append(triangles, [3]int{remaining[0], remaining[1], remaining[2]})
+	}
+
+	return triangles, nil
+}
+
+// isEar reports whether the convex corner a, b, c holds no other vertex of
+// the polygon.
+func isEar(vertices []models.Vector2, polygon []int, a int, b int, c int) bool {
+	pa, pb, pc := vertices[a], vertices[b], vertices[c]
+	if cross(pa, pb, pc) <= epsilon {
+		return false
+	}
+
+	for _, index := range polygon {
+		point := vertices[index]
+		if point == pa || point == pb || point == pc {
+			continue
+		}
+		if cross(pa, pb, point) >= 0 && cross(pb, pc, point) >= 0 && cross(pc, pa, point) >= 0 {
+			return false
+		}
+	}
+
+	return true
+}
+
+// cross is positive when c lies left of the line from a to b.
+func cross(a models.Vector2, b models.Vector2, c models.Vector2) float64 {
+	return (b.X-a.X)*(c.Y-a.Y) - (b.Y-a.Y)*(c.X-a.X)
+}
+
+func distance(a models.Vector2, b models.Vector2) float64 {
+	return math.Hypot(a.X-b.X, a.Y-b.Y)
+}
@@ -0,0 +1,281 @@
+package terrainfile
+
+import (
+	"ais-summoner/internal/models"
+	"ais-summoner/internal/pkg/geometry"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+)
+
+// GeoJSON files hold a FeatureCollection with one Polygon or MultiPolygon
+// feature for the terrain, Point features for spawn points and Polygon
+// features for zones, told apart by their "kind" property. A single
+// Feature or a bare geometry is read as the terrain alone.
+//
+// The terrain polygon holding the largest outer ring is the boundary and
+// its inner rings are holes; the outer rings of the other polygons are
+// obstacles. Positions are [x, z] on the horizontal plane; spawn points add
+// their height as a third coordinate.
+
+const (
+	geoJSONKindTerrain = "terrain"
+	geoJSONKindSpawn   = "spawn"
+	geoJSONKindZone    = "zone"
+)
+
+type geoJSONObject struct {
+	Type        string             `json:"type"`
+	Features    []geoJSONObject    `json:"features,omitempty"`
+	Geometry    *geoJSONObject     `json:"geometry,omitempty"`
+	Properties  *geoJSONProperties `json:"properties,omitempty"`
+	Coordinates json.RawMessage    `json:"coordinates,omitempty"`
+}
+
+type geoJSONProperties struct {
+	Kind     string          `json:"kind,omitempty"`
+	ID       string          `json:"id,omitempty"`
+	Name     string          `json:"name,omitempty"`
+	Rotation float64         `json:"rotation,omitempty"`
+	Facing   float64         `json:"facing,omitempty"`
+	Tags     []string        `json:"tags,omitempty"`
+	Zone     models.ZoneKind `json:"zone,omitempty"`
+}
+
+func decodeGeoJSON(data []byte) (*terrainFile, error) {
+	var object geoJSONObject
+	if err := json.Unmarshal(data, &object); err != nil {
+		return nil, fmt.Errorf("invalid GeoJSON: %v", err)
+	}
+
+	features := []geoJSONObject{object}
+	switch object.Type {
+	case "FeatureCollection":
+		features = object.Features
+	case "Feature":
+	default:
+		features = []geoJSONObject{{Type: "Feature", Geometry: &object}}
+	}
+
+	file := &terrainFile{}
+	terrains := 0
+	for i, feature := range features {
+		if feature.Type != "Feature" || feature.Geometry == nil {
+			return nil, fmt.Errorf("GeoJSON feature %d has no geometry", i)
+		}
+		properties := feature.Properties
+		if properties == nil {
+			properties = &geoJSONProperties{}
+		}
+
+		var err error
+		switch {
+		case properties.Kind == geoJSONKindSpawn || properties.Kind == "" && feature.Geometry.Type == "Point":
+			err = file.decodeGeoJSONSpawn(feature.Geometry, properties)
+		case properties.Kind == geoJSONKindZone:
+			err = file.decodeGeoJSONZone(feature.Geometry, properties)
+		case properties.Kind == geoJSONKindTerrain || properties.Kind == "":
+			terrains++
+			file.name, file.rotation = properties.Name, properties.Rotation
+			err = file.decodeGeoJSONTerrain(feature.Geometry)
+		default:
+			err = fmt.Errorf("unknown kind %q", properties.Kind)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("GeoJSON feature %d: %v", i, err)
+		}
+	}
+
+	if terrains != 1 {
+		return nil, fmt.Errorf("GeoJSON must hold exactly one terrain feature, got %d", terrains)
+	}
+
+	return file, nil
+}
+
+func (file *terrainFile) decodeGeoJSONTerrain(object *geoJSONObject) error {
+	polygons, err := geoJSONPolygons(object)
+	if err != nil {
+		return err
+	}
+
+	boundary, largest := -1, 0.0
+	for i, polygon := range polygons {
+		if len(polygon) == 0 {
+			return errors.New("terrain polygons need an outer ring")
+		}
+		if area := math.Abs(geometry.Polygon(polygon[0]).SignedArea()); boundary < 0 || area > largest {
+			boundary, largest = i, area
+		}
+	}
+
+	for i, polygon := range polygons {
+		if i == boundary {
+			file.shape.Boundary = polygon[0]
+			file.shape.Holes = append(file.shape.Holes, polygon[1:]...)
+			continue
+		}
+		if len(polygon) > 1 {
+			return errors.New("obstacles cannot have holes")
+		}
+		file.shape.Obstacles = append(file.shape.Obstacles, polygon[0])
+	}
+
+	return nil
+}
+
+func (file *terrainFile) decodeGeoJSONSpawn(object *geoJSONObject, properties *geoJSONProperties) error {
+	if object.Type != "Point" {
+		return fmt.Errorf("spawn points must be a Point, got %q", object.Type)
+	}
+
+	var position []float64
+	if err := json.Unmarshal(object.Coordinates, &position); err != nil || len(position) < 2 {
+		return errors.New("invalid Point coordinates")
+	}
+
+	spawn := models.SpawnPoint{
+		Position: models.Vector3{X: position[0], Z: position[1]},
+		Facing:   properties.Facing,
+		Tags:     properties.Tags,
+	}
+	if len(position) > 2 {
+		spawn.Position.Y = position[2]
+	}
+	file.shape.SpawnPoints = append(file.shape.SpawnPoints, spawn)
+
+	return nil
+}
+
+func (file *terrainFile) decodeGeoJSONZone(object *geoJSONObject, properties *geoJSONProperties) error {
+	polygons, err := geoJSONPolygons(object)
+	if err != nil {
+		return err
+	}
+	if len(polygons) != 1 || len(polygons[0]) != 1 {
+		return errors.New("zones must be a single polygon without holes")
+	}
+
+	file.shape.Zones = append(file.shape.Zones, geometry.ShapeZone{
+		Name:    properties.Name,
+		Kind:    properties.Zone,
+		Polygon: polygons[0][0],
+	})
+
+	return nil
+}
+
+// geoJSONPolygons reads Polygon or MultiPolygon coordinates as a list of
+// polygons made of open rings.
+func geoJSONPolygons(object *geoJSONObject) ([][]geometry.Polygon, error) {
+	var raw [][][][]float64
+	switch object.Type {
+	case "Polygon":
+		var polygon [][][]float64
+		if err := json.Unmarshal(object.Coordinates, &polygon); err != nil {
+			return nil, fmt.Errorf("invalid Polygon coordinates: %v", err)
+		}
+		raw = append(raw, polygon)
+	case "MultiPolygon":
+		if err := json.Unmarshal(object.Coordinates, &raw); err != nil {
+			return nil, fmt.Errorf("invalid MultiPolygon coordinates: %v", err)
+		}
+	default:
+		return nil, fmt.Errorf("unsupported geometry %q, expected Polygon or MultiPolygon", object.Type)
+	}
+
+	polygons := make([][]geometry.Polygon, len(raw))
+	for i, rings := range raw {
+		for _, positions := range rings {
+			ring := make(geometry.Polygon, len(positions))
+			for j, position := range positions {
+				if len(position) < 2 {
+					return nil, errors.New("positions need at least 2 coordinates")
+				}
+				ring[j] = models.Vector2{X: position[0], Y: position[1]}
+			}
+			polygons[i] = append(polygons[i], openRing(ring))
+		}
+	}
+
+	return polygons, nil
+}
+
+func encodeGeoJSON(file *terrainFile, id string) ([]byte, error) {
+	polygons := [][][][]float64{{geoJSONRing(file.shape.Boundary)}}
+	for _, hole := range file.shape.Holes {
+		polygons[0] = append(polygons[0], geoJSONRing(hole))
+	}
+	for _, obstacle := range file.shape.Obstacles {
+		polygons = append(polygons, [][][]float64{geoJSONRing(obstacle)})
+	}
+
+	terrain := &geoJSONObject{Type: "MultiPolygon"}
+	var coordinates interface{} = polygons
+	if len(polygons) == 1 {
+		terrain.Type, coordinates = "Polygon", polygons[0]
+	}
+
+	collection := geoJSONObject{Type: "FeatureCollection"}
+	if err := collection.addFeature(terrain, coordinates, &geoJSONProperties{
+		Kind:     geoJSONKindTerrain,
+		ID:       id,
+		Name:     file.name,
+		Rotation: file.rotation,
+	}); err != nil {
+		return nil, err
+	}
+
+	for _, spawn := range file.shape.SpawnPoints {
+		position := []float64{spawn.Position.X, spawn.Position.Z, spawn.Position.Y}
+		if err := collection.addFeature(&geoJSONObject{Type: "Point"}, position, &geoJSONProperties{
+			Kind:   geoJSONKindSpawn,
+			Facing: spawn.Facing,
+			Tags:   spawn.Tags,
+		}); err != nil {
+			return nil, err
+		}
+	}
+
+	for _, zone := range file.shape.Zones {
+		if err := collection.addFeature(&geoJSONObject{Type: "Polygon"}, [][][]float64{geoJSONRing(zone.Polygon)}, &geoJSONProperties{
+			Kind: geoJSONKindZone,
+			Name: zone.Name,
+			Zone: zone.Kind,
+		}); err != nil {
+			return nil, err
+		}
+	}
+
+	return json.Marshal(collection)
+}
+
+func (collection *geoJSONObject) addFeature(object *geoJSONObject, coordinates interface{}, properties *geoJSONProperties) error {
+	raw, err := json.Marshal(coordinates)
+	if err != nil {
+		return err
+	}
+
+	object.Coordinates = raw
+	collection.Features = append(collection.Features, geoJSONObject{
+		Type:       "Feature",
+		Geometry:   object,
+		Properties: properties,
+	})
+
+	return nil
+}
+
+// geoJSONRing closes the ring as GeoJSON requires.
+func geoJSONRing(polygon geometry.Polygon) [][]float64 {
+	ring := make([][]float64, 0, len(polygon)+1)
+	for _, point := range polygon {
+		ring = append(ring, []float64{point.X, point.Y})
+	}
+	if len(ring) > 0 {
+		ring = append(ring, ring[0])
+	}
+
+	return ring
+}
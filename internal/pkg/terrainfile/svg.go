@@ -0,0 +1,362 @@
+package terrainfile
+
+import (
+	"ais-summoner/internal/models"
+	"ais-summoner/internal/pkg/geometry"
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"html"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// SVG files describe the terrain with <polygon> elements and <path>
+// elements made of straight segments, tagged by a data-kind attribute:
+//
+//   - boundary: the outline; further subpaths of a boundary path are holes
+//   - hole, obstacle: one outline each
+//   - zone: an outline named by data-name, of the kind given by data-zone
+//   - spawn: a <circle> centred on the spawn point, with data-facing,
+//     data-height and comma separated data-tags
+//
+// Untagged shapes are read like map editors draw them: the first polygon
+// or path is the boundary, later ones are obstacles and circles are spawn
+// points. The name and rotation are stored as data-name and data-rotation
+// attributes of the <svg> element. SVG's y axis points down while the world
+// Z axis points up in the top view, so y is mirrored.
+
+const (
+	svgKindBoundary = "boundary"
+	svgKindHole     = "hole"
+	svgKindObstacle = "obstacle"
+	svgKindZone     = "zone"
+	svgKindSpawn    = "spawn"
+)
+
+var svgTokenPattern = regexp.MustCompile(`[A-Za-z]|[-+]?(?:\d+\.?\d*|\.\d+)(?:[eE][-+]?\d+)?`)
+
+func decodeSVG(data []byte) (*terrainFile, error) {
+	file := &terrainFile{}
+	boundaries := 0
+
+	decoder := xml.NewDecoder(bytes.NewReader(data))
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid SVG: %v", err)
+		}
+
+		element, ok := token.(xml.StartElement)
+		if !ok {
+			continue
+		}
+
+		var rings [][]models.Vector2
+		switch element.Name.Local {
+		case "svg":
+			file.name = svgAttribute(element, "data-name")
+			if file.rotation, err = svgNumber(element, "data-rotation"); err != nil {
+				return nil, err
+			}
+			continue
+		case "circle":
+			if kind := svgAttribute(element, "data-kind"); kind != "" && kind != svgKindSpawn {
+				return nil, fmt.Errorf("SVG circles can only be spawn points, got data-kind %q", kind)
+			}
+			spawn, err := decodeSVGSpawn(element)
+			if err != nil {
+				return nil, err
+			}
+			file.shape.SpawnPoints = append(file.shape.SpawnPoints, spawn)
+			continue
+		case "polygon":
+			ring, err := parseSVGPoints(svgAttribute(element, "points"))
+			if err != nil {
+				return nil, err
+			}
+			rings = append(rings, ring)
+		case "path":
+			if rings, err = parseSVGPath(svgAttribute(element, "d")); err != nil {
+				return nil, err
+			}
+		default:
+			continue
+		}
+		if len(rings) == 0 {
+			continue
+		}
+
+		kind := svgAttribute(element, "data-kind")
+		if kind == "" {
+			kind = svgKindObstacle
+			if boundaries == 0 {
+				kind = svgKindBoundary
+			}
+		}
+
+		switch kind {
+		case svgKindBoundary:
+			boundaries++
+			file.shape.Boundary = rings[0]
+			for _, ring := range rings[1:] {
+				file.shape.Holes = append(file.shape.Holes, ring)
+			}
+		case svgKindHole, svgKindObstacle:
+			if len(rings) != 1 {
+				return nil, fmt.Errorf("SVG %s must be a single outline, got %d", kind, len(rings))
+			}
+			if kind == svgKindHole {
+				file.shape.Holes = append(file.shape.Holes, rings[0])
+			} else {
+				file.shape.Obstacles = append(file.shape.Obstacles, rings[0])
+			}
+		case svgKindZone:
+			if len(rings) != 1 {
+				return nil, fmt.Errorf("SVG zone must be a single outline, got %d", len(rings))
+			}
+			file.shape.Zones = append(file.shape.Zones, geometry.ShapeZone{
+				Name:    svgAttribute(element, "data-name"),
+				Kind:    models.ZoneKind(svgAttribute(element, "data-zone")),
+				Polygon: rings[0],
+			})
+		default:
+			return nil, fmt.Errorf("unknown SVG data-kind %q", kind)
+		}
+	}
+
+	if boundaries != 1 {
+		return nil, fmt.Errorf("SVG must hold exactly one boundary, got %d", boundaries)
+	}
+
+	return file, nil
+}
+
+func decodeSVGSpawn(element xml.StartElement) (models.SpawnPoint, error) {
+	var spawn models.SpawnPoint
+	var err error
+	var y float64
+	if spawn.Position.X, err = svgNumber(element, "cx"); err != nil {
+		return spawn, err
+	}
+	if y, err = svgNumber(element, "cy"); err != nil {
+		return spawn, err
+	}
+	if spawn.Position.Y, err = svgNumber(element, "data-height"); err != nil {
+		return spawn, err
+	}
+	if spawn.Facing, err = svgNumber(element, "data-facing"); err != nil {
+		return spawn, err
+	}
+	spawn.Position.Z = mirror(y)
+
+	if tags := svgAttribute(element, "data-tags"); tags != "" {
+		spawn.Tags = strings.Split(tags, ",")
+	}
+
+	return spawn, nil
+}
+
+// svgNumber reads a numeric attribute, which defaults to zero.
+func svgNumber(element xml.StartElement, name string) (float64, error) {
+	value := svgAttribute(element, name)
+	if value == "" {
+		return 0, nil
+	}
+
+	number, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid SVG %s %q", name, value)
+	}
+
+	return number, nil
+}
+
+func svgAttribute(element xml.StartElement, name string) string {
+	for _, attribute := range element.Attr {
+		if attribute.Name.Local == name {
+			return attribute.Value
+		}
+	}
+
+	return ""
+}
+
+func parseSVGPoints(points string) ([]models.Vector2, error) {
+	tokens := svgTokenPattern.FindAllString(points, -1)
+	if len(tokens)%2 != 0 {
+		return nil, errors.New("SVG polygon points need an even number of coordinates")
+	}
+
+	ring := make([]models.Vector2, 0, len(tokens)/2)
+	for i := 0; i < len(tokens); i += 2 {
+		x, errX := strconv.ParseFloat(tokens[i], 64)
+		y, errY := strconv.ParseFloat(tokens[i+1], 64)
+		if errX != nil || errY != nil {
+			return nil, fmt.Errorf("invalid SVG polygon point %q,%q", tokens[i], tokens[i+1])
+		}
+		ring = append(ring, models.Vector2{X: x, Y: mirror(y)})
+	}
+
+	return openRing(ring), nil
+}
+
+// parseSVGPath reads the subpaths of path data made of moveto, lineto and
+// closepath commands. Curves cannot describe a terrain and are rejected.
+func parseSVGPath(data string) ([][]models.Vector2, error) {
+	tokens := svgTokenPattern.FindAllString(data, -1)
+
+	var rings [][]models.Vector2
+	var ring []models.Vector2
+	var current, start models.Vector2
+	command := byte(0)
+
+	number := func(i *int) (float64, error) {
+		if *i >= len(tokens) {
+			return 0, fmt.Errorf("SVG path command %c is missing coordinates", command)
+		}
+		value, err := strconv.ParseFloat(tokens[*i], 64)
+		if err != nil {
+			return 0, fmt.Errorf("SVG path command %c expects a number, got %q", command, tokens[*i])
+		}
+		*i++
+		return value, nil
+	}
+	finish := func() {
+		if len(ring) > 0 {
+			rings = append(rings, openRing(ring))
+		}
+		ring = nil
+	}
+
+	for i := 0; i < len(tokens); {
+		if token := tokens[i]; len(token) == 1 && strings.ContainsAny(token, "ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz") {
+			command = token[0]
+			i++
+		} else if command == 0 {
+			return nil, fmt.Errorf("SVG path coordinate %q does not follow a command", token)
+		}
+
+		relative := command >= 'a'
+		var next models.Vector2
+		switch command {
+		case 'M', 'm', 'L', 'l':
+			x, err := number(&i)
+			if err != nil {
+				return nil, err
+			}
+			y, err := number(&i)
+			if err != nil {
+				return nil, err
+			}
+			next = models.Vector2{X: x, Y: y}
+		case 'H', 'h':
+			x, err := number(&i)
+			if err != nil {
+				return nil, err
+			}
+			next = models.Vector2{X: x, Y: current.Y}
+			if relative {
+				next.Y = 0
+			}
+		case 'V', 'v':
+			y, err := number(&i)
+			if err != nil {
+				return nil, err
+			}
+			next = models.Vector2{X: current.X, Y: y}
+			if relative {
+				next.X = 0
+			}
+		case 'Z', 'z':
+			finish()
+			current = start
+			command = 0
+			continue
+		default:
+			return nil, fmt.Errorf("unsupported SVG path command %c, terrains only use straight segments", command)
+		}
+
+		if relative {
+			next = models.Vector2{X: current.X + next.X, Y: current.Y + next.Y}
+		}
+
+		switch command {
+		case 'M', 'm':
+			finish()
+			start = next
+			// Coordinates after a moveto are implicit linetos.
+			if command == 'M' {
+				command = 'L'
+			} else {
+				command = 'l'
+			}
+		}
+		current = next
+		ring = append(ring, models.Vector2{X: next.X, Y: next.Y})
+	}
+	finish()
+
+	// Mirror y last so relative commands are resolved in SVG space.
+	for _, ring := range rings {
+		for i := range ring {
+			ring[i].Y = mirror(ring[i].Y)
+		}
+	}
+
+	return rings, nil
+}
+
+func encodeSVG(file *terrainFile, id string) ([]byte, error) {
+	bounds := file.shape.Boundary.Bounds()
+	minX, minY := bounds.Min.X, mirror(bounds.Max.Y)
+	width, height := bounds.Max.X-bounds.Min.X, bounds.Max.Y-bounds.Min.Y
+
+	var buffer bytes.Buffer
+	fmt.Fprintf(&buffer, `<svg xmlns="http://www.w3.org/2000/svg" viewBox="%s %s %s %s" data-id="%s" data-name="%s" data-rotation="%s">`+"\n",
+		formatSVGNumber(minX), formatSVGNumber(minY), formatSVGNumber(width), formatSVGNumber(height),
+		html.EscapeString(id), html.EscapeString(file.name), formatSVGNumber(file.rotation))
+
+	writePolygon := func(polygon geometry.Polygon, attributes string) {
+		points := make([]string, len(polygon))
+		for i, point := range polygon {
+			points[i] = formatSVGNumber(point.X) + "," + formatSVGNumber(mirror(point.Y))
+		}
+		fmt.Fprintf(&buffer, `  <polygon %s points="%s" vector-effect="non-scaling-stroke"/>`+"\n", attributes, strings.Join(points, " "))
+	}
+
+	writePolygon(file.shape.Boundary, `data-kind="boundary" fill="none" stroke="black"`)
+	for _, hole := range file.shape.Holes {
+		writePolygon(hole, `data-kind="hole" fill="white" stroke="gray"`)
+	}
+	for _, obstacle := range file.shape.Obstacles {
+		writePolygon(obstacle, `data-kind="obstacle" fill="gray" stroke="black"`)
+	}
+	for _, zone := range file.shape.Zones {
+		writePolygon(zone.Polygon, fmt.Sprintf(`data-kind="zone" data-name="%s" data-zone="%s" fill="none" stroke="blue" stroke-dasharray="4"`,
+			html.EscapeString(zone.Name), html.EscapeString(string(zone.Kind))))
+	}
+	for _, spawn := range file.shape.SpawnPoints {
+		fmt.Fprintf(&buffer, `  <circle data-kind="spawn" cx="%s" cy="%s" r="0.5" data-height="%s" data-facing="%s" data-tags="%s" fill="green"/>`+"\n",
+			formatSVGNumber(spawn.Position.X), formatSVGNumber(mirror(spawn.Position.Z)), formatSVGNumber(spawn.Position.Y),
+			formatSVGNumber(spawn.Facing), html.EscapeString(strings.Join(spawn.Tags, ",")))
+	}
+
+	buffer.WriteString("</svg>\n")
+	return buffer.Bytes(), nil
+}
+
+// mirror flips y between SVG and world space without producing -0.
+func mirror(y float64) float64 {
+	return 0 - y
+}
+
+func formatSVGNumber(value float64) string {
+	return strconv.FormatFloat(value, 'g', -1, 64)
+}
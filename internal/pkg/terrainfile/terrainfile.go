@@ -0,0 +1,175 @@
+// Package terrainfile converts terrains to and from the formats map editors
+// work with. Files hold world coordinates, that is the terrain with its
+// rotation applied, and the rotation is kept alongside them so an import
+// recovers the stored coordinates. Imports wind the boundary and zones
+// counter-clockwise and holes and obstacles clockwise. Importing then
+// exporting a terrain reproduces its coordinates within Tolerance.
+package terrainfile
+
+import (
+	"ais-summoner/internal/models"
+	"ais-summoner/internal/pkg/geometry"
+	"errors"
+	"fmt"
+	"math"
+)
+
+type Format string
+
+const (
+	FormatGeoJSON Format = "geojson"
+	FormatSVG     Format = "svg"
+)
+
+// Tolerance is the largest coordinate difference a round trip through any
+// format may introduce, in world units.
+const Tolerance = 1e-6
+
+var ErrUnknownFormat = errors.New("unknown terrain format")
+
+// ImportOptions override the name and rotation stored in the file.
+type ImportOptions struct {
+	Name     string
+	Rotation *float64
+}
+
+func ParseFormat(value string) (Format, error) {
+	switch Format(value) {
+	case FormatGeoJSON, FormatSVG:
+		return Format(value), nil
+	}
+
+	return "", fmt.Errorf("%w: %q", ErrUnknownFormat, value)
+}
+
+func (format Format) ContentType() string {
+	if format == FormatSVG {
+		return "image/svg+xml"
+	}
+
+	return "application/geo+json"
+}
+
+func (format Format) Extension() string {
+	if format == FormatSVG {
+		return ".svg"
+	}
+
+	return ".geojson"
+}
+
+// Import reads a terrain from the data. The returned terrain is not
+// validated; callers run geometry.ValidateTerrain before storing it.
+func Import(format Format, data []byte, options ImportOptions) (*models.Terrain, error) {
+	var file *terrainFile
+	var err error
+	switch format {
+	case FormatGeoJSON:
+		file, err = decodeGeoJSON(data)
+	case FormatSVG:
+		file, err = decodeSVG(data)
+	default:
+		return nil, ErrUnknownFormat
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if options.Name != "" {
+		file.name = options.Name
+	}
+	if options.Rotation != nil {
+		file.rotation = *options.Rotation
+	}
+
+	return file.terrain(), nil
+}
+
+// Export writes the terrain in the given format.
+func Export(format Format, terrain *models.Terrain) ([]byte, error) {
+	file := &terrainFile{
+		name:     terrain.Name,
+		rotation: terrain.Rotation,
+		shape:    *geometry.NewShape(terrain),
+	}
+
+	switch format {
+	case FormatGeoJSON:
+		return encodeGeoJSON(file, terrain.ID.Hex())
+	case FormatSVG:
+		return encodeSVG(file, terrain.ID.Hex())
+	}
+
+	return nil, ErrUnknownFormat
+}
+
+// terrainFile is the format independent content of a terrain file, in
+// world coordinates.
+type terrainFile struct {
+	name     string
+	rotation float64
+	shape    geometry.Shape
+}
+
+// terrain undoes the rotation of the world coordinates and normalizes the
+// winding of every outline.
+func (file *terrainFile) terrain() *models.Terrain {
+	terrain := &models.Terrain{
+		Name:     file.name,
+		Rotation: file.rotation,
+		Boundary: file.outline(file.shape.Boundary, true),
+	}
+
+	for _, hole := range file.shape.Holes {
+		terrain.Holes = append(terrain.Holes, file.outline(hole, false))
+	}
+	for _, obstacle := range file.shape.Obstacles {
+		terrain.Obstacles = append(terrain.Obstacles, file.outline(obstacle, false))
+	}
+	for _, spawn := range file.shape.SpawnPoints {
+		position := geometry.Rotate(models.Vector2{X: spawn.Position.X, Y: spawn.Position.Z}, -file.rotation)
+		facing := math.Mod(spawn.Facing-file.rotation, 360)
+		if facing < 0 {
+			facing += 360
+		}
+		terrain.SpawnPoints = append(terrain.SpawnPoints, models.SpawnPoint{
+			Position: models.Vector3{X: position.X, Y: spawn.Position.Y, Z: position.Y},
+			Facing:   facing,
+			Tags:     spawn.Tags,
+		})
+	}
+	for _, zone := range file.shape.Zones {
+		terrain.Zones = append(terrain.Zones, models.Zone{
+			Name:   zone.Name,
+			Kind:   zone.Kind,
+			Points: file.outline(zone.Polygon, true),
+		})
+	}
+
+	return terrain
+}
+
+// outline unrotates the polygon and winds it counter-clockwise, or
+// clockwise for interior rings.
+func (file *terrainFile) outline(polygon geometry.Polygon, counterClockwise bool) []models.Vector2 {
+	points := geometry.NewPolygon(polygon, -file.rotation)
+	if (points.SignedArea() > 0) != counterClockwise {
+		for i, j := 0, len(points)-1; i < j; i, j = i+1, j-1 {
+			points[i], points[j] = points[j], points[i]
+		}
+	}
+
+	return points
+}
+
+// openRing drops the closing point that repeats the first one.
+func openRing(ring []models.Vector2) []models.Vector2 {
+	if len(ring) > 1 {
+		first, last := ring[0], ring[len(ring)-1]
+		if math.Abs(first.X-last.X) <= Tolerance && math.Abs(first.Y-last.Y) <= Tolerance {
+			return ring[:len(ring)-1]
+		}
+	}
+
+	return ring
+}
@@ -0,0 +1,223 @@
+package terrainfile
+
+import (
+	"ais-summoner/internal/models"
+	"ais-summoner/internal/pkg/geometry"
+	"math"
+	"strings"
+	"testing"
+)
+
+// courtyard is a rotated 40 by 30 terrain with a hole, an obstacle, two
+// spawn points and a zone, wound the way imports normalize outlines.
+func courtyard() *models.Terrain {
+	return &models.Terrain{
+		Name:     "courtyard",
+		Rotation: 30,
+		Boundary: []models.Vector2{{X: -20, Y: -15}, {X: 20, Y: -15}, {X: 20, Y: 15}, {X: -20, Y: 15}},
+		Holes:    [][]models.Vector2{{{X: -10, Y: -5}, {X: -10, Y: 5}, {X: -5, Y: 5}, {X: -5, Y: -5}}},
+		Obstacles: [][]models.Vector2{
+			{{X: 5, Y: -2.5}, {X: 7.25, Y: 4}, {X: 12, Y: -1}},
+		},
+		SpawnPoints: []models.SpawnPoint{
+			{Position: models.Vector3{X: -15, Y: 0, Z: -10}, Facing: 45, Tags: []string{"red", "start"}},
+			{Position: models.Vector3{X: 15.5, Y: 1.25, Z: 10}, Facing: 350, Tags: []string{"blue"}},
+		},
+		Zones: []models.Zone{
+			{Name: "flag", Kind: models.ZoneCapture, Points: []models.Vector2{{X: 0, Y: 8}, {X: 4, Y: 8}, {X: 4, Y: 12}, {X: 0, Y: 12}}},
+		},
+	}
+}
+
+func near(a float64, b float64) bool {
+	return math.Abs(a-b) <= Tolerance
+}
+
+// compareOutline checks that the outline holds the points of want in the
+// same order.
+func compareOutline(t *testing.T, what string, got []models.Vector2, want []models.Vector2) {
+	t.Helper()
+
+	if len(got) != len(want) {
+		t.Fatalf("%s has %d points, want %d: %v", what, len(got), len(want), got)
+	}
+	for i := range want {
+		if !near(got[i].X, want[i].X) || !near(got[i].Y, want[i].Y) {
+			t.Fatalf("%s point %d is %+v, want %+v", what, i, got[i], want[i])
+		}
+	}
+}
+
+// compareTerrains checks that got holds the coordinates of want within
+// Tolerance, along with its name, rotation, spawn points and zones.
+func compareTerrains(t *testing.T, got *models.Terrain, want *models.Terrain) {
+	t.Helper()
+
+	if got.Name != want.Name || !near(got.Rotation, want.Rotation) {
+		t.Fatalf("imported %q rotated by %v, want %q rotated by %v", got.Name, got.Rotation, want.Name, want.Rotation)
+	}
+
+	compareOutline(t, "boundary", got.Boundary, want.Boundary)
+	if len(got.Holes) != len(want.Holes) || len(got.Obstacles) != len(want.Obstacles) {
+		t.Fatalf("imported %d holes and %d obstacles, want %d and %d", len(got.Holes), len(got.Obstacles), len(want.Holes), len(want.Obstacles))
+	}
+	for i := range want.Holes {
+		compareOutline(t, "hole", got.Holes[i], want.Holes[i])
+	}
+	for i := range want.Obstacles {
+		compareOutline(t, "obstacle", got.Obstacles[i], want.Obstacles[i])
+	}
+
+	if len(got.SpawnPoints) != len(want.SpawnPoints) {
+		t.Fatalf("imported %d spawn points, want %d", len(got.SpawnPoints), len(want.SpawnPoints))
+	}
+	for i, spawn := range want.SpawnPoints {
+		imported := got.SpawnPoints[i]
+		if !near(imported.Position.X, spawn.Position.X) || !near(imported.Position.Y, spawn.Position.Y) || !near(imported.Position.Z, spawn.Position.Z) {
+			t.Fatalf("spawn point %d is at %+v, want %+v", i, imported.Position, spawn.Position)
+		}
+		if !near(imported.Facing, spawn.Facing) {
+			t.Fatalf("spawn point %d faces %v, want %v", i, imported.Facing, spawn.Facing)
+		}
+		if strings.Join(imported.Tags, ",") != strings.Join(spawn.Tags, ",") {
+			t.Fatalf("spawn point %d is tagged %v, want %v", i, imported.Tags, spawn.Tags)
+		}
+	}
+
+	if len(got.Zones) != len(want.Zones) {
+		t.Fatalf("imported %d zones, want %d", len(got.Zones), len(want.Zones))
+	}
+	for i, zone := range want.Zones {
+		if got.Zones[i].Name != zone.Name || got.Zones[i].Kind != zone.Kind {
+			t.Fatalf("zone %d is %s %q, want %s %q", i, got.Zones[i].Kind, got.Zones[i].Name, zone.Kind, zone.Name)
+		}
+		compareOutline(t, "zone", got.Zones[i].Points, zone.Points)
+	}
+}
+
+func TestRoundTrip(t *testing.T) {
+	for _, format := range []Format{FormatGeoJSON, FormatSVG} {
+		t.Run(string(format), func(t *testing.T) {
+			terrain := courtyard()
+
+			data, err := Export(format, terrain)
+			if err != nil {
+				t.Fatalf("exporting: %v", err)
+			}
+			imported, err := Import(format, data, ImportOptions{})
+			if err != nil {
+				t.Fatalf("importing: %v", err)
+			}
+
+			compareTerrains(t, imported, terrain)
+			if err := geometry.ValidateTerrain(imported); err != nil {
+				t.Fatalf("imported an invalid terrain: %v", err)
+			}
+		})
+	}
+}
+
+// TestRoundTripHoldsWorldCoordinates checks that files hold the terrain
+// with its rotation applied, so editors show it as players see it.
+func TestRoundTripHoldsWorldCoordinates(t *testing.T) {
+	terrain := courtyard()
+	world := geometry.NewShape(terrain)
+
+	data, err := Export(FormatGeoJSON, terrain)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rotation := 0.0
+	imported, err := Import(FormatGeoJSON, data, ImportOptions{Name: "renamed", Rotation: &rotation})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if imported.Name != "renamed" || imported.Rotation != 0 {
+		t.Fatalf("options gave %q rotated by %v", imported.Name, imported.Rotation)
+	}
+	compareOutline(t, "boundary", imported.Boundary, world.Boundary)
+	compareOutline(t, "obstacle", imported.Obstacles[0], world.Obstacles[0])
+}
+
+func TestImportNormalizesWinding(t *testing.T) {
+	// The boundary and zone are wound clockwise, the hole and obstacle
+	// counter-clockwise, and the GeoJSON rings are closed.
+	files := map[Format]string{
+		FormatGeoJSON: `{"type": "FeatureCollection", "features": [
+			{"type": "Feature", "properties": {"kind": "terrain", "name": "reversed"}, "geometry": {"type": "MultiPolygon", "coordinates": [
+				[[[-20, -15], [-20, 15], [20, 15], [20, -15], [-20, -15]], [[-10, -5], [-5, -5], [-5, 5], [-10, 5], [-10, -5]]],
+				[[[5, -2.5], [12, -1], [7.25, 4], [5, -2.5]]]
+			]}},
+			{"type": "Feature", "properties": {"kind": "zone", "name": "flag", "zone": "capture"}, "geometry": {"type": "Polygon", "coordinates": [
+				[[0, 8], [0, 12], [4, 12], [4, 8], [0, 8]]
+			]}}
+		]}`,
+		FormatSVG: `<svg xmlns="http://www.w3.org/2000/svg" data-name="reversed">
+			<path data-kind="boundary" d="M -20 15 L -20 -15 L 20 -15 L 20 15 Z M -10 5 h 5 v -10 h -5 Z"/>
+			<polygon data-kind="obstacle" points="5,2.5 12,1 7.25,-4"/>
+			<polygon data-kind="zone" data-name="flag" data-zone="capture" points="0,-8 0,-12 4,-12 4,-8"/>
+		</svg>`,
+	}
+
+	for format, file := range files {
+		t.Run(string(format), func(t *testing.T) {
+			imported, err := Import(format, []byte(file), ImportOptions{})
+			if err != nil {
+				t.Fatalf("importing: %v", err)
+			}
+
+			for what, outline := range map[string]struct {
+				points           []models.Vector2
+				counterClockwise bool
+			}{
+				"boundary": {imported.Boundary, true},
+				"hole":     {imported.Holes[0], false},
+				"obstacle": {imported.Obstacles[0], false},
+				"zone":     {imported.Zones[0].Points, true},
+			} {
+				if area := geometry.Polygon(outline.points).SignedArea(); (area > 0) != outline.counterClockwise {
+					t.Errorf("%s has signed area %v after normalizing", what, area)
+				}
+			}
+
+			// Reversing the outlines gives those of the courtyard, which is
+			// wound the right way.
+			want := courtyard()
+			for what, outlines := range map[string][2][]models.Vector2{
+				"boundary": {imported.Boundary, want.Boundary},
+				"hole":     {imported.Holes[0], want.Holes[0]},
+				"obstacle": {imported.Obstacles[0], want.Obstacles[0]},
+				"zone":     {imported.Zones[0].Points, want.Zones[0].Points},
+			} {
+				if !sameRing(outlines[0], outlines[1]) {
+					t.Errorf("%s is %v, want the points of %v in that order", what, outlines[0], outlines[1])
+				}
+			}
+		})
+	}
+}
+
+// sameRing reports whether the rings hold the same points in the same
+// cyclic order, whichever point they start from.
+func sameRing(a []models.Vector2, b []models.Vector2) bool {
+	if len(a) != len(b) {
+		return false
+	}
+
+	for offset := range b {
+		matches := true
+		for i := range a {
+			other := b[(i+offset)%len(b)]
+			if !near(a[i].X, other.X) || !near(a[i].Y, other.Y) {
+				matches = false
+				break
+			}
+		}
+		if matches {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,56 @@
+package repositories
+
+import (
+	"ais-summoner/internal/models"
+	"context"
+	"log"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+type MatchRepository struct {
+	collection *mongo.Collection
+	logger     *log.Logger
+}
+
+func NewMatchRepository(db *mongo.Database) *MatchRepository {
+	return &MatchRepository{
+		collection: db.Collection("matches"),
+		logger:     log.New(log.Writer(), "[MatchRepository] ", log.LstdFlags),
+	}
+}
+
+func (mr *MatchRepository) Insert(ctx context.Context, match *models.Match) (*models.Match, error) {
+	match.CreatedAt = time.Now()
+
+	result, err := mr.collection.InsertOne(ctx, match)
+	if err != nil {
+		mr.logger.Printf("Error inserting match: %v", err)
+		return nil, err
+	}
+
+	match.ID = result.InsertedID.(primitive.ObjectID)
+	return match, nil
+}
+
+func (mr *MatchRepository) GetByID(ctx context.Context, id string) (*models.Match, error) {
+	objectID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return nil, err
+	}
+
+	var match models.Match
+	err = mr.collection.FindOne(ctx, bson.M{"_id": objectID}).Decode(&match)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, nil
+		}
+		mr.logger.Printf("Error finding match by ID: %v", err)
+		return nil, err
+	}
+
+	return &match, nil
+}
@@ -0,0 +1,67 @@
+package repositories
+
+import (
+	"bytes"
+	"context"
+	"log"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/gridfs"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// ReplayRepository stores replay logs in the "replays" GridFS bucket, using
+// the id of the match as the file id.
+type ReplayRepository struct {
+	bucket *gridfs.Bucket
+	logger *log.Logger
+}
+
+func NewReplayRepository(db *mongo.Database) *ReplayRepository {
+	logger := log.New(log.Writer(), "[ReplayRepository] ", log.LstdFlags)
+
+	bucket, err := gridfs.NewBucket(db, options.GridFSBucket().SetName("replays"))
+	if err != nil {
+		logger.Fatalf("Failed to open the replays bucket: %v", err)
+	}
+
+	return &ReplayRepository{
+		bucket: bucket,
+		logger: logger,
+	}
+}
+
+func (rr *ReplayRepository) Save(ctx context.Context, matchID string, data []byte) error {
+	objectID, err := primitive.ObjectIDFromHex(matchID)
+	if err != nil {
+		return err
+	}
+
+	err = rr.bucket.UploadFromStreamWithID(objectID, matchID+".replay", bytes.NewReader(data))
+	if err != nil {
+		rr.logger.Printf("Error saving replay: %v", err)
+		return err
+	}
+
+	return nil
+}
+
+// Load returns the replay of the match, or nil if there is none.
+func (rr *ReplayRepository) Load(ctx context.Context, matchID string) ([]byte, error) {
+	objectID, err := primitive.ObjectIDFromHex(matchID)
+	if err != nil {
+		return nil, err
+	}
+
+	var data bytes.Buffer
+	if _, err := rr.bucket.DownloadToStream(objectID, &data); err != nil {
+		if err == gridfs.ErrFileNotFound {
+			return nil, nil
+		}
+		rr.logger.Printf("Error loading replay: %v", err)
+		return nil, err
+	}
+
+	return data.Bytes(), nil
+}
@@ -24,6 +24,7 @@ func NewTerrainRepository(db *mongo.Database) *TerrainRepository {
 }
 
 func (tr *TerrainRepository) Insert(ctx context.Context, terrain *models.Terrain) (*models.Terrain, error) {
+	terrain.MigrateLegacyOutline()
 	terrain.CreatedAt = time.Now()
 	terrain.UpdatedAt = time.Now()
 
@@ -53,6 +54,7 @@ func (tr *TerrainRepository) GetByID(ctx context.Context, id string) (*models.Te
 		return nil, err
 	}
 
+	terrain.MigrateLegacyOutline()
 	return &terrain, nil
 }
 
@@ -71,6 +73,7 @@ func (tr *TerrainRepository) Find(ctx context.Context) ([]*models.Terrain, error
 			tr.logger.Printf("Error decoding terrain: %v", err)
 			continue
 		}
+		terrain.MigrateLegacyOutline()
 		terrains = append(terrains, &terrain)
 	}
 
@@ -88,14 +91,20 @@ func (tr *TerrainRepository) Update(ctx context.Context, id string, terrain *mod
 		return nil, err
 	}
 
+	terrain.MigrateLegacyOutline()
 	terrain.UpdatedAt = time.Now()
 	update := bson.M{
 		"$set": bson.M{
-			"name":      terrain.Name,
-			"rotation":  terrain.Rotation,
-			"points":    terrain.Points,
-			"updatedAt": terrain.UpdatedAt,
+			"name":        terrain.Name,
+			"rotation":    terrain.Rotation,
+			"boundary":    terrain.Boundary,
+			"holes":       terrain.Holes,
+			"obstacles":   terrain.Obstacles,
+			"spawnPoints": terrain.SpawnPoints,
+			"zones":       terrain.Zones,
+			"updatedAt":   terrain.UpdatedAt,
 		},
+		"$unset": bson.M{"points": ""},
 	}
 
 	_, err = tr.collection.UpdateOne(ctx, bson.M{"_id": objectID}, update)
@@ -121,3 +130,27 @@ func (tr *TerrainRepository) Delete(ctx context.Context, id string) error {
 
 	return nil
 }
+
+// MigrateLegacyOutlines rewrites the documents still holding the single
+// outline terrains had before Boundary, like Terrain.MigrateLegacyOutline
+// does: the outline becomes the boundary unless there already is one, and
+// is dropped either way.
+func (tr *TerrainRepository) MigrateLegacyOutlines(ctx context.Context) (int64, error) {
+	filter := bson.M{"points": bson.M{"$exists": true}}
+	update := mongo.Pipeline{
+		{{Key: "$set", Value: bson.M{"boundary": bson.M{"$cond": bson.A{
+			bson.M{"$gt": bson.A{bson.M{"$size": bson.M{"$ifNull": bson.A{"$boundary", bson.A{}}}}, 0}},
+			"$boundary",
+			"$points",
+		}}}}},
+		{{Key: "$unset", Value: "points"}},
+	}
+
+	result, err := tr.collection.UpdateMany(ctx, filter, update)
+	if err != nil {
+		tr.logger.Printf("Error migrating legacy terrains: %v", err)
+		return 0, err
+	}
+
+	return result.ModifiedCount, nil
+}
@@ -2,6 +2,7 @@ package repositories
 
 import (
 	"ais-summoner/internal/models"
+	"ais-summoner/internal/pkg/glicko"
 	"context"
 	"log"
 	"time"
@@ -31,6 +32,14 @@ func (ur *UserRepository) Insert(ctx context.Context, user *models.User) (*model
 		user.Metadata.ModelID = "019590ed-2942-7503-b8db-0a185f81a1de"
 	}
 
+	if user.Rating.Deviation == 0 {
+		user.Rating = models.Rating{
+			Rating:     glicko.DefaultRating,
+			Deviation:  glicko.DefaultDeviation,
+			Volatility: glicko.DefaultVolatility,
+		}
+	}
+
 	result, err := ur.collection.InsertOne(ctx, user)
 	if err != nil {
 		ur.logger.Printf("Error inserting user: %v", err)
@@ -74,6 +83,20 @@ func (ur *UserRepository) GetByUsername(ctx context.Context, username string) (*
 	return &user, nil
 }
 
+func (ur *UserRepository) GetBySubject(ctx context.Context, subject string) (*models.User, error) {
+	var user models.User
+	err := ur.collection.FindOne(ctx, bson.M{"subject": subject}).Decode(&user)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, nil
+		}
+		ur.logger.Printf("Error finding user by subject: %v", err)
+		return nil, err
+	}
+
+	return &user, nil
+}
+
 func (ur *UserRepository) GetByEmail(ctx context.Context, email string) (*models.User, error) {
 	var user models.User
 	err := ur.collection.FindOne(ctx, bson.M{"email": email}).Decode(&user)
@@ -139,6 +162,104 @@ func (ur *UserRepository) Update(ctx context.Context, id string, user *models.Us
 	return ur.GetByID(ctx, id)
 }
 
+// FindByIDs returns the users with the given ids. Ids that are not valid or
+// do not exist are skipped.
+func (ur *UserRepository) FindByIDs(ctx context.Context, ids []string) ([]*models.User, error) {
+	objectIDs := make([]primitive.ObjectID, 0, len(ids))
+	for _, id := range ids {
+		if objectID, err := primitive.ObjectIDFromHex(id); err == nil {
+			objectIDs = append(objectIDs, objectID)
+		}
+	}
+
+	cursor, err := ur.collection.Find(ctx, bson.M{"_id": bson.M{"$in": objectIDs}})
+	if err != nil {
+		ur.logger.Printf("Error finding users by IDs: %v", err)
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var users []*models.User
+	if err := cursor.All(ctx, &users); err != nil {
+		ur.logger.Printf("Error decoding users: %v", err)
+		return nil, err
+	}
+
+	return users, nil
+}
+
+// UpdateRating stores a rating computed from the one the user had after
+// gamesPlayed games and counts the game. It reports false, leaving the
+// user as is, when another game was counted since. An empty season
+// selects the lifetime rating.
+func (ur *UserRepository) UpdateRating(ctx context.Context, id string, season string, gamesPlayed int, rating models.Rating) (bool, error) {
+	objectID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return false, err
+	}
+
+	field := "rating"
+	if season != "" {
+		field = "seasonRatings." + season
+	}
+
+	filter := bson.M{"_id": objectID, field + ".gamesPlayed": gamesPlayed}
+	if gamesPlayed == 0 {
+		// A rating never stored has no games played at all.
+		filter[field+".gamesPlayed"] = bson.M{"$in": bson.A{0, nil}}
+	}
+
+	update := bson.M{
+		"$set": bson.M{
+			field + ".rating":     rating.Rating,
+			field + ".deviation":  rating.Deviation,
+			field + ".volatility": rating.Volatility,
+			"updatedAt":           time.Now(),
+		},
+		"$inc": bson.M{
+			field + ".gamesPlayed": 1,
+		},
+	}
+
+	result, err := ur.collection.UpdateOne(ctx, filter, update)
+	if err != nil {
+		ur.logger.Printf("Error updating user rating: %v", err)
+		return false, err
+	}
+
+	return result.MatchedCount == 1, nil
+}
+
+// IncrementStats atomically adds the given amounts to the lifetime stats.
+func (ur *UserRepository) IncrementStats(ctx context.Context, id string, stats models.UserStats) error {
+	objectID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return err
+	}
+
+	update := bson.M{
+		"$inc": bson.M{
+			"stats.matches": stats.Matches,
+			"stats.wins":    stats.Wins,
+			"stats.kills":   stats.Kills,
+			"stats.deaths":  stats.Deaths,
+			"stats.damage":  stats.Damage,
+			"stats.dashes":  stats.Dashes,
+		},
+		"$set": bson.M{
+			"updatedAt": time.Now(),
+		},
+	}
+
+	_, err = ur.collection.UpdateOne(ctx, bson.M{"_id": objectID}, update)
+	if err != nil {
+		ur.logger.Printf("Error incrementing user stats: %v", err)
+		return err
+	}
+
+	return nil
+}
+
 func (ur *UserRepository) Delete(ctx context.Context, id string) error {
 	objectID, err := primitive.ObjectIDFromHex(id)
 	if err != nil {
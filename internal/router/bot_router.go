@@ -0,0 +1,18 @@
+package router
+
+import (
+	"ais-summoner/internal/database"
+	"ais-summoner/internal/game"
+	"ais-summoner/internal/handler"
+	"ais-summoner/internal/middleware"
+
+	"github.com/gin-gonic/gin"
+)
+
+func NewBotRouterV1(router *gin.Engine, mongodb *database.MongoDB, gateway *game.GameGateway) {
+	pathPrefix := "/v1/bot"
+
+	admin := router.Group(pathPrefix, middleware.IsAdmin(mongodb))
+	admin.POST("", handler.AddBotsHandler(gateway))
+	admin.DELETE("/:roomId", handler.RemoveBotsHandler(gateway))
+}
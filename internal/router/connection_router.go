@@ -0,0 +1,17 @@
+package router
+
+import (
+	"ais-summoner/internal/database"
+	"ais-summoner/internal/game"
+	"ais-summoner/internal/handler"
+	"ais-summoner/internal/middleware"
+
+	"github.com/gin-gonic/gin"
+)
+
+func NewConnectionRouterV1(router *gin.Engine, mongodb *database.MongoDB, gateway *game.GameGateway) {
+	pathPrefix := "/v1/connection"
+
+	admin := router.Group(pathPrefix, middleware.IsAdmin(mongodb))
+	admin.GET("", handler.GetConnectionsHandler(gateway))
+}
@@ -0,0 +1,16 @@
+package router
+
+import (
+	"ais-summoner/internal/database"
+	"ais-summoner/internal/handler"
+	"ais-summoner/internal/leaderboard"
+
+	"github.com/gin-gonic/gin"
+)
+
+func NewLeaderboardRouterV1(router *gin.Engine, mongodb *database.MongoDB, board *leaderboard.Leaderboard) {
+	pathPrefix := "/v1/leaderboard"
+
+	router.GET(pathPrefix, handler.GetLeaderboardHandler(mongodb, board))
+	router.GET(pathPrefix+"/around/:userId", handler.GetLeaderboardAroundUserHandler(mongodb, board))
+}
@@ -0,0 +1,14 @@
+package router
+
+import (
+	"ais-summoner/internal/database"
+	"ais-summoner/internal/handler"
+
+	"github.com/gin-gonic/gin"
+)
+
+func NewMatchRouterV1(router *gin.Engine, mongodb *database.MongoDB) {
+	pathPrefix := "/v1/match"
+
+	router.GET(pathPrefix+"/:id", handler.GetMatchByIdHandler(mongodb))
+}
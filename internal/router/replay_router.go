@@ -0,0 +1,14 @@
+package router
+
+import (
+	"ais-summoner/internal/game"
+	"ais-summoner/internal/handler"
+
+	"github.com/gin-gonic/gin"
+)
+
+func NewReplayRouterV1(router *gin.Engine, replays game.ReplayStore) {
+	pathPrefix := "/v1/replay"
+
+	router.GET(pathPrefix+"/:matchId", handler.GetReplayHandler(replays))
+}
@@ -3,6 +3,7 @@ package router
 import (
 	"ais-summoner/internal/database"
 	"ais-summoner/internal/handler"
+	"ais-summoner/internal/middleware"
 
 	"github.com/gin-gonic/gin"
 )
@@ -11,4 +12,12 @@ func NewTerrainRouterV1(router *gin.Engine, mongodb *database.MongoDB) {
 	pathPrefix := "/v1/terrain"
 
 	router.GET(pathPrefix+"/:id", handler.GetTerrainByIdHandler(mongodb))
+	router.GET(pathPrefix+"/:id/export", handler.ExportTerrainHandler(mongodb))
+
+	admin := router.Group(pathPrefix, middleware.IsAdmin(mongodb))
+	admin.GET("", handler.GetTerrainListHandler(mongodb))
+	admin.POST("", handler.CreateTerrainHandler(mongodb))
+	admin.POST("/import", handler.ImportTerrainHandler(mongodb))
+	admin.PUT("/:id", handler.UpdateTerrainHandler(mongodb))
+	admin.DELETE("/:id", handler.DeleteTerrainHandler(mongodb))
 }
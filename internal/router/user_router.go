@@ -3,13 +3,15 @@ package router
 import (
 	"ais-summoner/internal/database"
 	"ais-summoner/internal/handler"
+	"ais-summoner/internal/leaderboard"
 
 	"github.com/gin-gonic/gin"
 )
 
-func NewUserRouterV1(router *gin.Engine, mongodb *database.MongoDB) {
+func NewUserRouterV1(router *gin.Engine, mongodb *database.MongoDB, board *leaderboard.Leaderboard) {
 	pathPrefix := "/v1/user"
 
-	router.GET(pathPrefix+"/:id", handler.GetUserByIdHandler(mongodb))
-	router.GET(pathPrefix+"/:email", handler.GetUserByEmailHandler(mongodb))
+	router.GET(pathPrefix+"/:id", handler.GetUserHandler(mongodb))
+	router.GET(pathPrefix+"/:id/rank", handler.GetUserRankHandler(mongodb, board))
+	router.GET(pathPrefix+"/:id/stats", handler.GetUserStatsHandler(mongodb))
 }